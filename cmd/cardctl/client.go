@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wool/go2hw11/cmd/server_new/app"
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// APIError - ответ сервера с кодом 4xx/5xx
+type APIError struct {
+	Status  int
+	Message string
+	Fields  []app.FieldError // ошибки проверки запроса (400)
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server: %d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// voidTimeout - на отмену холда, если его подтверждение не прошло
+const voidTimeout = 10 * time.Second
+
+// Client - HTTP-клиент сервера карт
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	return &Client{baseURL: strings.TrimRight(cfg.Server, "/"), token: cfg.Token, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// do - запрос с телом in (JSON, если не nil), ответ - в out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return apiError(resp, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func apiError(resp *http.Response, data []byte) *APIError {
+	e := &APIError{Status: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var verr struct {
+			Error  string           `json:"error"`
+			Fields []app.FieldError `json:"fields"`
+		}
+		if json.Unmarshal(data, &verr) == nil && verr.Error != "" {
+			e.Message, e.Fields = verr.Error, verr.Fields
+		}
+	}
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		e.Message += " (retry after " + retry + "s)"
+	}
+	return e
+}
+
+// PurchaseCard - POST /purchaseCard; с адресом сервер отвечает заказом, а не картой
+func (c *Client) PurchaseCard(ctx context.Context, params app.PurchaseCardParams) (*card.Card, *card.CardOrder, error) {
+	if params.Address != nil {
+		var order card.CardOrder
+		if err := c.do(ctx, http.MethodPost, "/purchaseCard", params, &order); err != nil {
+			return nil, nil, err
+		}
+		return nil, &order, nil
+	}
+	var issued card.Card
+	if err := c.do(ctx, http.MethodPost, "/purchaseCard", params, &issued); err != nil {
+		return nil, nil, err
+	}
+	return &issued, nil, nil
+}
+
+// UserCards - GET /getusercards/?userID=
+func (c *Client) UserCards(ctx context.Context, userID int64) ([]*card.Card, error) {
+	var resp struct {
+		CardsLength int64
+		Cards       []*card.Card
+	}
+	q := url.Values{"userID": {strconv.FormatInt(userID, 10)}}
+	if err := c.do(ctx, http.MethodGet, "/getusercards/?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Cards, nil
+}
+
+// Purchase - покупка картой: холд (POST /authorize) и сразу его подтверждение (POST /capture)
+func (c *Client) Purchase(ctx context.Context, cardID int64, amount card.Money, mcc string) (*card.Transaction, error) {
+	var hold card.Hold
+	if err := c.do(ctx, http.MethodPost, "/authorize", app.AuthorizeParams{CardID: cardID, Amount: amount, MccCode: mcc}, &hold); err != nil {
+		return nil, err
+	}
+	// без amount сервер подтверждает всю сумму холда; нулевая Money разобралась бы как 0 RUB
+	capture := struct {
+		HoldID int64 `json:"hold_id"`
+	}{HoldID: hold.ID}
+	var tr card.Transaction
+	if err := c.do(ctx, http.MethodPost, "/capture", capture, &tr); err != nil {
+		// иначе сумма остаётся заблокированной до истечения холда; ctx к этому моменту может быть уже отменён
+		voidCtx, cancel := context.WithTimeout(context.Background(), voidTimeout)
+		defer cancel()
+		if verr := c.do(voidCtx, http.MethodPost, "/void", app.VoidParams{HoldID: hold.ID}, nil); verr != nil {
+			return nil, fmt.Errorf("capture hold %d: %w (void failed: %v)", hold.ID, err, verr)
+		}
+		return nil, fmt.Errorf("capture hold %d: %w (hold voided)", hold.ID, err)
+	}
+	return &tr, nil
+}
+
+// UserTransactions - транзакции всех карт пользователя
+func (c *Client) UserTransactions(ctx context.Context, userID int64) ([]*card.Transaction, error) {
+	cards, err := c.UserCards(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]*card.Transaction, 0)
+	for _, crd := range cards {
+		transactions = append(transactions, crd.Transactions...)
+	}
+	return transactions, nil
+}
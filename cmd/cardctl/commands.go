@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/wool/go2hw11/cmd/server_new/app"
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// cli - настроенный клиент и вывод для подкоманд
+type cli struct {
+	client *Client
+	format string // formatTable или formatJSON
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// purchase - выпуск карты: по продукту каталога (-product) или по типу и платёжной системе;
+// с адресом доставки - заказ пластиковой карты
+func (c *cli) purchase(ctx context.Context, args []string) error {
+	fs := c.newCommandFlags("purchase")
+	var params app.PurchaseCardParams
+	var address card.Address
+	fs.Int64Var(&params.UserID, "user", 0, "user ID (required)")
+	fs.StringVar(&params.ProductID, "product", "", "catalogue product ID; -type, -issuer and -currency are then ignored")
+	fs.StringVar(&params.CardType, "type", "virtual", "card type: "+strings.Join(card.CardTypes, ", "))
+	fs.StringVar(&params.CardIssuer, "issuer", "Visa", "card issuer: "+strings.Join(card.CardIssuer, ", "))
+	fs.StringVar(&params.Currency, "currency", "", "card currency (default RUB)")
+	fs.StringVar(&address.Recipient, "recipient", "", "delivery recipient, for a plastic card")
+	fs.StringVar(&address.City, "city", "", "delivery city, for a plastic card")
+	fs.StringVar(&address.Street, "street", "", "delivery street, for a plastic card")
+	fs.StringVar(&address.PostalCode, "postal-code", "", "delivery postal code, for a plastic card")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requirePositive(fs, "user", params.UserID); err != nil {
+		return err
+	}
+	if address != (card.Address{}) {
+		params.Address = &address
+	}
+
+	issued, order, err := c.client.PurchaseCard(ctx, params)
+	if err != nil {
+		return err
+	}
+	if order != nil {
+		return c.print(order, func(t *table) {
+			t.row("ORDER", "CARD", "USER", "STATUS", "CITY")
+			t.row(order.ID, order.CardID, order.UserID, order.Status, order.Address.City)
+		})
+	}
+	return c.print(issued, func(t *table) { cardTable(t, []*card.Card{issued}) })
+}
+
+// cards - карты пользователя
+func (c *cli) cards(ctx context.Context, args []string) error {
+	fs := c.newCommandFlags("cards")
+	userID := fs.Int64("user", 0, "user ID (required)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requirePositive(fs, "user", *userID); err != nil {
+		return err
+	}
+
+	cards, err := c.client.UserCards(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	return c.print(cards, func(t *table) { cardTable(t, cards) })
+}
+
+// addTransaction - покупка картой на сумму -amount ("150.50 RUB") с кодом MCC
+func (c *cli) addTransaction(ctx context.Context, args []string) error {
+	fs := c.newCommandFlags("add-transaction")
+	cardID := fs.Int64("card", 0, "card ID (required)")
+	amount := fs.String("amount", "", `purchase amount with currency, e.g. "150.50 RUB" (required)`)
+	mcc := fs.String("mcc", "", "merchant category code, 4 digits (required)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requirePositive(fs, "card", *cardID); err != nil {
+		return err
+	}
+	sum, err := card.ParseMoney(*amount)
+	if err != nil {
+		fmt.Fprintf(fs.Output(), "-amount: %v\n", err)
+		fs.Usage()
+		return errUsage
+	}
+
+	tr, err := c.client.Purchase(ctx, *cardID, sum, *mcc)
+	if err != nil {
+		return err
+	}
+	return c.print(tr, func(t *table) { transactionTable(t, []*card.Transaction{tr}) })
+}
+
+// exportFormats - форматы выгрузки, те же, что у card.ExportToCSV/ExporttoJSON/ExportXML
+var exportFormats = map[string]func([]*card.Transaction) ([]byte, error){
+	"csv":  card.MakeCSV,
+	"json": card.MakeJSON,
+	"xml":  card.MakeXML,
+}
+
+// export - транзакции всех карт пользователя в файл (-file) или stdout; -o не влияет на формат выгрузки
+func (c *cli) export(ctx context.Context, args []string) error {
+	fs := c.newCommandFlags("export")
+	userID := fs.Int64("user", 0, "user ID (required)")
+	format := fs.String("format", "csv", "export format: csv, json or xml")
+	file := fs.String("file", "", "output file (default stdout)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requirePositive(fs, "user", *userID); err != nil {
+		return err
+	}
+	marshal, ok := exportFormats[*format]
+	if !ok {
+		fmt.Fprintf(fs.Output(), "-format: unknown format %q\n", *format)
+		fs.Usage()
+		return errUsage
+	}
+
+	transactions, err := c.client.UserTransactions(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	if len(transactions) == 0 {
+		return fmt.Errorf("user %d has no transactions", *userID)
+	}
+	data, err := marshal(transactions)
+	if err != nil {
+		return err
+	}
+	if *file != "" {
+		return ioutil.WriteFile(*file, data, 0666)
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
+
+// analyticsMethods - F1-F4 дают одинаковый результат и различаются только способом подсчёта
+var analyticsMethods = map[string]func(tr []*card.Transaction, ownerID int64) (card.Spending, error){
+	"f1": card.F1,
+	"f2": card.F2,
+	"f3": card.F3,
+	"f4": card.F4,
+}
+
+// Spending - траты пользователя по категории MCC в одной валюте
+type Spending struct {
+	Category string     `json:"category"`
+	Amount   card.Money `json:"amount"`
+}
+
+// analytics - траты пользователя по категориям, как SpendingByCategory в gRPC, но по выгруженным транзакциям
+func (c *cli) analytics(ctx context.Context, args []string) error {
+	fs := c.newCommandFlags("analytics")
+	userID := fs.Int64("user", 0, "user ID (required)")
+	method := fs.String("method", "f1", "aggregation method: f1, f2, f3 or f4")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requirePositive(fs, "user", *userID); err != nil {
+		return err
+	}
+	aggregate, ok := analyticsMethods[*method]
+	if !ok {
+		fmt.Fprintf(fs.Output(), "-method: unknown method %q\n", *method)
+		fs.Usage()
+		return errUsage
+	}
+
+	transactions, err := c.client.UserTransactions(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	totals, err := aggregate(transactions, *userID)
+	if err != nil {
+		return err
+	}
+	spending := make([]Spending, 0, len(totals))
+	for _, key := range totals.Keys() {
+		spending = append(spending, Spending{Category: key.Category, Amount: totals.Money(key)})
+	}
+	return c.print(spending, func(t *table) {
+		t.row("CATEGORY", "AMOUNT")
+		for _, s := range spending {
+			t.row(s.Category, s.Amount)
+		}
+	})
+}
+
+func cardTable(t *table, cards []*card.Card) {
+	t.row("ID", "NUMBER", "ISSUER", "BANK", "BALANCE", "AVAILABLE", "STATE", "DUE", "TRANSACTIONS")
+	for _, crd := range cards {
+		t.row(crd.ID, crd.CardNumber, crd.Type, crd.BankName, crd.Balance, crd.Available, cardState(crd), crd.CardDueDate, len(crd.Transactions))
+	}
+}
+
+// cardState - active, blocked или inactive (пластик до активации)
+func cardState(crd *card.Card) string {
+	switch {
+	case crd.Blocked:
+		return "blocked"
+	case crd.Inactive:
+		return "inactive"
+	}
+	return "active"
+}
+
+func transactionTable(t *table, transactions []*card.Transaction) {
+	t.row("ID", "TYPE", "AMOUNT", "MCC", "STATUS", "DATE")
+	for _, tr := range transactions {
+		t.row(tr.ID, tr.TranType, tr.TranSum, tr.MccCode, tr.Status, time.Unix(tr.TranDate, 0).UTC().Format(time.RFC3339))
+	}
+}
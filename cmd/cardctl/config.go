@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DefaultServer - адрес сервера, если в файле настроек его нет
+const DefaultServer = "http://localhost:9999"
+
+// Config - файл настроек cardctl:
+//
+//	{"server": "http://localhost:9999", "token": "..."}
+type Config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"` // отправляется как Authorization: Bearer; пусто - без заголовка
+}
+
+// defaultConfigPath - CARDCTL_CONFIG или <каталог настроек пользователя>/cardctl/config.json
+func defaultConfigPath(lookupEnv func(string) (string, bool)) string {
+	if path, ok := lookupEnv("CARDCTL_CONFIG"); ok && path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cardctl", "config.json")
+}
+
+// loadConfig - настройки из path; файла по умолчанию может не быть (required == false), тогда - DefaultServer
+func loadConfig(path string, required bool) (*Config, error) {
+	cfg := &Config{Server: DefaultServer}
+	if path == "" {
+		return cfg, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	if cfg.Server == "" {
+		cfg.Server = DefaultServer
+	}
+	return cfg, nil
+}
@@ -0,0 +1,157 @@
+// Command cardctl - клиент сервера карт (cmd/server_new) из командной строки
+//
+//	cardctl [-config file] [-server url] [-o table|json] <command> [flags]
+//
+// Адрес сервера и токен берутся из файла настроек (см. Config, пример - test/cardctl.json), -server его перекрывает.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// errUsage - неверные аргументы; подсказка уже выведена, код выхода 2
+var errUsage = errors.New("usage")
+
+// command - подкоманда cardctl
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, ctx context.Context, args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "purchase", summary: "issue a card (or order a plastic one) for a user", run: (*cli).purchase},
+		{name: "cards", summary: "list user cards", run: (*cli).cards},
+		{name: "add-transaction", summary: "make a purchase with a card (authorize and capture)", run: (*cli).addTransaction},
+		{name: "export", summary: "export user transactions as csv, json or xml", run: (*cli).export},
+		{name: "analytics", summary: "show user spending by category", run: (*cli).analytics},
+	}
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr, os.LookupEnv))
+}
+
+// run - разбор аргументов и выполнение команды; возвращает код выхода
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) int {
+	fs := flag.NewFlagSet("cardctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "config file (default $CARDCTL_CONFIG or <user config dir>/cardctl/config.json)")
+	server := fs.String("server", "", "server URL, overrides the config file")
+	output := fs.String("o", formatTable, "output format: table or json")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != formatTable && *output != formatJSON {
+		fmt.Fprintf(stderr, "cardctl: unknown output format %q (table or json)\n", *output)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var cmd *command
+	for _, c := range commands() {
+		if c.name == fs.Arg(0) {
+			c := c
+			cmd = &c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "cardctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	path, required := *configPath, true
+	if path == "" {
+		path, required = defaultConfigPath(lookupEnv), false
+	}
+	cfg, err := loadConfig(path, required)
+	if err != nil {
+		fmt.Fprintln(stderr, "cardctl:", err)
+		return 1
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+
+	c := &cli{client: NewClient(cfg), format: *output, stdout: stdout, stderr: stderr}
+	err = cmd.run(c, ctx, fs.Args()[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	}
+	fmt.Fprintln(stderr, "cardctl:", err)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		for _, f := range apiErr.Fields {
+			fmt.Fprintf(stderr, "  %s: %s\n", f.Field, f.Message)
+		}
+	}
+	return 1
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "Usage: cardctl [flags] <command> [command flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	cmds := commands()
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\nRun 'cardctl <command> -h' for command flags.")
+}
+
+// newCommandFlags - флаги подкоманды; ошибки разбора выводятся в stderr
+func (c *cli) newCommandFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("cardctl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse - разбор флагов подкоманды; позиционные аргументы не принимаются
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// requirePositive - обязательный флаг с ID
+func requirePositive(fs *flag.FlagSet, name string, v int64) error {
+	if v <= 0 {
+		fmt.Fprintf(fs.Output(), "-%s is required and must be positive\n", name)
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
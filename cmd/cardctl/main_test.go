@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wool/go2hw11/cmd/server_new/app"
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+// newTestServer - сервер карт с картой 1 пользователя 1; возвращает файл настроек cardctl и последний Authorization
+func newTestServer(t *testing.T) (configPath string, auth *string, cleanup func()) {
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{{ID: 1, UserID: 1, Type: "Visa", CardNumber: "4000 0000 0000 0001", Balance: card.Rub(1000_00), Available: card.Rub(1000_00)}})
+	application := app.NewServer(svc, http.NewServeMux())
+	application.Init()
+	var lastAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuth = r.Header.Get("Authorization")
+		application.ServeHTTP(w, r)
+	}))
+
+	dir, err := ioutil.TempDir("", "cardctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath = filepath.Join(dir, "config.json")
+	content := `{"server": "` + srv.URL + `", "token": "secret"}`
+	if err := ioutil.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return configPath, &lastAuth, func() {
+		srv.Close()
+		application.Shutdown()
+		os.RemoveAll(dir)
+	}
+}
+
+func runCmd(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, &out, &errOut, noEnv)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	configPath, auth, cleanup := newTestServer(t)
+	defer cleanup()
+	cfg := "-config=" + configPath
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want []string // подстроки stdout
+	}{
+		{name: "purchase", args: []string{cfg, "purchase", "-user", "1", "-issuer", "Master"},
+			want: []string{"ID", "NUMBER", "Master", "0.00 RUB", "active"}},
+		{name: "purchase plastic", args: []string{cfg, "purchase", "-user", "1", "-type", "plastic",
+			"-recipient", "Ivan Petrov", "-city", "Moscow", "-street", "Tverskaya 1", "-postal-code", "125009"},
+			want: []string{"ORDER", "Moscow"}},
+		{name: "add transaction", args: []string{cfg, "add-transaction", "-card", "1", "-amount", "150.50 RUB", "-mcc", "5411"},
+			want: []string{"purchase", "150.50 RUB", "5411", "done"}},
+		{name: "cards", args: []string{cfg, "cards", "-user", "1"},
+			want: []string{"4000 0000 0000 0001", "849.50 RUB", "inactive"}},
+		{name: "analytics", args: []string{cfg, "analytics", "-user", "1", "-method", "f3"},
+			want: []string{"CATEGORY", card.TranslateMCC("5411"), "150.50 RUB"}},
+		{name: "export csv", args: []string{cfg, "export", "-user", "1"},
+			want: []string{",purchase,15050,", ",5411,done,1,RUB"}},
+		{name: "export xml", args: []string{cfg, "export", "-user", "1", "-format", "xml"},
+			want: []string{"<?xml", "<mcccode>5411</mcccode>"}},
+		{name: "validation error", args: []string{cfg, "add-transaction", "-card", "1", "-amount", "1 RUB", "-mcc", "54"}, code: 1},
+		{name: "unknown user", args: []string{cfg, "cards", "-user", "42"}, code: 1},
+		{name: "missing user", args: []string{cfg, "cards"}, code: 2},
+		{name: "bad amount", args: []string{cfg, "add-transaction", "-card", "1", "-amount", "150", "-mcc", "5411"}, code: 2},
+		{name: "unknown command", args: []string{cfg, "transfer"}, code: 2},
+		{name: "unknown output", args: []string{cfg, "-o", "yaml", "cards", "-user", "1"}, code: 2},
+		{name: "missing config", args: []string{"-config", configPath + ".missing", "cards", "-user", "1"}, code: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCmd(t, tt.args...)
+			if code != tt.code {
+				t.Fatalf("run(%v) = %d, want %d\nstdout: %s\nstderr: %s", tt.args, code, tt.code, stdout, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("run(%v) stdout = %q, want it to contain %q", tt.args, stdout, want)
+				}
+			}
+		})
+	}
+
+	if *auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", *auth, "Bearer secret")
+	}
+}
+
+func TestRun_JSONOutput(t *testing.T) {
+	configPath, _, cleanup := newTestServer(t)
+	defer cleanup()
+
+	code, stdout, stderr := runCmd(t, "-config", configPath, "-o", "json", "cards", "-user", "1")
+	if code != 0 {
+		t.Fatalf("run() = %d, stderr: %s", code, stderr)
+	}
+	var cards []*card.Card
+	if err := json.Unmarshal([]byte(stdout), &cards); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout)
+	}
+	if len(cards) != 1 || cards[0].ID != 1 || cards[0].Balance != card.Rub(1000_00) {
+		t.Errorf("cards = %+v", cards)
+	}
+
+	// ошибки проверки запроса выводятся по полям
+	code, _, stderr = runCmd(t, "-config", configPath, "-o", "json", "add-transaction", "-card", "1", "-amount", "1 RUB", "-mcc", "54")
+	if code != 1 || !strings.Contains(stderr, "mcc: must be 4 digits") {
+		t.Errorf("run() = %d, stderr: %s", code, stderr)
+	}
+}
+
+func TestClient_PurchaseVoidsHoldOnCaptureFailure(t *testing.T) {
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{{ID: 1, UserID: 1, Balance: card.Rub(1000_00), Available: card.Rub(1000_00)}})
+	application := app.NewServer(svc, http.NewServeMux())
+	application.Init()
+	defer application.Shutdown()
+	var voided bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/capture":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		case "/void":
+			voided = true
+		}
+		application.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(&Config{Server: srv.URL}).Purchase(context.Background(), 1, card.Rub(10_00), "5411")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("Purchase() error = %v, want the capture error", err)
+	}
+	if c, _ := svc.SearchByID(1); !voided || c.Available != card.Rub(1000_00) {
+		t.Errorf("voided = %v, available = %v, want the hold released", voided, c.Available)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cardctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		required bool
+		want     Config
+		wantErr  bool
+	}{
+		{name: "full", path: write("full.json", `{"server": "https://cards.example.com", "token": "t"}`), required: true,
+			want: Config{Server: "https://cards.example.com", Token: "t"}},
+		{name: "token only", path: write("token.json", `{"token": "t"}`), required: true, want: Config{Server: DefaultServer, Token: "t"}},
+		{name: "default missing", path: filepath.Join(dir, "none.json"), want: Config{Server: DefaultServer}},
+		{name: "explicit missing", path: filepath.Join(dir, "none.json"), required: true, wantErr: true},
+		{name: "unknown field", path: write("typo.json", `{"sever": "x"}`), required: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfig(tt.path, tt.required)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *cfg != tt.want {
+				t.Errorf("loadConfig() = %+v, want %+v", *cfg, tt.want)
+			}
+		})
+	}
+
+	env := func(string) (string, bool) { return "/etc/cardctl.json", true }
+	if path := defaultConfigPath(env); path != "/etc/cardctl.json" {
+		t.Errorf("defaultConfigPath() = %q, want $CARDCTL_CONFIG", path)
+	}
+}
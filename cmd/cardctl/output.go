@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// table - колонки, выровненные пробелами
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (t *table) row(cells ...interface{}) {
+	s := make([]string, len(cells))
+	for i, c := range cells {
+		s[i] = fmt.Sprint(c)
+	}
+	fmt.Fprintln(t.w, strings.Join(s, "\t"))
+}
+
+// print - v как JSON (-o json) или таблица, которую заполняет fill
+func (c *cli) print(v interface{}, fill func(t *table)) error {
+	if c.format == formatJSON {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	t := &table{w: tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)}
+	fill(t)
+	return t.w.Flush()
+}
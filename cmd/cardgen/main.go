@@ -0,0 +1,127 @@
+// Command cardgen - синтетические карты (фикстура для -seed fixture) и транзакции (pkg/datagen) в файл или stdout
+//
+//	cardgen [-seed 1] [-users 10] [-kind cards|transactions] [-format json|csv|xml] [-out file]
+//
+// Один и тот же -seed с теми же флагами даёт те же данные.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/datagen"
+)
+
+const (
+	kindCards        = "cards"
+	kindTransactions = "transactions"
+)
+
+// transactionFormats - форматы выгрузки транзакций, как у экспорта в pkg/card
+var transactionFormats = map[string]func([]*card.Transaction) ([]byte, error){
+	"csv":  card.MakeCSV,
+	"json": card.MakeJSON,
+	"xml":  card.MakeXML,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run - разбор флагов и генерация; возвращает код выхода
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cardgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defaults := datagen.DefaultOptions()
+	opts := defaults
+	fs.Int64Var(&opts.Seed, "seed", defaults.Seed, "random seed")
+	fs.IntVar(&opts.Users, "users", defaults.Users, "number of users (owners for -count)")
+	fs.IntVar(&opts.MaxCardsPerUser, "cards", defaults.MaxCardsPerUser, "maximum cards per user")
+	fs.IntVar(&opts.TransactionsPerCard, "transactions", defaults.TransactionsPerCard, "average transactions per card")
+	fs.StringVar(&opts.BankName, "bank", defaults.BankName, "bank name on cards")
+	from := fs.String("from", defaults.From.Format(dateLayout), "first day of transactions, YYYY-MM-DD")
+	to := fs.String("to", defaults.To.Format(dateLayout), "day after the last day of transactions, YYYY-MM-DD")
+	kind := fs.String("kind", kindCards, "what to generate: cards with transactions (a seed fixture) or transactions")
+	format := fs.String("format", "json", "output format: json, csv or xml")
+	count := fs.Int("count", 0, "with -kind transactions: exactly this many transactions of -users owners, without cards")
+	out := fs.String("out", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	fail := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, "cardgen: "+format+"\n", args...)
+		return 2
+	}
+	if fs.NArg() > 0 {
+		return fail("unexpected arguments: %v", fs.Args())
+	}
+	var err error
+	if opts.From, err = time.Parse(dateLayout, *from); err != nil {
+		return fail("-from: %v", err)
+	}
+	if opts.To, err = time.Parse(dateLayout, *to); err != nil {
+		return fail("-to: %v", err)
+	}
+	switch {
+	case *kind != kindCards && *kind != kindTransactions:
+		return fail("-kind: unknown kind %q", *kind)
+	case transactionFormats[*format] == nil:
+		return fail("-format: unknown format %q", *format)
+	case *count < 0:
+		return fail("-count: must not be negative")
+	case *count > 0 && *kind != kindTransactions:
+		return fail("-count requires -kind transactions")
+	}
+
+	g, err := datagen.New(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "cardgen:", err)
+		return 2
+	}
+	data, err := generate(g, *kind, *format, *count, opts.Users)
+	if err == nil {
+		if *out != "" {
+			err = ioutil.WriteFile(*out, data, 0666)
+		} else {
+			_, err = stdout.Write(data)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "cardgen:", err)
+		return 1
+	}
+	return 0
+}
+
+const dateLayout = "2006-01-02"
+
+func generate(g *datagen.Generator, kind, format string, count, owners int) ([]byte, error) {
+	if kind == kindCards {
+		// формат фикстуры: его читает сервер с -seed fixture
+		var buf bytes.Buffer
+		err := card.WriteFixture(&buf, g.Cards(), format)
+		return buf.Bytes(), err
+	}
+	var transactions []*card.Transaction
+	if count > 0 {
+		transactions = g.Transactions(count, owners)
+	} else {
+		for _, c := range g.Cards() {
+			transactions = append(transactions, c.Transactions...)
+		}
+	}
+	if len(transactions) == 0 {
+		return nil, errors.New("no transactions generated")
+	}
+	return transactionFormats[format](transactions)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func runCmd(args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	code, stdout, stderr := runCmd("-seed", "3", "-users", "4")
+	if code != 0 {
+		t.Fatalf("run() = %d, stderr: %s", code, stderr)
+	}
+	var cards []*card.Card
+	if err := json.Unmarshal([]byte(stdout), &cards); err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) < 4 || cards[len(cards)-1].UserID != 4 {
+		t.Errorf("cards = %d, last user %d", len(cards), cards[len(cards)-1].UserID)
+	}
+	if _, again, _ := runCmd("-seed", "3", "-users", "4"); again != stdout {
+		t.Error("same seed produced different output")
+	}
+
+	// карты в любом формате - корректная фикстура
+	for _, format := range []string{card.FixtureCSV, card.FixtureXML} {
+		code, stdout, stderr = runCmd("-seed", "3", "-users", "4", "-format", format)
+		if code != 0 {
+			t.Fatalf("run(-format %s) = %d, stderr: %s", format, code, stderr)
+		}
+		fixture, err := card.ReadFixture(strings.NewReader(stdout), format)
+		if err != nil || len(fixture) != len(cards) {
+			t.Errorf("ReadFixture(%s) = %d cards, %v; want %d", format, len(fixture), err, len(cards))
+		}
+	}
+
+	code, stdout, stderr = runCmd("-kind", "transactions", "-format", "csv", "-count", "250", "-users", "5",
+		"-from", "2021-03-01", "-to", "2021-04-01")
+	if code != 0 {
+		t.Fatalf("run() = %d, stderr: %s", code, stderr)
+	}
+	transactions, err := card.ReadCSV(strings.NewReader(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 250 {
+		t.Errorf("transactions = %d, want 250", len(transactions))
+	}
+	if months := card.MakeTransMap(transactions); len(months) != 1 || len(months["2021 03"]) != 250 {
+		t.Errorf("transactions are not all in 2021 03: %d months", len(months))
+	}
+
+	errorCases := [][]string{
+		{"-kind", "users"},
+		{"-format", "yaml"},
+		{"-kind", "transactions", "-format", "yaml"},
+		{"-count", "10"},
+		{"-from", "2021-13-01"},
+		{"-from", "2021-01-01", "-to", "2020-01-01"},
+		{"-cards", "0"},
+	}
+	for _, args := range errorCases {
+		if code, _, stderr := runCmd(args...); code != 2 || stderr == "" {
+			t.Errorf("run(%v) = %d, stderr %q; want 2", args, code, stderr)
+		}
+	}
+}
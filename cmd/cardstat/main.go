@@ -0,0 +1,209 @@
+// Command cardstat - отчёты по выгрузкам транзакций (CSV, JSON, XML) без сервера
+//
+//	cardstat [flags] [file ...]
+//
+// Без файлов (или с файлом "-") читает stdin. Печатает траты по категориям (как F1) и помесячные итоги
+// либо, с -convert, перекладывает транзакции в другой формат.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// errUsage - неверные аргументы; подсказка уже выведена, код выхода 2
+var errUsage = errors.New("usage")
+
+// readers - форматы выгрузки и функции импорта для них
+var readers = map[string]func(io.Reader) ([]*card.Transaction, error){
+	"csv":  card.ReadCSV,
+	"json": card.ReadJSON,
+	"xml":  card.ReadXML,
+}
+
+// writers - форматы для -convert, те же, что у экспорта
+var writers = map[string]func([]*card.Transaction) ([]byte, error){
+	"csv":  card.MakeCSV,
+	"json": card.MakeJSON,
+	"xml":  card.MakeXML,
+}
+
+// options - разобранные флаги
+type options struct {
+	in      string // формат входа; пусто - по расширению файла
+	convert string // формат выхода; пусто - отчёт
+	out     string // файл для -convert; пусто - stdout
+	reports []string
+	method  string
+	owner   int64
+	files   []string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run - разбор аргументов, чтение выгрузок, отчёт или конвертация; возвращает код выхода
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	transactions, err := readAll(opts, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "cardstat:", err)
+		return 1
+	}
+	if opts.owner != 0 {
+		transactions = ownedBy(transactions, opts.owner)
+	}
+	if opts.convert != "" {
+		err = convert(stdout, transactions, opts)
+	} else {
+		err = report(stdout, transactions, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "cardstat:", err)
+		return 1
+	}
+	return 0
+}
+
+func parseFlags(args []string, stderr io.Writer) (*options, error) {
+	fs := flag.NewFlagSet("cardstat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts := &options{}
+	fs.StringVar(&opts.in, "in", "", "input format: csv, json or xml (default by file extension; required for stdin)")
+	fs.StringVar(&opts.convert, "convert", "", "write transactions in this format (csv, json or xml) instead of reports")
+	fs.StringVar(&opts.out, "out", "", "output file for -convert (default stdout)")
+	reports := fs.String("report", "category,monthly", "comma-separated reports: category, monthly")
+	fs.StringVar(&opts.method, "method", "f1", "category aggregation: f1, f2, f3, f4, or all to compare them")
+	fs.Int64Var(&opts.owner, "owner", 0, "only transactions of this owner, for reports and -convert (default all owners)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cardstat [flags] [file ...]\n\nReads transaction dumps from files or stdin (\"-\").\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.files = fs.Args()
+
+	fail := func(format string, args ...interface{}) (*options, error) {
+		fmt.Fprintf(fs.Output(), format+"\n", args...)
+		fs.Usage()
+		return nil, errUsage
+	}
+	if _, ok := readers[opts.in]; opts.in != "" && !ok {
+		return fail("-in: unknown format %q", opts.in)
+	}
+	if _, ok := writers[opts.convert]; opts.convert != "" && !ok {
+		return fail("-convert: unknown format %q", opts.convert)
+	}
+	if opts.out != "" && opts.convert == "" {
+		return fail("-out requires -convert")
+	}
+	if _, ok := methods[opts.method]; !ok && opts.method != methodAll {
+		return fail("-method: unknown method %q", opts.method)
+	}
+	if opts.owner < 0 {
+		return fail("-owner must be positive")
+	}
+	for _, r := range strings.Split(*reports, ",") {
+		r = strings.TrimSpace(r)
+		if r != reportCategory && r != reportMonthly {
+			return fail("-report: unknown report %q", r)
+		}
+		opts.reports = append(opts.reports, r)
+	}
+	return opts, nil
+}
+
+// readAll - транзакции из всех файлов по порядку; "-" или отсутствие файлов - stdin
+func readAll(opts *options, stdin io.Reader) ([]*card.Transaction, error) {
+	files := opts.files
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	transactions := make([]*card.Transaction, 0)
+	for _, name := range files {
+		format, err := inputFormat(name, opts.in)
+		if err != nil {
+			return nil, err
+		}
+		var trans []*card.Transaction
+		if name == "-" {
+			trans, err = readers[format](stdin)
+		} else {
+			trans, err = readFile(name, readers[format])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		transactions = append(transactions, trans...)
+	}
+	return transactions, nil
+}
+
+func readFile(name string, read func(io.Reader) ([]*card.Transaction, error)) ([]*card.Transaction, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+// inputFormat - формат из -in или по расширению файла
+func inputFormat(name, in string) (string, error) {
+	if in != "" {
+		return in, nil
+	}
+	if name == "-" {
+		return "", errors.New("stdin: -in is required")
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if _, ok := readers[ext]; !ok {
+		return "", fmt.Errorf("%s: cannot detect format from extension, use -in", name)
+	}
+	return ext, nil
+}
+
+func displayName(name string) string {
+	if name == "-" {
+		return "stdin"
+	}
+	return name
+}
+
+// convert - транзакции в формате -convert в файл -out или w
+func convert(w io.Writer, transactions []*card.Transaction, opts *options) error {
+	data, err := marshal(transactions, opts.convert)
+	if err != nil {
+		return err
+	}
+	if opts.out != "" {
+		return ioutil.WriteFile(opts.out, data, 0666)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// marshal - MakeCSV/MakeJSON/MakeXML; пустую выгрузку они не пишут
+func marshal(transactions []*card.Transaction, format string) ([]byte, error) {
+	if len(transactions) == 0 {
+		return nil, errors.New("no transactions to convert")
+	}
+	return writers[format](transactions)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func testTransactions() []*card.Transaction {
+	date := func(month time.Month) int64 { return time.Date(2020, month, 15, 12, 0, 0, 0, time.UTC).Unix() }
+	return []*card.Transaction{
+		{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: card.Rub(100_00), TranDate: date(1), MccCode: "5411", Status: card.StatusDone},
+		{ID: 2, TranType: "purchase", OwnerID: 2, TranSum: card.Rub(50_50), TranDate: date(1), MccCode: "5912", Status: card.StatusDone},
+		{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: card.Rub(200_00), TranDate: date(2), MccCode: "5411", Status: card.StatusDone},
+		{ID: 4, TranType: "purchase", OwnerID: 3, TranSum: card.Rub(10_00), TranDate: date(2), MccCode: "5533", Status: card.StatusDone},
+	}
+}
+
+// writeDumps - выгрузка testTransactions во всех форматах; возвращает каталог
+func writeDumps(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cardstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, marshal := range writers {
+		data, err := marshal(testTransactions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "dump."+format), data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runCmd(args []string, stdin string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_Report(t *testing.T) {
+	dir := writeDumps(t)
+	defer os.RemoveAll(dir)
+	csvDump, err := ioutil.ReadFile(filepath.Join(dir, "dump.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    []string
+		notWant []string
+	}{
+		{name: "csv", args: []string{filepath.Join(dir, "dump.csv")},
+			want: []string{"4 transactions", "Супермаркеты  300.00 RUB", "Аптеки        50.50 RUB", "2020 01  RUB       2             150.50 RUB", "2020 02  RUB       2             210.00 RUB"}},
+		{name: "json", args: []string{filepath.Join(dir, "dump.json")}, want: []string{"Супермаркеты  300.00 RUB"}},
+		{name: "xml", args: []string{filepath.Join(dir, "dump.xml")}, want: []string{"Супермаркеты  300.00 RUB"}},
+		{name: "stdin", args: []string{"-in", "csv"}, stdin: string(csvDump), want: []string{"Супермаркеты  300.00 RUB"}},
+		{name: "several files", args: []string{filepath.Join(dir, "dump.csv"), filepath.Join(dir, "dump.json")},
+			want: []string{"8 transactions", "Супермаркеты  600.00 RUB"}},
+		{name: "owner", args: []string{"-owner", "3", "-report", "category", filepath.Join(dir, "dump.json")},
+			want: []string{"1 transactions", "Автоуслуги  10.00 RUB"}, notWant: []string{"Супермаркеты", "Monthly"}},
+		{name: "monthly only", args: []string{"-report", "monthly", filepath.Join(dir, "dump.json")},
+			want: []string{"Monthly totals"}, notWant: []string{"Spending by category"}},
+		{name: "compare methods", args: []string{"-method", "all", filepath.Join(dir, "dump.xml")},
+			want: []string{"Spending by category (all)", "Супермаркеты  300.00 RUB", "METHOD", "f1", "f4"}},
+		{name: "method f3", args: []string{"-method", "f3", filepath.Join(dir, "dump.xml")},
+			want: []string{"Spending by category (f3)"}, notWant: []string{"METHOD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCmd(tt.args, tt.stdin)
+			if code != 0 {
+				t.Fatalf("run(%v) = %d, stderr: %s", tt.args, code, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("stdout contains %q:\n%s", notWant, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryTotals(t *testing.T) {
+	calls := 0
+	method := func(tr []*card.Transaction, ownerID int64) (card.Spending, error) {
+		calls++
+		for _, v := range tr {
+			if v.OwnerID != ownerID {
+				t.Errorf("method(owner %d) got a transaction of owner %d", ownerID, v.OwnerID)
+			}
+		}
+		return card.F1(tr, ownerID)
+	}
+	got, err := categoryTotals(testTransactions(), method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := card.Spending{
+		{Category: card.TranslateMCC("5411"), Currency: card.RUB}: 300_00,
+		{Category: card.TranslateMCC("5912"), Currency: card.RUB}: 50_50,
+		{Category: card.TranslateMCC("5533"), Currency: card.RUB}: 10_00,
+	}
+	if !reflect.DeepEqual(got, want) || calls != 2 {
+		t.Errorf("categoryTotals() = %v after %d calls, want %v after 2", got, calls, want)
+	}
+}
+
+func TestRun_Convert(t *testing.T) {
+	dir := writeDumps(t)
+	defer os.RemoveAll(dir)
+
+	// csv -> xml в файл -> json в stdout: транзакции не меняются
+	xmlPath := filepath.Join(dir, "converted.xml")
+	if code, _, stderr := runCmd([]string{"-convert", "xml", "-out", xmlPath, filepath.Join(dir, "dump.csv")}, ""); code != 0 {
+		t.Fatalf("convert to xml = %d, stderr: %s", code, stderr)
+	}
+	code, stdout, stderr := runCmd([]string{"-convert", "json", xmlPath}, "")
+	if code != 0 {
+		t.Fatalf("convert to json = %d, stderr: %s", code, stderr)
+	}
+	got, err := card.ReadJSON(strings.NewReader(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testTransactions()
+	if len(got) != len(want) {
+		t.Fatalf("converted %d transactions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("transaction %d = %+v, want %+v", i, *got[i], *want[i])
+		}
+	}
+
+	code, stdout, _ = runCmd([]string{"-convert", "csv", "-owner", "3", filepath.Join(dir, "dump.json")}, "")
+	if code != 0 || strings.Count(stdout, "\n") != 1 || !strings.HasPrefix(stdout, "4,purchase,1000,") {
+		t.Errorf("convert with -owner = %d %q", code, stdout)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	dir := writeDumps(t)
+	defer os.RemoveAll(dir)
+	txt := filepath.Join(dir, "dump.txt")
+	if err := ioutil.WriteFile(txt, []byte("1,2,3"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		{name: "stdin without format", code: 1, stderr: "-in is required"},
+		{name: "unknown extension", args: []string{txt}, code: 1, stderr: "cannot detect format"},
+		{name: "short csv row", args: []string{"-in", "csv", txt}, code: 1, stderr: "line 1 has 3 columns"},
+		{name: "malformed json", args: []string{"-in", "json"}, stdin: "[{", code: 1, stderr: "stdin: " + card.ErrInvalidStatement.Error()},
+		{name: "missing file", args: []string{filepath.Join(dir, "none.csv")}, code: 1, stderr: "none.csv"},
+		{name: "empty convert", args: []string{"-in", "json", "-convert", "csv"}, stdin: "[]", code: 1, stderr: "no transactions"},
+		{name: "unknown method", args: []string{"-method", "f5"}, code: 2, stderr: "unknown method"},
+		{name: "unknown report", args: []string{"-report", "weekly"}, code: 2, stderr: "unknown report"},
+		{name: "out without convert", args: []string{"-out", "x.csv"}, code: 2, stderr: "-out requires -convert"},
+		{name: "help", args: []string{"-h"}, code: 0, stderr: "Usage: cardstat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCmd(tt.args, tt.stdin)
+			if code != tt.code || !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("run(%v) = %d, stderr %q; want %d, %q", tt.args, code, stderr, tt.code, tt.stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+const (
+	reportCategory = "category"
+	reportMonthly  = "monthly"
+
+	methodAll = "all"
+)
+
+// methods - F1-F4 считают одно и то же разными способами (в лоб, мьютекс, каналы, части с мьютексом)
+var methods = map[string]func(tr []*card.Transaction, ownerID int64) (card.Spending, error){
+	"f1": card.F1,
+	"f2": card.F2,
+	"f3": card.F3,
+	"f4": card.F4,
+}
+
+// ownedBy - транзакции одного владельца
+func ownedBy(transactions []*card.Transaction, ownerID int64) []*card.Transaction {
+	owned := make([]*card.Transaction, 0)
+	for _, tr := range transactions {
+		if tr.OwnerID == ownerID {
+			owned = append(owned, tr)
+		}
+	}
+	return owned
+}
+
+// report - выбранные отчёты один за другим
+func report(w io.Writer, transactions []*card.Transaction, opts *options) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d transactions\n", len(transactions))
+	for _, r := range opts.reports {
+		fmt.Fprintln(tw)
+		var err error
+		switch r {
+		case reportCategory:
+			err = categoryReport(tw, transactions, opts.method)
+		case reportMonthly:
+			err = monthlyReport(tw, transactions)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// categoryTotals - траты по категориям методом method; F1-F4 считают одного владельца, поэтому транзакции
+// один раз раскладываются по владельцам и метод считает каждую группу, а не всю выгрузку заново
+func categoryTotals(transactions []*card.Transaction, method func(tr []*card.Transaction, ownerID int64) (card.Spending, error)) (card.Spending, error) {
+	totals := make(card.Spending)
+	ids, groups := groupByOwner(transactions)
+	for _, owner := range ids {
+		spending, err := method(groups[owner], owner)
+		if err != nil {
+			return nil, fmt.Errorf("owner %d: %w", owner, err)
+		}
+		if err := totals.Merge(spending); err != nil {
+			return nil, err
+		}
+	}
+	return totals, nil
+}
+
+// groupByOwner - транзакции по владельцам и владельцы по возрастанию ID
+func groupByOwner(transactions []*card.Transaction) ([]int64, map[int64][]*card.Transaction) {
+	groups := make(map[int64][]*card.Transaction)
+	ids := make([]int64, 0)
+	for _, tr := range transactions {
+		if _, ok := groups[tr.OwnerID]; !ok {
+			ids = append(ids, tr.OwnerID)
+		}
+		groups[tr.OwnerID] = append(groups[tr.OwnerID], tr)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, groups
+}
+
+// categoryReport - траты по категориям MCC; с -method all - ещё и время каждого метода, результаты должны совпасть
+func categoryReport(w io.Writer, transactions []*card.Transaction, method string) error {
+	names := []string{method}
+	if method == methodAll {
+		names = []string{"f1", "f2", "f3", "f4"}
+	}
+	var totals card.Spending
+	elapsed := make([]time.Duration, len(names))
+	for i, name := range names {
+		start := time.Now()
+		got, err := categoryTotals(transactions, methods[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		elapsed[i] = time.Since(start)
+		if totals != nil && !reflect.DeepEqual(got, totals) {
+			return fmt.Errorf("%s result differs from %s: %v != %v", name, names[0], got, totals)
+		}
+		totals = got
+	}
+
+	fmt.Fprintf(w, "Spending by category (%s)\n", method)
+	fmt.Fprintln(w, "CATEGORY\tAMOUNT")
+	for _, key := range totals.Keys() {
+		fmt.Fprintf(w, "%s\t%s\n", key.Category, totals.Money(key))
+	}
+	if method == methodAll {
+		fmt.Fprintln(w, "\nMETHOD\tDURATION")
+		for i, name := range names {
+			fmt.Fprintf(w, "%s\t%v\n", name, elapsed[i])
+		}
+	}
+	return nil
+}
+
+// monthlyReport - итоги по месяцам (MakeTransMap) отдельно по каждой валюте
+func monthlyReport(w io.Writer, transactions []*card.Transaction) error {
+	byMonth := card.MakeTransMap(transactions)
+	months := make([]string, 0, len(byMonth))
+	for month := range byMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	fmt.Fprintln(w, "Monthly totals")
+	fmt.Fprintln(w, "MONTH\tCURRENCY\tTRANSACTIONS\tTOTAL")
+	for _, month := range months {
+		byCurrency := make(map[card.Currency][]card.Money)
+		for _, m := range byMonth[month] {
+			byCurrency[m.Currency] = append(byCurrency[m.Currency], m)
+		}
+		currencies := make([]string, 0, len(byCurrency))
+		for c := range byCurrency {
+			currencies = append(currencies, string(c))
+		}
+		sort.Strings(currencies)
+		for _, c := range currencies {
+			sums := byCurrency[card.Currency(c)]
+			total, err := card.Sum(sums)
+			if err != nil {
+				return fmt.Errorf("month %s: %w", month, err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", month, c, len(sums), total)
+		}
+	}
+	return nil
+}
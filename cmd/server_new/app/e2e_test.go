@@ -0,0 +1,457 @@
+// Сквозные тесты app.Server через httptest: сценарий из test/requests.http с эталонными ответами (testdata/*.golden.json),
+// ошибки на каждом маршруте и параллельные запросы
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// go test ./cmd/server_new/app -run E2E -update - переписать эталоны по текущим ответам
+var update = flag.Bool("update", false, "rewrite testdata/*.golden.json from the current responses")
+
+// e2eNow - время сервера в /echo и /time
+var e2eNow = time.Date(2021, 3, 1, 9, 30, 0, 0, time.UTC)
+
+// newE2EServer - сервер с фиксированными картами: 1 - пользователь 1, 2 и 3 - пользователь 2 (3 - долларовая)
+func newE2EServer(t *testing.T) (*card.Service, *Server, *httptest.Server) {
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{
+		{ID: 1, UserID: 1, Type: "Visa", BankName: "Tinkoff", CardNumber: cardNumber(t, "400000", 1), CardDueDate: "2030-01-01",
+			Balance: card.Rub(1000_00)},
+		{ID: 2, UserID: 2, Type: "Master", BankName: "Tinkoff", CardNumber: cardNumber(t, "510000", 2), CardDueDate: "2030-01-01",
+			Balance: card.Rub(500_00)},
+		{ID: 3, UserID: 2, Type: "Visa", BankName: "Tinkoff", CardNumber: cardNumber(t, "400150", 3), CardDueDate: "2030-01-01",
+			Balance: card.NewMoney(100_00, card.USD), IsVirtual: true},
+	})
+	application := NewServer(svc, http.NewServeMux())
+	application.now = func() time.Time { return e2eNow }
+	application.Init()
+	srv := httptest.NewServer(application)
+	t.Cleanup(func() {
+		application.Shutdown()
+		srv.Close()
+	})
+	return svc, application, srv
+}
+
+func cardNumber(t *testing.T, bin string, cardID int64) string {
+	number, err := card.CardNumber(bin, cardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return number
+}
+
+// e2eStep - запрос и ожидаемый ответ
+type e2eStep struct {
+	name     string
+	method   string
+	path     string
+	body     string
+	status   int
+	golden   string   // эталон testdata/<golden>.golden.json; пусто - тело проверяется по contains
+	volatile []string // поля, зависящие от текущего времени: в эталоне заменяются на "<volatile>"
+	contains string   // часть тела ответа
+}
+
+const address = `"address": {"recipient": "Ivan Ivanov", "city": "Moscow", "street": "Tverskaya 1", "postal_code": "125009"}`
+
+// e2eScenario - шаги test/requests.http по порядку; каждый следующий видит изменения предыдущих
+var e2eScenario = []e2eStep{
+	{name: "purchase card", method: "POST", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "Visa", "user_id": 2}`,
+		status: 200, golden: "purchase_card", volatile: []string{"CardDueDate"}}, // срок карты продукта - от текущей даты
+	{name: "invalid card type", method: "POST", path: "/purchaseCard", body: `{"card_type": "virtual2", "card_issuer": "Visa", "user_id": 1}`,
+		status: 400, golden: "purchase_card_invalid_type"},
+	{name: "invalid card issuer", method: "POST", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "Visa2", "user_id": 1}`,
+		status: 400, golden: "purchase_card_invalid_issuer"},
+	{name: "unknown user", method: "POST", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "Visa", "user_id": 555}`,
+		status: 400, contains: "user 555 does not exist"},
+	{name: "user cards", method: "GET", path: "/getusercards/?userID=2", status: 200, golden: "user_cards", volatile: []string{"CardDueDate"}},
+	{name: "products", method: "GET", path: "/products", status: 200, golden: "products"},
+	{name: "purchase product", method: "POST", path: "/purchaseCard", body: `{"product_id": "virtual-visa-usd", "user_id": 2}`,
+		status: 200, golden: "purchase_product", volatile: []string{"CardDueDate"}},
+	{name: "order plastic card", method: "POST", path: "/purchaseCard", body: `{"product_id": "plastic-master", "user_id": 2, ` + address + `}`,
+		status: 200, golden: "card_order", volatile: []string{"time"}},
+	{name: "card order", method: "GET", path: "/cardOrder?cardID=6", status: 200, golden: "card_order", volatile: []string{"time"}},
+	{name: "activate before delivery", method: "POST", path: "/activateCard", body: `{"card_id": 6}`, status: 409,
+		contains: "not delivered"},
+	{name: "unknown product", method: "POST", path: "/purchaseCard", body: `{"product_id": "metal-amex", "user_id": 2}`, status: 404},
+	{name: "purchase usd card", method: "POST", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "UnionPay", "user_id": 2, "currency": "USD"}`,
+		status: 200, golden: "purchase_card_usd"},
+	{name: "authorize", method: "POST", path: "/authorize", body: `{"card_id": 1, "amount": {"amount": 50000, "currency": "RUB"}, "mcc": "5411", "ttl_seconds": 600}`,
+		status: 200, golden: "authorize", volatile: []string{"created_at", "expires_at"}},
+	{name: "capture part", method: "POST", path: "/capture", body: `{"hold_id": 1, "amount": {"amount": 45000, "currency": "RUB"}}`,
+		status: 200, golden: "capture", volatile: []string{"trandate"}},
+	{name: "void captured", method: "POST", path: "/void", body: `{"hold_id": 1}`, status: 409},
+	{name: "refund part", method: "POST", path: "/refundTransaction", body: `{"transaction_id": 1, "amount": {"amount": 10000, "currency": "RUB"}}`,
+		status: 200, golden: "refund", volatile: []string{"trandate"}},
+	{name: "reverse after refund", method: "POST", path: "/reverseTransaction", body: `{"transaction_id": 1}`, status: 400},
+	{name: "authorize again", method: "POST", path: "/authorize", body: `{"card_id": 1, "amount": {"amount": 30000, "currency": "RUB"}, "mcc": "5812"}`,
+		status: 200},
+	{name: "void", method: "POST", path: "/void", body: `{"hold_id": 2}`, status: 200, golden: "void",
+		volatile: []string{"created_at", "expires_at"}},
+	{name: "authorize over balance", method: "POST", path: "/authorize", body: `{"card_id": 1, "amount": {"amount": 100000000000, "currency": "RUB"}, "mcc": "5411"}`,
+		status: 409, contains: "balance"},
+	{name: "reverse unknown", method: "POST", path: "/reverseTransaction", body: `{"transaction_id": 99}`, status: 404},
+	{name: "reconciliation", method: "GET", path: "/reconciliation", status: 200, golden: "reconciliation"},
+	{name: "set limits", method: "POST", path: "/limits",
+		body:   `{"card_id": 2, "limits": {"per_transaction": {"amount": 100000, "currency": "RUB"}, "daily": {"amount": 500000, "currency": "RUB"}, "deny_mcc_groups": ["gambling"]}}`,
+		status: 200, golden: "limits"},
+	{name: "limits", method: "GET", path: "/limits?cardID=2", status: 200, golden: "limits"},
+	{name: "gambling denied", method: "POST", path: "/authorize", body: `{"card_id": 2, "amount": {"amount": 100, "currency": "RUB"}, "mcc": "7995"}`,
+		status: 403},
+	{name: "block card", method: "POST", path: "/blockCard", body: `{"card_id": 2}`, status: 200, golden: "block_card"},
+	{name: "authorize blocked", method: "POST", path: "/authorize", body: `{"card_id": 2, "amount": {"amount": 100, "currency": "RUB"}, "mcc": "5411"}`,
+		status: 403},
+	{name: "unblock card", method: "POST", path: "/unblockCard", body: `{"card_id": 2}`, status: 200, golden: "unblock_card"},
+	{name: "user events", method: "GET", path: "/users/555/events", status: 404, contains: "user 555 does not exist"},
+	{name: "metrics", method: "GET", path: "/metrics", status: 200, contains: `route="/blockCard"`},
+	{name: "healthz", method: "GET", path: "/healthz", status: 200, golden: "healthz"},
+	{name: "readyz", method: "GET", path: "/readyz", status: 200, golden: "readyz"},
+	{name: "version", method: "GET", path: "/version", status: 200, golden: "version", volatile: []string{"go_version"}},
+	{name: "time in Moscow", method: "GET", path: "/time?tz=Moscow", status: 200, golden: "time_moscow"},
+	{name: "time in Tokyo", method: "GET", path: "/time?tz=Asia/Tokyo", status: 200, golden: "time_tokyo"},
+	{name: "unknown time zone", method: "GET", path: "/time?tz=Mars", status: 400, contains: `unknown time zone "Mars"`},
+	{name: "echo", method: "GET", path: "/echo", status: 200, contains: "ECHO 2021-03-01 12:30:00 +0300 MSK"},
+	{name: "openapi", method: "GET", path: "/openapi.json", status: 200, contains: `"openapi"`},
+	{name: "unknown field", method: "POST", path: "/purchaseCard", body: `{"card_type": "metal", "userid": 1}`,
+		status: 400, golden: "unknown_field"},
+}
+
+func TestE2E_Scenario(t *testing.T) {
+	_, application, srv := newE2EServer(t)
+
+	covered := make(map[string]bool)
+	for _, step := range e2eScenario {
+		req, err := http.NewRequest(step.method, srv.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, pattern := application.mux.Handler(req)
+		covered[pattern] = true
+
+		t.Run(step.name, func(t *testing.T) {
+			status, body := do(t, req)
+			if status != step.status {
+				t.Fatalf("%s %s = %d %s, want %d", step.method, step.path, status, body, step.status)
+			}
+			if step.golden != "" {
+				checkGolden(t, step.golden, body, step.volatile)
+			}
+			if !strings.Contains(string(body), step.contains) {
+				t.Errorf("%s %s = %s, want it to contain %q", step.method, step.path, body, step.contains)
+			}
+		})
+	}
+
+	// SSE-поток не завершается сам: только заголовки и отмена запроса
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/users/2/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("GET /users/2/events = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	cancel()
+	resp.Body.Close()
+
+	// сценарий проходит по всем маршрутам сервера
+	for _, rt := range application.routes() {
+		if !covered[rt.pattern] {
+			t.Errorf("route %s is not covered by the scenario", rt.pattern)
+		}
+	}
+}
+
+// do - выполнить запрос, вернуть код и тело
+func do(t *testing.T, req *http.Request) (int, []byte) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// checkGolden - тело JSON совпадает с testdata/<name>.golden.json с точностью до порядка ключей и полей volatile
+func checkGolden(t *testing.T, name string, body []byte, volatile []string) {
+	t.Helper()
+	got, err := normalizeJSON(body, volatile)
+	if err != nil {
+		t.Fatalf("response is not JSON: %v\n%s", err, body)
+	}
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response differs from %s:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// normalizeJSON - JSON с отступами и сортированными ключами; значения полей volatile - "<volatile>"
+func normalizeJSON(data []byte, volatile []string) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	hide := make(map[string]bool, len(volatile))
+	for _, key := range volatile {
+		hide[key] = true
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if hide[key] {
+					v[key] = "<volatile>"
+					continue
+				}
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(v)
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	return out.Bytes(), err
+}
+
+// e2eMethods - методы каждого маршрута; новый маршрут без записи здесь роняет TestE2E_Errors
+var e2eMethods = map[string][]string{
+	"/echo": {"GET"}, "/healthz": {"GET", "HEAD"}, "/readyz": {"GET", "HEAD"}, "/version": {"GET"}, "/time": {"GET"},
+	"/purchaseCard": {"POST"}, "/products": {"GET"}, "/cardOrder": {"GET"}, "/activateCard": {"POST"},
+	"/getusercards/": {"GET"}, "/reconciliation": {"GET"}, "/reverseTransaction": {"POST"}, "/refundTransaction": {"POST"},
+	"/authorize": {"POST"}, "/capture": {"POST"}, "/void": {"POST"}, "/limits": {"GET", "POST"},
+	"/blockCard": {"POST"}, "/unblockCard": {"POST"}, "/users/": {"GET"}, "/metrics": {"GET"}, "/openapi.json": {"GET"},
+}
+
+// e2eQueryIDs - обязательный числовой параметр GET-маршрута
+var e2eQueryIDs = map[string]string{"/cardOrder": "cardID", "/getusercards/": "userID", "/limits": "cardID"}
+
+// e2eMethodChecked - маршруты, которые отвечают 405 на чужой метод (/echo, /metrics и /openapi.json отвечают на любой)
+func e2eMethodChecked(pattern string) bool {
+	return pattern != "/echo" && pattern != "/metrics" && pattern != "/openapi.json"
+}
+
+func TestE2E_Errors(t *testing.T) {
+	_, application, srv := newE2EServer(t)
+
+	for _, rt := range application.routes() {
+		methods, ok := e2eMethods[rt.pattern]
+		if !ok {
+			t.Errorf("route %s: methods are not listed in e2eMethods", rt.pattern)
+			continue
+		}
+		path := rt.pattern
+		if path == "/users/" {
+			path = "/users/1/events"
+		}
+		allowed := make(map[string]bool)
+		for _, m := range methods {
+			allowed[m] = true
+		}
+
+		var tests []e2eStep
+		for _, m := range []string{"GET", "POST", "PUT", "DELETE"} {
+			if !allowed[m] && e2eMethodChecked(rt.pattern) {
+				tests = append(tests, e2eStep{name: m, method: m, path: path, status: 405})
+			}
+		}
+		if allowed["POST"] {
+			// прежде неверный JSON молча давал 200 с нулевыми параметрами
+			tests = append(tests,
+				e2eStep{name: "bad json", method: "POST", path: path, body: `{"card_id": 1`, status: 400, contains: `"field":"body"`},
+				e2eStep{name: "not an object", method: "POST", path: path, body: `[1, 2]`, status: 400, contains: `{"field":"body","message":"must be an object"}`},
+				e2eStep{name: "empty body", method: "POST", path: path, status: 400, contains: `{"field":"body","message":"is required"}`},
+				e2eStep{name: "unknown field", method: "POST", path: path, body: `{"cardID": 1}`, status: 400,
+					contains: `{"field":"cardID","message":"unknown field"}`},
+				e2eStep{name: "too large", method: "POST", path: path, body: `{"pad": "` + strings.Repeat("x", MaxBodyBytes) + `"}`, status: 413},
+			)
+		}
+		if name, ok := e2eQueryIDs[rt.pattern]; ok {
+			tests = append(tests,
+				e2eStep{name: "missing " + name, method: "GET", path: path, status: 400, contains: `"message":"is required"`},
+				e2eStep{name: "text " + name, method: "GET", path: path + "?" + name + "=x", status: 400, contains: `"message":"must be an integer"`},
+				e2eStep{name: "zero " + name, method: "GET", path: path + "?" + name + "=0", status: 400, contains: `"message":"is required"`},
+				e2eStep{name: "negative " + name, method: "GET", path: path + "?" + name + "=-1", status: 400, contains: `"message":"must be positive"`},
+			)
+		}
+		for _, tt := range tests {
+			t.Run(rt.pattern+"/"+tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				status, body := do(t, req)
+				if status != tt.status || !strings.Contains(string(body), tt.contains) {
+					t.Errorf("%s %s = %d %s, want %d containing %q", tt.method, tt.path, status, body, tt.status, tt.contains)
+				}
+			})
+		}
+	}
+
+	for _, path := range []string{"/users/1", "/users/x/events", "/nowhere"} {
+		req, err := http.NewRequest("GET", srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status, body := do(t, req); status != 404 {
+			t.Errorf("GET %s = %d %s, want 404", path, status, body)
+		}
+	}
+}
+
+// post - POST с JSON-телом; ответ декодируется в v при коде 200
+func post(t *testing.T, url, body string, v interface{}) int {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 200 && v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Error(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestE2E_Concurrent(t *testing.T) {
+	svc, _, srv := newE2EServer(t)
+	const workers, purchases = 8, 10
+
+	// покупки по карте 1 (по 10 руб.), выпуск карт пользователю 1 и чтение его карт - одновременно
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	transactions := make([]int64, 0)
+	cards := make([]int64, 0)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < purchases; i++ {
+				var hold card.Hold
+				if code := post(t, srv.URL+"/authorize", `{"card_id": 1, "amount": {"amount": 1000, "currency": "RUB"}, "mcc": "5411"}`, &hold); code != 200 {
+					t.Errorf("authorize = %d", code)
+					return
+				}
+				var tr card.Transaction
+				if code := post(t, srv.URL+"/capture", fmt.Sprintf(`{"hold_id": %d}`, hold.ID), &tr); code != 200 {
+					t.Errorf("capture hold %d = %d", hold.ID, code)
+					return
+				}
+				mu.Lock()
+				transactions = append(transactions, tr.ID)
+				mu.Unlock()
+
+				var uc userCards
+				if code := getJSON(t, srv.URL+"/getusercards/?userID=1", &uc); code != 200 || int(uc.CardsLength) != len(uc.Cards) {
+					t.Errorf("getusercards = %d %+v", code, uc)
+				}
+			}
+			var issued card.Card
+			if code := post(t, srv.URL+"/purchaseCard", `{"card_type": "virtual", "card_issuer": "Master", "user_id": 1}`, &issued); code != 200 {
+				t.Errorf("worker %d: purchaseCard = %d", w, code)
+				return
+			}
+			mu.Lock()
+			cards = append(cards, issued.ID)
+			mu.Unlock()
+		}(w)
+	}
+	wg.Wait()
+
+	if !unique(transactions) || len(transactions) != workers*purchases {
+		t.Errorf("transactions = %v, want %d unique", transactions, workers*purchases)
+	}
+	if !unique(cards) || len(cards) != workers {
+		t.Errorf("issued cards = %v, want %d unique", cards, workers)
+	}
+	c, ok := svc.SearchByID(1)
+	if want := card.Rub(1000_00 - workers*purchases*10_00); !ok || !reflect.DeepEqual(c.Balance, want) {
+		t.Errorf("card 1 balance = %v, want %v", c.Balance, want)
+	}
+	var report card.ReconciliationReport
+	if code := getJSON(t, srv.URL+"/reconciliation", &report); code != 200 || !report.OK {
+		t.Errorf("reconciliation = %d %+v", code, report)
+	}
+
+	// лимит карт на пользователя соблюдается и при гонке: у пользователя 2 уже 2 карты
+	var wg2 sync.WaitGroup
+	statuses := make([]int, card.DefaultMaxCardsPerUser)
+	for i := range statuses {
+		wg2.Add(1)
+		go func(i int) {
+			defer wg2.Done()
+			statuses[i] = post(t, srv.URL+"/purchaseCard", `{"product_id": "virtual-visa", "user_id": 2}`, nil)
+		}(i)
+	}
+	wg2.Wait()
+	sort.Ints(statuses)
+	issued := 0
+	for _, s := range statuses {
+		if s == 200 {
+			issued++
+		} else if s != 409 {
+			t.Errorf("purchaseCard statuses = %v, want only 200 and 409", statuses)
+			break
+		}
+	}
+	if issued != card.DefaultMaxCardsPerUser-2 {
+		t.Errorf("issued %d cards over the cap, statuses %v", issued, statuses)
+	}
+}
+
+func unique(ids []int64) bool {
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// DefaultHeartbeat - интервал комментариев-пингов в SSE-потоке, чтобы прокси не закрывали соединение
+const DefaultHeartbeat = 15 * time.Second
+
+// sseQueueSize - сколько событий ждёт записи в поток; при переполнении поток закрывается,
+// клиент переподключается с Last-Event-ID и получает пропущенное из истории
+const sseQueueSize = 64
+
+// SetHeartbeat - интервал пингов в /users/{id}/events
+func (s *Server) SetHeartbeat(d time.Duration) {
+	s.heartbeat = d
+}
+
+// userIDFromEventsPath - ID пользователя из пути /users/{id}/events
+func userIDFromEventsPath(path string) (int64, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "users" || parts[2] != "events" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// ----------------------------------------------------------------
+// GET /users/{id}/events - SSE-поток событий пользователя: транзакции и изменения состояния карт;
+// при переподключении заголовок Last-Event-ID (или параметр lastEventId) - ID последнего полученного события
+func (s *Server) handlerUserEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromEventsPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if err := s.cardSvc.CheckUser(userID); err != nil {
+		http.Error(w, fmt.Sprintf("user %v does not exist", userID), 404)
+		return
+	}
+	lastID, err := lastEventID(r)
+	if err != nil {
+		http.Error(w, "Last-Event-ID not parsed to int64", 400)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", 500)
+		return
+	}
+
+	// подписка до чтения истории, чтобы не потерять события между ними; дубли отсекаются по ID.
+	// Подписчик только кладёт событие в канал и не ждёт: в сеть пишет горутина обработчика
+	ctx := r.Context()
+	live := make(chan card.Event, sseQueueSize)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	unsubscribe := s.cardSvc.Events().Subscribe(func(e card.Event) {
+		if e.UserID != userID {
+			return
+		}
+		select {
+		case live <- e:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range s.cardSvc.Events().Since(lastID) {
+		if e.UserID != userID {
+			continue
+		}
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+		lastID = e.ID
+	}
+	s.extendWriteDeadline(w, r)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case <-overflow:
+			s.metrics.sseOverflows.Inc()
+			s.logger.Warn(ctx, "event stream overflow, closing", "user_id", userID, "last_event_id", lastID)
+			return
+		case e := <-live:
+			if e.ID <= lastID {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			lastID = e.ID
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		s.extendWriteDeadline(w, r)
+		flusher.Flush()
+	}
+}
+
+// extendWriteDeadline - продлить WriteTimeout сервера для долгого потока: следующая запись - не позже чем через два пинга
+func (s *Server) extendWriteDeadline(w http.ResponseWriter, r *http.Request) {
+	if rw, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		if err := rw.SetWriteDeadline(time.Now().Add(2 * s.heartbeat)); err != nil {
+			s.logger.Warn(r.Context(), "extend write deadline", "error", err)
+		}
+	}
+}
+
+func lastEventID(r *http.Request) (int64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("lastEventId")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// writeEvent - событие в формате SSE: id, event, data
+func writeEvent(w http.ResponseWriter, e card.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,221 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func newTestServer(t *testing.T) (*card.Service, *httptest.Server) {
+	svc, _, srv := newTestApp(t)
+	return svc, srv
+}
+
+func newTestApp(t *testing.T) (*card.Service, *Server, *httptest.Server) {
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{
+		{ID: 1, UserID: 1, Balance: card.Rub(1000_00)},
+		{ID: 2, UserID: 2, Balance: card.Rub(1000_00)},
+	})
+	application := NewServer(svc, http.NewServeMux())
+	application.SetHeartbeat(20 * time.Millisecond)
+	application.Init()
+	return svc, application, httptest.NewServer(application)
+}
+
+// readEvents - читает поток до want событий (строк "id: ..."), возвращает ID и число пингов
+func readEvents(t *testing.T, r *bufio.Reader, want int) (ids []string, heartbeats int) {
+	for len(ids) < want {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v (ids %v)", err, ids)
+		}
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		case strings.HasPrefix(line, ": heartbeat"):
+			heartbeats++
+		}
+	}
+	return ids, heartbeats
+}
+
+func TestServer_UserEvents(t *testing.T) {
+	svc, srv := newTestServer(t)
+	defer srv.Close()
+	baseline := svc.Events().Subscribers() // подписка метрик сервера
+
+	// события до подключения: 1 и 3 - пользователь 1, 2 - пользователь 2
+	for _, id := range []int64{1, 2, 1} {
+		if _, err := svc.Purchase(context.Background(), id, card.Rub(1_00), "5411"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/users/1/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	ids, _ := readEvents(t, stream, 1)
+	if ids[0] != "3" {
+		t.Errorf("resumed ids = %v, want [3]", ids)
+	}
+
+	if _, err := svc.Purchase(context.Background(), 2, card.Rub(1_00), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BlockCard(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	ids, heartbeats := readEvents(t, stream, 1)
+	if ids[0] != "5" {
+		t.Errorf("live ids = %v, want [5]", ids)
+	}
+
+	// без новых событий приходят только пинги
+	deadline := time.Now().Add(time.Second)
+	for heartbeats == 0 && time.Now().Before(deadline) {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, ": heartbeat") {
+			heartbeats++
+		}
+	}
+	if heartbeats == 0 {
+		t.Error("no heartbeats")
+	}
+
+	cancel()
+	deadline = time.Now().Add(time.Second)
+	for svc.Events().Subscribers() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := svc.Events().Subscribers(); n != baseline {
+		t.Errorf("subscribers after disconnect = %d, want %d", n, baseline)
+	}
+}
+
+func TestServer_UserEventsErrors(t *testing.T) {
+	_, srv := newTestServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		lastEventID string
+		status      int
+	}{
+		{name: "unknown user", path: "/users/42/events", status: 404},
+		{name: "bad user id", path: "/users/abc/events", status: 404},
+		{name: "bad path", path: "/users/1/cards", status: 404},
+		{name: "bad Last-Event-ID", path: "/users/1/events", lastEventID: "x", status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestServer_UserEventsShutdown(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/users/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	application.Shutdown()
+	done := make(chan error, 1)
+	go func() {
+		_, err := ioutil.ReadAll(resp.Body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stream error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream is not closed on Shutdown")
+	}
+}
+
+// stalledWriter - клиент, который перестал читать: первая запись в поток ждёт release
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	stalled chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.stalled)
+		<-w.release
+	})
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestServer_UserEventsSlowClient(t *testing.T) {
+	svc, application, srv := newTestApp(t)
+	defer srv.Close()
+
+	w := &stalledWriter{ResponseRecorder: httptest.NewRecorder(), stalled: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		application.ServeHTTP(w, httptest.NewRequest("GET", "/users/1/events", nil))
+	}()
+	<-w.stalled // поток завис на записи пинга
+
+	// подписчик шины не ждёт зависшего клиента: покупки проходят, лишние события переполняют очередь потока
+	for i := 0; i <= sseQueueSize; i++ {
+		if _, err := svc.Purchase(context.Background(), 1, card.Rub(1), "5411"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond) // события доходят до подписчика асинхронно
+	close(w.release)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream is not closed on overflow")
+	}
+	if got := application.metrics.sseOverflows.Value(); got != 1 {
+		t.Errorf("overflows = %v, want 1", got)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/version"
+)
+
+// DefaultReadinessTimeout - сколько /readyz ждёт все проверки вместе
+const DefaultReadinessTimeout = 2 * time.Second
+
+// readinessCheck - проверка зависимости для /readyz
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// AddReadinessCheck - дополнительная проверка для /readyz (хранилище карт проверяется всегда)
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.checks = append(s.checks, readinessCheck{name: name, check: check})
+}
+
+// Readiness - ответ /readyz; Checks - "ok" или текст ошибки по каждой проверке
+type Readiness struct {
+	Status string            `json:"status"` // ready, not ready, shutting down
+	Checks map[string]string `json:"checks"`
+}
+
+// handlerHealthz - процесс жив и обрабатывает запросы; зависимости не проверяются
+func (s *Server) handlerHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	s.writeJSON(w, r, map[string]string{"status": "ok"})
+}
+
+// handlerReadyz - можно ли направлять трафик: 503, если проверка не прошла или сервер останавливается
+func (s *Server) handlerReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.readinessTimeout)
+	defer cancel()
+
+	resp := Readiness{Status: "ready", Checks: make(map[string]string, len(s.checks)+1)}
+	checks := append([]readinessCheck{{name: "storage", check: s.cardSvc.Ping}}, s.checks...)
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			resp.Status = "not ready"
+			resp.Checks[c.name] = err.Error()
+			s.logger.Warn(r.Context(), "readiness check failed", "check", c.name, "error", err)
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+	select {
+	case <-s.done:
+		resp.Status = "shutting down"
+	default:
+	}
+
+	if resp.Status != "ready" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	s.writeJSON(w, r, resp)
+}
+
+// handlerVersion - данные сборки из -ldflags (pkg/version)
+func (s *Server) handlerVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	s.writeJSON(w, r, version.Get())
+}
+
+// cityTimezones - города, которые /time принимает вместо имени зоны IANA
+var cityTimezones = map[string]string{
+	"Moscow": "Europe/Moscow",
+}
+
+// loadLocation - зона по имени IANA ("Europe/Moscow") или городу из cityTimezones; "" - UTC
+func loadLocation(name string) (*time.Location, error) {
+	if tz, ok := cityTimezones[name]; ok {
+		name = tz
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// ServerTime - ответ /time
+type ServerTime struct {
+	Time     string `json:"time"` // RFC 3339 в запрошенной зоне
+	Unix     int64  `json:"unix"`
+	Timezone string `json:"timezone"`
+}
+
+// handlerTime - время сервера: /time?tz=Europe/Moscow или /time?tz=Moscow, по умолчанию UTC
+func (s *Server) handlerTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	loc, err := loadLocation(r.URL.Query().Get("tz"))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	now := s.now().In(loc)
+	s.writeJSON(w, r, ServerTime{Time: now.Format(time.RFC3339), Unix: now.Unix(), Timezone: loc.String()})
+}
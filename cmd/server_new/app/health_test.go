@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/version"
+)
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Health(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+
+	var health map[string]string
+	if code := getJSON(t, srv.URL+"/healthz", &health); code != 200 || health["status"] != "ok" {
+		t.Errorf("/healthz = %d %v", code, health)
+	}
+
+	var info version.Info
+	if code := getJSON(t, srv.URL+"/version", &info); code != 200 || info.Version != "dev" || info.GoVersion == "" {
+		t.Errorf("/version = %d %+v", code, info)
+	}
+
+	var ready Readiness
+	if code := getJSON(t, srv.URL+"/readyz", &ready); code != 200 || ready.Status != "ready" || ready.Checks["storage"] != "ok" {
+		t.Errorf("/readyz = %d %+v", code, ready)
+	}
+
+	application.AddReadinessCheck("webhooks", func(ctx context.Context) error { return errors.New("endpoint down") })
+	ready = Readiness{}
+	if code := getJSON(t, srv.URL+"/readyz", &ready); code != 503 || ready.Status != "not ready" || ready.Checks["webhooks"] != "endpoint down" {
+		t.Errorf("/readyz with failing check = %d %+v", code, ready)
+	}
+
+	// при остановке сервер выводится из балансировки, но остаётся живым
+	application.Shutdown()
+	ready = Readiness{}
+	if code := getJSON(t, srv.URL+"/readyz", &ready); code != 503 || ready.Status != "shutting down" {
+		t.Errorf("/readyz after Shutdown = %d %+v", code, ready)
+	}
+	if code := getJSON(t, srv.URL+"/healthz", nil); code != 200 {
+		t.Errorf("/healthz after Shutdown = %d", code)
+	}
+}
+
+func TestServer_Time(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+	application.now = func() time.Time { return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		tz     string
+		status int
+		want   ServerTime
+	}{
+		{tz: "", status: 200, want: ServerTime{Time: "2020-01-01T12:00:00Z", Unix: 1577880000, Timezone: "UTC"}},
+		{tz: "Moscow", status: 200, want: ServerTime{Time: "2020-01-01T15:00:00+03:00", Unix: 1577880000, Timezone: "Europe/Moscow"}},
+		{tz: "Asia/Tokyo", status: 200, want: ServerTime{Time: "2020-01-01T21:00:00+09:00", Unix: 1577880000, Timezone: "Asia/Tokyo"}},
+		{tz: "Mars/Olympus", status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tz, func(t *testing.T) {
+			var got ServerTime
+			code := getJSON(t, srv.URL+"/time?tz="+tt.tz, &got)
+			if code != tt.status || got != tt.want {
+				t.Errorf("/time?tz=%s = %d %+v, want %d %+v", tt.tz, code, got, tt.status, tt.want)
+			}
+		})
+	}
+}
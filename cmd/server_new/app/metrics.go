@@ -0,0 +1,103 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/metrics"
+)
+
+// routeUnmatched - метка route для запросов, которым не нашёлся маршрут (чтобы не плодить серии по путям)
+const routeUnmatched = "unmatched"
+
+// serverMetrics - метрики HTTP и сервиса карт для GET /metrics
+type serverMetrics struct {
+	registry     *metrics.Registry
+	requests     *metrics.CounterVec
+	latency      *metrics.HistogramVec
+	rateLimited  *metrics.CounterVec
+	sseOverflows *metrics.CounterVec
+	issued       *metrics.CounterVec
+	purchases    *metrics.CounterVec
+	volume       *metrics.CounterVec
+}
+
+func newServerMetrics(cardSvc *card.Service) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		requests: r.NewCounterVec("http_requests_total",
+			"HTTP requests by route, method and status code.", "route", "method", "code"),
+		latency: r.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request latency by route.", nil, "route"),
+		rateLimited: r.NewCounterVec("http_rate_limited_total",
+			"HTTP requests rejected with 429 by route and limit scope (ip or user).", "route", "scope"),
+		sseOverflows: r.NewCounterVec("http_event_stream_overflows_total",
+			"SSE streams closed because the client did not keep up with its events."),
+		issued: r.NewCounterVec("card_cards_issued_total",
+			"Cards issued by issuer and type.", "issuer", "type"),
+		purchases: r.NewCounterVec("card_purchases_total",
+			"Completed purchases by MCC.", "mcc"),
+		volume: r.NewCounterVec("card_purchase_volume_total",
+			"Completed purchase volume by MCC in major currency units.", "mcc", "currency"),
+	}
+
+	r.NewCounterFunc("card_service_lock_acquisitions_total",
+		"card.Service lock acquisitions by mode.", []string{"mode"}, func(emit metrics.Emit) {
+			stats := cardSvc.LockStats()
+			emit(float64(stats.ReadAcquisitions), "read")
+			emit(float64(stats.WriteAcquisitions), "write")
+		})
+	r.NewCounterFunc("card_service_lock_wait_seconds_total",
+		"Time spent waiting for the card.Service lock by mode.", []string{"mode"}, func(emit metrics.Emit) {
+			stats := cardSvc.LockStats()
+			emit(stats.ReadWait.Seconds(), "read")
+			emit(stats.WriteWait.Seconds(), "write")
+		})
+	r.NewGaugeFunc("card_event_subscribers",
+		"Active event bus subscribers (SSE, webhooks, metrics).", nil, func(emit metrics.Emit) {
+			emit(float64(cardSvc.Events().Subscribers()))
+		})
+	r.NewCounterFunc("card_event_deliveries_dropped_total",
+		"Events not delivered to a subscriber because its queue was full.", nil, func(emit metrics.Emit) {
+			emit(float64(cardSvc.Events().Dropped()))
+		})
+	return m
+}
+
+// observeRequest - запрос по шаблону маршрута из mux, а не по пути: /getusercards/1 и /getusercards/2 - одна серия
+func (m *serverMetrics) observeRequest(route, method string, status int, latency time.Duration) {
+	if route == "" {
+		route = routeUnmatched
+	}
+	m.requests.Inc(route, method, strconv.Itoa(status))
+	m.latency.Observe(latency.Seconds(), route)
+}
+
+// observeEvent - подписчик шины событий: выпуск карт и завершённые покупки
+// (сразу проведённые или подтверждённые capture по холду)
+func (m *serverMetrics) observeEvent(e card.Event) {
+	switch e.Type {
+	case card.EventCardIssued:
+		cardType := "plastic"
+		if e.Card.IsVirtual {
+			cardType = "virtual"
+		}
+		m.issued.Inc(e.Card.Type, cardType)
+	case card.EventTransactionPosted, card.EventTransactionUpdated:
+		t := e.Transaction
+		if t == nil || t.TranType != card.TranTypePurchase || t.Status != card.StatusDone {
+			return
+		}
+		m.purchases.Inc(t.MccCode)
+		m.volume.Add(t.TranSum.Major(), t.MccCode, string(t.TranSum.Currency))
+	}
+}
+
+// routePattern - шаблон маршрута, который обработает запрос
+func (s *Server) routePattern(r *http.Request) string {
+	_, pattern := s.mux.Handler(r)
+	return pattern
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/metrics"
+)
+
+// scrape - текст GET /metrics
+func scrape(t *testing.T, url string) string {
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("GET /metrics = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	svc, application, srv := newTestApp(t)
+	defer srv.Close()
+	defer application.Shutdown()
+
+	if _, err := svc.IssueCard(context.Background(), "virtual", "Visa", 1, card.RUB); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, card.Rub(150_50), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	hold, err := svc.Authorize(context.Background(), 2, card.Rub(100_00), "5812", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Capture(context.Background(), hold.ID, card.Rub(80_00)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/getusercards/?userID=1", "/getusercards/?userID=1", "/nowhere"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []string{
+		`http_requests_total{route="/getusercards/",method="GET",code="200"} 2`,
+		`http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`http_request_duration_seconds_count{route="/getusercards/"} 2`,
+		`card_cards_issued_total{issuer="Visa",type="virtual"} 1`,
+		`card_purchases_total{mcc="5411"} 1`,
+		`card_purchases_total{mcc="5812"} 1`, // холд считается только после capture
+		`card_purchase_volume_total{mcc="5411",currency="RUB"} 150.5`,
+		`card_purchase_volume_total{mcc="5812",currency="RUB"} 80`,
+		`card_service_lock_acquisitions_total{mode="write"} `,
+		`card_service_lock_wait_seconds_total{mode="read"} `,
+		"card_event_deliveries_dropped_total 0",
+		"# TYPE http_request_duration_seconds histogram",
+	}
+	// события доходят до метрик асинхронно
+	deadline := time.Now().Add(time.Second)
+	for {
+		text := scrape(t, srv.URL)
+		missing := ""
+		for _, line := range want {
+			if !strings.Contains(text, line) {
+				missing = line
+				break
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no %q in\n%s", missing, text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/logging"
+)
+
+// HeaderRequestID - ID запроса: берётся из запроса клиента или создаётся, возвращается в ответе
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength - более длинный ID клиента заменяется своим
+const maxRequestIDLength = 64
+
+// validRequestID - непустой, не длиннее maxRequestIDLength, только печатные ASCII без пробелов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder - запоминает статус и размер ответа для журнала доступа
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Flush - для SSE-потока
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// SetWriteDeadline - для extendWriteDeadline, если исходный ResponseWriter его поддерживает
+func (rec *statusRecorder) SetWriteDeadline(deadline time.Time) error {
+	if rw, ok := rec.ResponseWriter.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return rw.SetWriteDeadline(deadline)
+	}
+	return http.ErrNotSupported
+}
+
+// traceRequest - ID запроса в контекст (дальше - в вызовы card.Service) и в заголовок ответа,
+// после ответа - запись журнала доступа со статусом и временем обработки и метрики маршрута
+func (s *Server) traceRequest(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	start := time.Now()
+	id := r.Header.Get(HeaderRequestID)
+	if !validRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	w.Header().Set(HeaderRequestID, id)
+	r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+	rec := &statusRecorder{ResponseWriter: w}
+	next.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	latency := time.Since(start)
+	s.metrics.observeRequest(s.routePattern(r), r.Method, rec.status, latency)
+
+	level := logging.LevelInfo
+	if rec.status >= 500 {
+		level = logging.LevelError
+	}
+	s.logger.Log(r.Context(), level, "http request", "method", r.Method, "path", r.URL.Path, "status", rec.status,
+		"bytes", rec.bytes, "latency_ms", float64(latency.Microseconds())/1000, "remote_addr", r.RemoteAddr)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/logging"
+)
+
+// syncBuffer - буфер журнала, который пишут горутины сервера и читает тест
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestServer_RequestTracing(t *testing.T) {
+	svc, application, srv := newTestApp(t)
+	defer srv.Close()
+	var logs syncBuffer
+	logger := logging.New(&logs, logging.LevelDebug)
+	application.SetLogger(logger)
+	svc.SetLogger(logger)
+	svc.SetCards(append(svc.GetCards(), &card.Card{ID: 3, UserID: 1, CardNumber: "1111 2222 3333 4444", Balance: card.Rub(0)}))
+
+	tests := []struct {
+		name      string
+		requestID string
+		want      string // "" - сгенерированный
+	}{
+		{name: "client id", requestID: "abc-123", want: "abc-123"},
+		{name: "generated", requestID: ""},
+		{name: "invalid client id", requestID: "has space"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/blockCard", strings.NewReader(`{"card_id": 3}`))
+			if tt.requestID != "" {
+				req.Header.Set(HeaderRequestID, tt.requestID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			got := resp.Header.Get(HeaderRequestID)
+			if (tt.want != "" && got != tt.want) || (tt.want == "" && (len(got) != 16 || got == tt.requestID)) {
+				t.Errorf("%s = %q", HeaderRequestID, got)
+			}
+		})
+	}
+
+	records := logs.records(t)
+	var service, access map[string]interface{}
+	for _, r := range records {
+		if r["request_id"] != "abc-123" {
+			continue
+		}
+		switch r["msg"] {
+		case "block card":
+			service = r
+		case "http request":
+			access = r
+		}
+	}
+	if service == nil || service["card_id"] != 3.0 {
+		t.Errorf("service record = %v", service)
+	}
+	if access == nil || access["status"] != 200.0 || access["path"] != "/blockCard" || access["latency_ms"] == nil {
+		t.Errorf("access record = %v", access)
+	}
+	if strings.Contains(logs.String(), "1111 2222 3333 4444") {
+		t.Error("card number is not redacted")
+	}
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	_ "embed" // спецификация OpenAPI
+	"net/http"
+)
+
+// openAPISpec - описание всех маршрутов routes(); TestOpenAPI_CoversRoutes падает, если маршрута в нём нет
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handlerOpenAPI - GET /openapi.json: спецификация OpenAPI 3 для Swagger UI и генераторов клиентов
+func (s *Server) handlerOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPISpec); err != nil {
+		s.logger.Warn(r.Context(), "write response", "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/version"
+)
+
+type openAPIDoc struct {
+	OpenAPI    string                            `json:"openapi"`
+	Paths      map[string]map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want 3.x", doc.OpenAPI)
+	}
+	return doc
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	application := NewServer(card.NewService(), http.NewServeMux())
+	application.Init()
+	defer application.Shutdown()
+	doc := loadOpenAPI(t)
+
+	// каждый маршрут описан: точно или, для маршрута-префикса ("/users/"), путём под ним
+	for _, rt := range application.routes() {
+		found := false
+		for path := range doc.Paths {
+			if path == rt.pattern || (strings.HasSuffix(rt.pattern, "/") && strings.HasPrefix(path, rt.pattern)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("route %s is missing from openapi.json", rt.pattern)
+		}
+	}
+
+	// и каждый описанный путь действительно обслуживается
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			req := httptest.NewRequest(strings.ToUpper(method), pathParam.ReplaceAllString(path, "1"), nil)
+			if _, pattern := application.mux.Handler(req); pattern == "" {
+				t.Errorf("%s %s is in openapi.json but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_Refs(t *testing.T) {
+	doc := loadOpenAPI(t)
+	var raw map[string]interface{}
+	if err := json.Unmarshal(openAPISpec, &raw); err != nil {
+		t.Fatal(err)
+	}
+	components := raw["components"].(map[string]interface{})
+	for _, ref := range regexp.MustCompile(`"\$ref": *"#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(openAPISpec), -1) {
+		section, _ := components[ref[1]].(map[string]interface{})
+		if _, ok := section[ref[2]]; !ok {
+			t.Errorf("unresolved $ref #/components/%s/%s", ref[1], ref[2])
+		}
+	}
+	if len(doc.Components.Schemas) == 0 {
+		t.Error("no schemas")
+	}
+}
+
+// jsonFields - имена полей типа в JSON
+func jsonFields(v interface{}) []string {
+	typ := reflect.TypeOf(v)
+	fields := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestOpenAPI_SchemasMatchTypes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	types := map[string]interface{}{
+		"Card":                 card.Card{},
+		"Transaction":          card.Transaction{},
+		"UserCards":            userCards{},
+		"CardOrder":            card.CardOrder{},
+		"Address":              card.Address{},
+		"Hold":                 card.Hold{},
+		"Limits":               card.Limits{},
+		"Product":              card.Product{},
+		"Event":                card.Event{},
+		"FxInfo":               card.FxInfo{},
+		"ReconciliationReport": card.ReconciliationReport{},
+		"PurchaseCardParams":   PurchaseCardParams{},
+		"CardIDParams":         BlockParams{},
+		"ReverseParams":        ReverseParams{},
+		"RefundParams":         RefundParams{},
+		"AuthorizeParams":      AuthorizeParams{},
+		"CaptureParams":        CaptureParams{},
+		"VoidParams":           VoidParams{},
+		"LimitsParams":         LimitsParams{},
+		"ValidationError":      struct{ Error, Fields string }{},
+		"Readiness":            Readiness{},
+		"VersionInfo":          version.Info{},
+		"ServerTime":           ServerTime{},
+	}
+	for name, v := range types {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		got := make([]string, 0, len(schema.Properties))
+		for p := range schema.Properties {
+			got = append(got, p)
+		}
+		sort.Strings(got)
+		want := jsonFields(v)
+		if name == "ValidationError" {
+			want = []string{"error", "fields"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("schema %s properties = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	_, _, srv := newTestApp(t)
+	defer srv.Close()
+	var doc map[string]interface{}
+	if code := getJSON(t, srv.URL+"/openapi.json", &doc); code != 200 || doc["openapi"] != "3.0.3" {
+		t.Errorf("/openapi.json = %d %v", code, doc["openapi"])
+	}
+}
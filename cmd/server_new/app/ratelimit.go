@@ -0,0 +1,115 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/ratelimit"
+)
+
+// HeaderUserID - пользователь, которого аутентифицировал прокси перед сервером; по нему считается лимит PerUser,
+// только если прокси доверенный (trustUserHeader): своей аутентификации у сервера нет, без прокси заголовок задаёт клиент
+const HeaderUserID = "X-User-ID"
+
+// routeLimiter - корзины одного маршрута
+type routeLimiter struct {
+	perIP   *ratelimit.Limiter
+	perUser *ratelimit.Limiter
+}
+
+// SetRateLimits - лимиты по шаблону маршрута ("/purchaseCard"); маршруты без записи не ограничиваются.
+// trustForwardedFor - IP клиента брать из X-Forwarded-For (только за своим прокси, иначе IP подделывается),
+// trustUserHeader - пользователя брать из HeaderUserID; иначе - из поля user_id тела запроса
+func (s *Server) SetRateLimits(policies map[string]ratelimit.Policy, trustForwardedFor bool, trustUserHeader bool) {
+	limiters := make(map[string]routeLimiter, len(policies))
+	for route, p := range policies {
+		limiters[route] = routeLimiter{perIP: ratelimit.NewLimiter(p.PerIP), perUser: ratelimit.NewLimiter(p.PerUser)}
+	}
+	s.limiters = limiters
+	s.trustForwardedFor = trustForwardedFor
+	s.trustUserHeader = trustUserHeader
+}
+
+// rateLimitUser - пользователь для лимита PerUser: с доверенным прокси - HeaderUserID, иначе - user_id тела,
+// потому что свой заголовок клиент менял бы на каждом запросе и каждый раз получал новую корзину
+func (s *Server) rateLimitUser(r *http.Request) string {
+	if s.trustUserHeader {
+		return r.Header.Get(HeaderUserID)
+	}
+	return bodyUserID(r)
+}
+
+// bodyUserID - поле user_id тела JSON (пусто, если его нет); прочитанное тело возвращается в r.Body для обработчика
+func bodyUserID(r *http.Request) string {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	// остаток тела сверх лимита тоже возвращается: слишком большое тело отклонит decodeJSON
+	r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return ""
+	}
+	var v struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil || v.UserID <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(v.UserID, 10)
+}
+
+// clientIP - адрес соединения или, за доверенным прокси, последний адрес из X-Forwarded-For (его дописал прокси)
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustForwardedFor {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			hops := strings.Split(fwd, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit - 429 с Retry-After (секунды), если у IP клиента или пользователя кончились токены маршрута
+func (s *Server) rateLimit(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	route := s.routePattern(r)
+	l, ok := s.limiters[route]
+	if !ok {
+		next.ServeHTTP(w, r)
+		return
+	}
+	now := time.Now()
+	ip := s.clientIP(r)
+	scope, key := "ip", ip
+	allowed, retryAfter := l.perIP.Allow(key, now)
+	if user := s.rateLimitUser(r); allowed && user != "" {
+		scope, key = "user", user
+		if allowed, retryAfter = l.perUser.Allow(key, now); !allowed {
+			// отказ по пользователю не тратит токены IP: за одним NAT бывают и другие пользователи
+			l.perIP.Refund(ip, now)
+		}
+	}
+	if allowed {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	s.metrics.rateLimited.Inc(route, scope)
+	s.logger.Warn(r.Context(), "rate limited", "route", route, "scope", scope, "key", key, "retry_after", retryAfter)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
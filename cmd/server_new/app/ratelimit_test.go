@@ -0,0 +1,149 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/ratelimit"
+)
+
+func TestServer_RateLimit(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+	application.SetRateLimits(map[string]ratelimit.Policy{
+		"/purchaseCard": {PerIP: ratelimit.Rule{Rate: 0.001, Burst: 3}, PerUser: ratelimit.Rule{Rate: 0.001, Burst: 1}},
+	}, true, true)
+
+	purchase := func(ip, user string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/purchaseCard", strings.NewReader(`{"product_id": "virtual-visa", "user_id": 1}`))
+		req.Header.Set("X-Forwarded-For", "10.0.0.1, "+ip)
+		if user != "" {
+			req.Header.Set(HeaderUserID, user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	tests := []struct {
+		name   string
+		ip     string
+		user   string
+		status int
+	}{
+		{name: "user 1", ip: "192.0.2.1", user: "1", status: 200},
+		{name: "user 1 again", ip: "192.0.2.1", user: "1", status: 429},
+		{name: "user 2", ip: "192.0.2.1", user: "2", status: 200},
+		{name: "anonymous", ip: "192.0.2.1", status: 200}, // отказ user 1 вернул токен IP
+		{name: "anonymous again", ip: "192.0.2.1", status: 429},
+		{name: "other ip", ip: "192.0.2.2", status: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := purchase(tt.ip, tt.user)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == 429 && resp.Header.Get("Retry-After") != "1000" {
+				t.Errorf("Retry-After = %q, want 1000", resp.Header.Get("Retry-After"))
+			}
+		})
+	}
+
+	// другие маршруты не ограничены
+	for i := 0; i < 5; i++ {
+		if code := getJSON(t, srv.URL+"/healthz", nil); code != 200 {
+			t.Fatalf("/healthz = %d", code)
+		}
+	}
+	if got := application.metrics.rateLimited.Value("/purchaseCard", "user"); got != 1 {
+		t.Errorf("rate limited by user = %v, want 1", got)
+	}
+}
+
+func TestServer_RateLimitUntrustedUserHeader(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+	application.SetRateLimits(map[string]ratelimit.Policy{
+		"/purchaseCard": {PerUser: ratelimit.Rule{Rate: 0.001, Burst: 1}},
+	}, false, false)
+
+	// без доверенного прокси X-User-ID игнорируется, пользователь - из тела
+	purchase := func(header string, bodyUser int64) int {
+		body := fmt.Sprintf(`{"product_id": "virtual-visa", "user_id": %d}`, bodyUser)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/purchaseCard", strings.NewReader(body))
+		req.Header.Set(HeaderUserID, header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	tests := []struct {
+		name     string
+		header   string
+		bodyUser int64
+		status   int
+	}{
+		{name: "first", header: "100", bodyUser: 1, status: 200},
+		{name: "other header, same body user", header: "101", bodyUser: 1, status: 429},
+		{name: "other body user", header: "100", bodyUser: 2, status: 200},
+	}
+	for _, tt := range tests {
+		if code := purchase(tt.header, tt.bodyUser); code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, tt.status)
+		}
+	}
+}
+
+func TestServer_RateLimitUserDenialKeepsIPTokens(t *testing.T) {
+	_, application, srv := newTestApp(t)
+	defer srv.Close()
+	application.SetRateLimits(map[string]ratelimit.Policy{
+		"/purchaseCard": {PerIP: ratelimit.Rule{Rate: 0.001, Burst: 3}, PerUser: ratelimit.Rule{Rate: 0.001, Burst: 1}},
+	}, false, false)
+
+	purchase := func(user int64) int {
+		body := fmt.Sprintf(`{"product_id": "virtual-visa", "user_id": %d}`, user)
+		resp, err := http.Post(srv.URL+"/purchaseCard", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// все запросы с одного IP: отказы пользователю 1 не съедают корзину IP, и пользователь 2 проходит
+	for i, tt := range []struct {
+		user   int64
+		status int
+	}{{1, 200}, {1, 429}, {1, 429}, {1, 429}, {2, 200}, {2, 429}, {1, 429}} {
+		if code := purchase(tt.user); code != tt.status {
+			t.Errorf("request %d (user %d): status = %d, want %d", i+1, tt.user, code, tt.status)
+		}
+	}
+}
+
+func TestServer_CardCap(t *testing.T) {
+	svc, _, srv := newTestApp(t)
+	defer srv.Close()
+	settings := svc.IssueSettings()
+	settings.MaxCardsPerUser = 1 // у пользователя 1 уже есть карта
+	if err := svc.SetIssueSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Post(srv.URL+"/purchaseCard", "application/json", strings.NewReader(`{"product_id": "virtual-visa", "user_id": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 409 {
+		t.Errorf("status = %d, want 409", resp.StatusCode)
+	}
+}
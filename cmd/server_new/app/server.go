@@ -1,92 +1,277 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/logging"
 )
 
 type Server struct {
-	cardSvc *card.Service
-	mux     *http.ServeMux
+	cardSvc           *card.Service
+	mux               *http.ServeMux
+	logger            *logging.Logger
+	metrics           *serverMetrics
+	unobserve         func() // отписка метрик от шины событий
+	checks            []readinessCheck
+	readinessTimeout  time.Duration
+	limiters          map[string]routeLimiter // по шаблону маршрута; задаются до запуска сервера
+	trustForwardedFor bool
+	trustUserHeader   bool
+	heartbeat         time.Duration
+	echoLoc           *time.Location
+	now               func() time.Time // для тестов /time
+	done              chan struct{}    // закрывается в Shutdown, завершает SSE-потоки
+	closeOnce         sync.Once
 }
 
 func NewServer(cardSvc *card.Service, mux *http.ServeMux) *Server {
-	return &Server{cardSvc: cardSvc, mux: mux}
+	// время в /echo - московское; без базы зон в системе - фиксированное смещение
+	echoLoc, err := loadLocation("Moscow")
+	if err != nil {
+		echoLoc = time.FixedZone("MSK", 3*60*60)
+	}
+	return &Server{cardSvc: cardSvc, mux: mux, logger: logging.Nop(), metrics: newServerMetrics(cardSvc),
+		readinessTimeout: DefaultReadinessTimeout, heartbeat: DefaultHeartbeat, echoLoc: echoLoc, now: time.Now,
+		done: make(chan struct{})}
 }
 
-func (s *Server) Init() {
-	s.mux.HandleFunc("/echo", s.handlerEcho)
-	s.mux.HandleFunc("/purchaseCard", s.handlerPurchaseCard)
-	s.mux.HandleFunc("/getusercards/", s.handlerGetUserCards)
+// SetLogger - журнал доступа и ошибок обработчиков
+func (s *Server) SetLogger(logger *logging.Logger) {
+	s.logger = logger
 }
 
-// для Echo
-var countryTz = map[string]string{
-	"Moscow": "Europe/Moscow",
+// Shutdown - завершить долгие запросы (SSE), чтобы http.Server.Shutdown не ждал их до дедлайна;
+// регистрируется через http.Server.RegisterOnShutdown
+func (s *Server) Shutdown() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if s.unobserve != nil {
+			s.unobserve()
+		}
+	})
 }
 
-func timeIn(name string) time.Time {
-	loc, err := time.LoadLocation(countryTz[name])
-	if err != nil {
-		panic(err)
+// route - маршрут HTTP; по этому же списку тест сверяет спецификацию OpenAPI
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{"/echo", http.HandlerFunc(s.handlerEcho)},
+		{"/healthz", http.HandlerFunc(s.handlerHealthz)},
+		{"/readyz", http.HandlerFunc(s.handlerReadyz)},
+		{"/version", http.HandlerFunc(s.handlerVersion)},
+		{"/time", http.HandlerFunc(s.handlerTime)},
+		{"/purchaseCard", http.HandlerFunc(s.handlerPurchaseCard)},
+		{"/products", http.HandlerFunc(s.handlerProducts)},
+		{"/cardOrder", http.HandlerFunc(s.handlerCardOrder)},
+		{"/activateCard", http.HandlerFunc(s.handlerActivateCard)},
+		{"/getusercards/", http.HandlerFunc(s.handlerGetUserCards)},
+		{"/reconciliation", http.HandlerFunc(s.handlerReconciliation)},
+		{"/reverseTransaction", http.HandlerFunc(s.handlerReverseTransaction)},
+		{"/refundTransaction", http.HandlerFunc(s.handlerRefundTransaction)},
+		{"/authorize", http.HandlerFunc(s.handlerAuthorize)},
+		{"/capture", http.HandlerFunc(s.handlerCapture)},
+		{"/void", http.HandlerFunc(s.handlerVoid)},
+		{"/limits", http.HandlerFunc(s.handlerLimits)},
+		{"/blockCard", http.HandlerFunc(s.handlerBlockCard)},
+		{"/unblockCard", http.HandlerFunc(s.handlerUnblockCard)},
+		{"/users/", http.HandlerFunc(s.handlerUserEvents)},
+		{"/metrics", s.metrics.registry},
+		{"/openapi.json", http.HandlerFunc(s.handlerOpenAPI)},
 	}
-	return time.Now().In(loc)
+}
+
+func (s *Server) Init() {
+	for _, rt := range s.routes() {
+		s.mux.Handle(rt.pattern, rt.handler)
+	}
+	s.unobserve = s.cardSvc.Events().Subscribe(s.metrics.observeEvent)
 }
 
 // ----------------------------------------------------------------
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.traceRequest(w, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.rateLimit(w, r, s.mux)
+	}))
 }
 
 // ----------------------------------------------------------------
 func (s *Server) handlerEcho(w http.ResponseWriter, r *http.Request) {
-	loc, _ := time.LoadLocation("Europe/Moscow")
-	resp := "ECHO " + time.Now().In(loc).String()
+	resp := "ECHO " + s.now().In(s.echoLoc).String()
 	_, err := w.Write([]byte(resp))
 	if err != nil {
-		log.Println(err)
+		s.logger.Warn(r.Context(), "write response", "error", err)
 	}
 }
 
+// PurchaseCardParams - заказ карты: по ProductID из GET /products или по типу и платёжной системе
 type PurchaseCardParams struct {
+	ProductID  string `json:"product_id"` // если задан, CardType, CardIssuer и Currency не используются
 	CardType   string `json:"card_type"`
 	CardIssuer string `json:"card_issuer"`
 	UserID     int64  `json:"user_id"`
+	Currency   string `json:"currency"` // необязательно, по умолчанию RUB
+	// Address - адрес доставки, обязателен для пластиковой карты; тогда ответ - заказ (card.CardOrder), а не карта
+	Address *card.Address `json:"address"`
+}
+
+func (p *PurchaseCardParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("user_id", p.UserID)
+	if p.ProductID == "" {
+		fe.oneOf("card_type", p.CardType, card.CardTypes)
+		fe.required("card_issuer", p.CardIssuer)
+	}
+	if p.Currency != "" {
+		if _, err := card.ParseCurrency(p.Currency); err != nil {
+			fe.add("currency", "must be one of %s", strings.Join(currencyCodes(), ", "))
+		}
+	}
+	if p.Address != nil {
+		fe.required("address.recipient", p.Address.Recipient)
+		fe.required("address.city", p.Address.City)
+		fe.required("address.street", p.Address.Street)
+		fe.required("address.postal_code", p.Address.PostalCode)
+	}
+	return fe.err()
+}
+
+// currencyCodes - поддерживаемые валюты для сообщений об ошибке
+func currencyCodes() []string {
+	return []string{string(card.CNY), string(card.EUR), string(card.RUB), string(card.USD)}
 }
 
 // ----------------------------------------------------------------
 func (s *Server) handlerPurchaseCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
 	var qparams PurchaseCardParams
-	err := json.NewDecoder(r.Body).Decode(&qparams)
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	// адрес доставки в журнал не пишется
+	s.logger.Debug(r.Context(), "purchase card", "product_id", qparams.ProductID, "card_type", qparams.CardType,
+		"card_issuer", qparams.CardIssuer, "user_id", qparams.UserID, "currency", qparams.Currency, "delivery", qparams.Address != nil)
+
+	currency := card.DefaultCurrency
+	if qparams.Currency != "" {
+		currency, _ = card.ParseCurrency(qparams.Currency) // проверено в Validate
+	}
+
+	//
+	err := s.cardSvc.CheckUser(qparams.UserID)
 	if err != nil {
-		log.Println(err)
+		http.Error(w, fmt.Sprintf("user %v does not exist", qparams.UserID), 400)
 		return
 	}
-	log.Println("params=", qparams)
 
 	//
-	err = card.CheckCardTypeCardIssuer(qparams.CardType, qparams.CardIssuer)
+	var issued interface{}
+	switch {
+	case qparams.Address != nil:
+		issued, err = s.orderCard(r.Context(), qparams, currency)
+	case qparams.ProductID != "":
+		issued, err = s.cardSvc.IssueProduct(r.Context(), qparams.ProductID, qparams.UserID)
+	default:
+		issued, err = s.cardSvc.IssueCard(r.Context(), qparams.CardType, qparams.CardIssuer, qparams.UserID, currency)
+	}
+	if errors.Is(err, card.ErrProductNotFound) {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	if errors.Is(err, card.ErrTooManyCards) {
+		http.Error(w, err.Error(), 409)
+		return
+	}
+	// допустимые типы и платёжные системы задаются настройками выпуска
+	settings := s.cardSvc.IssueSettings()
+	if errors.Is(err, card.ErrInvaildCardType) {
+		s.writeValidationError(w, r, FieldError{Field: "card_type", Message: "must be one of " + strings.Join(settings.CardTypes, ", ")})
+		return
+	}
+	if errors.Is(err, card.ErrInvaildCardIssuer) {
+		s.writeValidationError(w, r, FieldError{Field: "card_issuer", Message: "must be one of " + strings.Join(settings.CardIssuers, ", ")})
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
+	s.writeJSON(w, r, issued)
+}
 
-	//
-	err = card.CheckUserID(s.cardSvc.GetCards(), qparams.UserID)
+// orderCard - заказ пластиковой карты по продукту или по типу и платёжной системе
+func (s *Server) orderCard(ctx context.Context, qparams PurchaseCardParams, currency card.Currency) (*card.CardOrder, error) {
+	productID := qparams.ProductID
+	if productID == "" {
+		p, err := s.cardSvc.FindProduct(qparams.CardType, qparams.CardIssuer, currency)
+		if err != nil {
+			return nil, err
+		}
+		productID = p.ID
+	}
+	return s.cardSvc.OrderCard(ctx, productID, qparams.UserID, *qparams.Address)
+}
+
+// ----------------------------------------------------------------
+// handlerCardOrder - GET /cardOrder?cardID=: заказ пластиковой карты и история его этапов
+func (s *Server) handlerCardOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	cardID, ok := s.queryID(w, r, "cardID")
+	if !ok {
+		return
+	}
+	order, err := s.cardSvc.GetOrder(cardID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("user %v does not exist", qparams.UserID), 400)
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, order)
+}
+
+// handlerActivateCard - POST /activateCard: активация доставленной пластиковой карты
+func (s *Server) handlerActivateCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams BlockParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	c, err := s.cardSvc.ActivateCard(r.Context(), qparams.CardID)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
 		return
 	}
+	s.writeJSON(w, r, c)
+}
 
-	//
-	mxid := card.GetMaxIDFromcards(s.cardSvc.GetCards())
-	s.cardSvc.SetCards(card.AddParamCardToCardslice(s.cardSvc.GetCards(), qparams.CardType, qparams.CardIssuer, qparams.UserID, mxid))
+// ----------------------------------------------------------------
+// handlerProducts - GET /products: продукты каталога, доступные для заказа
+func (s *Server) handlerProducts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	s.writeJSON(w, r, s.cardSvc.Products())
 }
 
 // ----------------------------------------------------------------
@@ -95,32 +280,324 @@ type userCards struct {
 	Cards       []*card.Card
 }
 
-//
 type gucError struct {
 }
 
 func (s *Server) handlerGetUserCards(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("userID")
-	userID2, err := strconv.ParseInt(userID, 10, 64)
-	if err != nil {
-		http.Error(w, "userid not parsed to int64", 400)
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	userID2, ok := s.queryID(w, r, "userID")
+	if !ok {
 		return
 	}
-	err = card.CheckUserID(s.cardSvc.GetCards(), userID2)
+	crdsUser, err := s.cardSvc.UserCards(userID2)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("user %v does not exist", userID2), 400)
 		return
 	}
-	crdsUser := card.ReturnCardsByUserID(userID2, s.cardSvc.GetCards())
 	crdsUserStruct := &userCards{CardsLength: int64(len(crdsUser)), Cards: crdsUser}
 
 	crdsUserStructJSON, err := json.Marshal(crdsUserStruct)
 	if err != nil {
 		http.Error(w, "500 Internal Server Error", 500)
-		log.Println(err.Error())
+		s.logger.Error(r.Context(), "encode response", "error", err)
 		return
 	}
 	//
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(crdsUserStructJSON)
 }
+
+// ----------------------------------------------------------------
+func (s *Server) handlerReconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	report := s.cardSvc.Reconcile()
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error", 500)
+		s.logger.Error(r.Context(), "encode response", "error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(reportJSON)
+	if err != nil {
+		s.logger.Warn(r.Context(), "write response", "error", err)
+	}
+}
+
+type ReverseParams struct {
+	TransactionID int64 `json:"transaction_id"`
+}
+
+func (p *ReverseParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("transaction_id", p.TransactionID)
+	return fe.err()
+}
+
+type RefundParams struct {
+	TransactionID int64      `json:"transaction_id"`
+	Amount        card.Money `json:"amount"`
+}
+
+func (p *RefundParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("transaction_id", p.TransactionID)
+	fe.amount("amount", p.Amount)
+	return fe.err()
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams ReverseParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	tr, err := s.cardSvc.Reverse(r.Context(), qparams.TransactionID)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, tr)
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerRefundTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams RefundParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	tr, err := s.cardSvc.Refund(r.Context(), qparams.TransactionID, qparams.Amount)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, tr)
+}
+
+type AuthorizeParams struct {
+	CardID     int64      `json:"card_id"`
+	Amount     card.Money `json:"amount"`
+	MccCode    string     `json:"mcc"`
+	TTLSeconds int64      `json:"ttl_seconds"` // необязательно, по умолчанию card.DefaultHoldTTL
+}
+
+func (p *AuthorizeParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("card_id", p.CardID)
+	fe.amount("amount", p.Amount)
+	fe.mcc("mcc", p.MccCode)
+	if p.TTLSeconds < 0 {
+		fe.add("ttl_seconds", "must not be negative")
+	}
+	return fe.err()
+}
+
+type CaptureParams struct {
+	HoldID int64      `json:"hold_id"`
+	Amount card.Money `json:"amount"` // необязательно, по умолчанию вся сумма холда
+}
+
+func (p *CaptureParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("hold_id", p.HoldID)
+	if p.Amount.IsNegative() {
+		fe.add("amount", "must be positive")
+	}
+	return fe.err()
+}
+
+type VoidParams struct {
+	HoldID int64 `json:"hold_id"`
+}
+
+func (p *VoidParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("hold_id", p.HoldID)
+	return fe.err()
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams AuthorizeParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	hold, err := s.cardSvc.Authorize(r.Context(), qparams.CardID, qparams.Amount, qparams.MccCode, time.Duration(qparams.TTLSeconds)*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, hold)
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerCapture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams CaptureParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	tr, err := s.cardSvc.Capture(r.Context(), qparams.HoldID, qparams.Amount)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, tr)
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerVoid(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams VoidParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	hold, err := s.cardSvc.Void(r.Context(), qparams.HoldID)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, hold)
+}
+
+type LimitsParams struct {
+	CardID int64       `json:"card_id"`
+	Limits card.Limits `json:"limits"`
+}
+
+// Validate - валюта и группы MCC проверяются сервисом (card.ErrInvalidLimits)
+func (p *LimitsParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("card_id", p.CardID)
+	for name, m := range map[string]card.Money{"limits.per_transaction": p.Limits.PerTransaction,
+		"limits.daily": p.Limits.Daily, "limits.monthly": p.Limits.Monthly} {
+		if m.IsNegative() {
+			fe.add(name, "must not be negative")
+		}
+	}
+	sort.Slice(fe, func(i, j int) bool { return fe[i].Field < fe[j].Field })
+	return fe.err()
+}
+
+// ----------------------------------------------------------------
+// GET /limits?cardID=1 - лимиты карты, POST /limits - задать лимиты
+func (s *Server) handlerLimits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cardID, ok := s.queryID(w, r, "cardID")
+		if !ok {
+			return
+		}
+		limits, err := s.cardSvc.GetLimits(cardID)
+		if err != nil {
+			http.Error(w, err.Error(), transactionErrorStatus(err))
+			return
+		}
+		s.writeJSON(w, r, &LimitsParams{CardID: cardID, Limits: limits})
+	case http.MethodPost:
+		var qparams LimitsParams
+		if !s.decodeJSON(w, r, &qparams) {
+			return
+		}
+		err := s.cardSvc.SetLimits(r.Context(), qparams.CardID, qparams.Limits)
+		if err != nil {
+			http.Error(w, err.Error(), transactionErrorStatus(err))
+			return
+		}
+		s.writeJSON(w, r, &qparams)
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// BlockParams - карта для /blockCard, /unblockCard и /activateCard
+type BlockParams struct {
+	CardID int64 `json:"card_id"`
+}
+
+func (p *BlockParams) Validate() error {
+	var fe fieldErrors
+	fe.positiveID("card_id", p.CardID)
+	return fe.err()
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerBlockCard(w http.ResponseWriter, r *http.Request) {
+	s.setBlocked(w, r, s.cardSvc.BlockCard)
+}
+
+// ----------------------------------------------------------------
+func (s *Server) handlerUnblockCard(w http.ResponseWriter, r *http.Request) {
+	s.setBlocked(w, r, s.cardSvc.UnblockCard)
+}
+
+func (s *Server) setBlocked(w http.ResponseWriter, r *http.Request, set func(ctx context.Context, cardID int64) (*card.Card, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	var qparams BlockParams
+	if !s.decodeJSON(w, r, &qparams) {
+		return
+	}
+	c, err := set(r.Context(), qparams.CardID)
+	if err != nil {
+		http.Error(w, err.Error(), transactionErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, r, c)
+}
+
+// transactionErrorStatus - ошибки сервиса в коды HTTP; группы те же, что у errorStatus в grpcapp
+func transactionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, card.ErrTransactionNotFound), errors.Is(err, card.ErrHoldNotFound), errors.Is(err, card.ErrCardNotFound),
+		errors.Is(err, card.ErrOrderNotFound):
+		return 404
+	case errors.Is(err, card.ErrHoldNotActive), errors.Is(err, card.ErrOrderNotDelivered), errors.Is(err, card.ErrCardFromBalanceLessThenAmount):
+		return 409
+	case errors.Is(err, card.ErrLimitPerTransaction), errors.Is(err, card.ErrLimitDaily), errors.Is(err, card.ErrLimitMonthly),
+		errors.Is(err, card.ErrMCCGroupDenied), errors.Is(err, card.ErrMCCGroupNotAllowed), errors.Is(err, card.ErrCardBlocked),
+		errors.Is(err, card.ErrCardNotActivated), errors.Is(err, card.ErrFraudDeclined):
+		return 403
+	}
+	return 400
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	trJSON, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error", 500)
+		s.logger.Error(r.Context(), "encode response", "error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(trJSON)
+	if err != nil {
+		s.logger.Warn(r.Context(), "write response", "error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func TestServer_Products(t *testing.T) {
+	svc, srv := newTestServer(t)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var products []card.Product
+	err = json.NewDecoder(resp.Body).Decode(&products)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 || len(products) != len(svc.Products()) {
+		t.Fatalf("GET /products = %d %+v, %v", resp.StatusCode, products, err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		product string
+	}{
+		{name: "by product", body: `{"product_id": "virtual-visa-usd", "user_id": 1}`, status: 200, product: "virtual-visa-usd"},
+		{name: "by type and issuer", body: `{"card_type": "virtual", "card_issuer": "Master", "user_id": 1}`, status: 200, product: "virtual-master"},
+		{name: "unknown product", body: `{"product_id": "metal-amex", "user_id": 1}`, status: 404},
+		{name: "unknown user", body: `{"product_id": "virtual-visa-usd", "user_id": 42}`, status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/purchaseCard", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != 200 {
+				return
+			}
+			var issued card.Card
+			if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+				t.Fatal(err)
+			}
+			if issued.ProductID != tt.product || !card.ValidCardNumber(issued.CardNumber) {
+				t.Errorf("purchaseCard = %+v", issued)
+			}
+		})
+	}
+
+	resp, err = http.Post(srv.URL+"/products", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 405 {
+		t.Errorf("POST /products status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestServer_OrderAndActivateCard(t *testing.T) {
+	svc, srv := newTestServer(t)
+	defer srv.Close()
+	post := func(path string, body string) *http.Response {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("/purchaseCard", `{"card_type": "plastic", "card_issuer": "Visa", "user_id": 1}`)
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("plastic without address status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = post("/purchaseCard", `{"card_type": "plastic", "card_issuer": "Visa", "user_id": 1,
+		"address": {"recipient": "Ivan Ivanov", "city": "Moscow", "street": "Tverskaya 1", "postal_code": "125009"}}`)
+	var order card.CardOrder
+	err := json.NewDecoder(resp.Body).Decode(&order)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 || order.CardID != 3 || order.Status != card.OrderOrdered {
+		t.Fatalf("purchaseCard = %d %+v, %v", resp.StatusCode, order, err)
+	}
+
+	resp = post("/activateCard", `{"card_id": 3}`)
+	resp.Body.Close()
+	if resp.StatusCode != 409 {
+		t.Errorf("activate before delivery status = %d, want 409", resp.StatusCode)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := svc.AdvanceOrder(context.Background(), 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp = post("/activateCard", `{"card_id": 3}`)
+	var activated card.Card
+	err = json.NewDecoder(resp.Body).Decode(&activated)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 || activated.Inactive {
+		t.Fatalf("activateCard = %d %+v, %v", resp.StatusCode, activated, err)
+	}
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{url: "/cardOrder?cardID=3", status: 200},
+		{url: "/cardOrder?cardID=1", status: 404},
+		{url: "/cardOrder?cardID=x", status: 400},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s status = %d, want %d", tt.url, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestTransactionErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: card.ErrHoldNotFound, want: 404},
+		{err: card.ErrHoldNotActive, want: 409},
+		{err: card.ErrCardFromBalanceLessThenAmount, want: 409},
+		{err: card.ErrCardBlocked, want: 403},
+		{err: fmt.Errorf("%w: score 90", card.ErrFraudDeclined), want: 403},
+		{err: card.ErrInvalidAmount, want: 400},
+	}
+	for _, tt := range tests {
+		if got := transactionErrorStatus(tt.err); got != tt.want {
+			t.Errorf("transactionErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
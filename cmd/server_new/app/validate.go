@@ -0,0 +1,210 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// MaxBodyBytes - предел тела JSON-запроса; больше - 413
+const MaxBodyBytes = 64 << 10
+
+// FieldError - ошибка одного поля запроса: "card_type: must be one of plastic, virtual"
+type FieldError struct {
+	Field   string `json:"field"` // имя поля в JSON, вложенные - через точку (address.city)
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError - все ошибки полей запроса; ответ - 400 с телом {"error": ..., "fields": [...]}
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator - DTO запроса проверяет свои поля после разбора JSON
+type Validator interface {
+	Validate() error
+}
+
+// fieldErrors - накопитель ошибок для Validate
+type fieldErrors []FieldError
+
+func (fe *fieldErrors) add(field, format string, args ...interface{}) {
+	*fe = append(*fe, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// positiveID - обязательный ID: отсутствующее поле разбирается как 0
+func (fe *fieldErrors) positiveID(field string, id int64) {
+	if id == 0 {
+		fe.add(field, "is required")
+	} else if id < 0 {
+		fe.add(field, "must be positive")
+	}
+}
+
+// required - непустая строка
+func (fe *fieldErrors) required(field string, v string) {
+	if strings.TrimSpace(v) == "" {
+		fe.add(field, "is required")
+	}
+}
+
+// oneOf - значение из списка
+func (fe *fieldErrors) oneOf(field string, v string, allowed []string) {
+	for _, a := range allowed {
+		if v == a {
+			return
+		}
+	}
+	fe.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// amount - обязательная положительная сумма (валюту проверяет Money.UnmarshalJSON)
+func (fe *fieldErrors) amount(field string, m card.Money) {
+	switch {
+	case m.IsZero():
+		fe.add(field, "is required")
+	case m.IsNegative():
+		fe.add(field, "must be positive")
+	}
+}
+
+// mcc - четыре цифры
+func (fe *fieldErrors) mcc(field string, v string) {
+	if len(v) != 4 || strings.Trim(v, "0123456789") != "" {
+		fe.add(field, "must be 4 digits")
+	}
+}
+
+func (fe fieldErrors) err() error {
+	if len(fe) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fe}
+}
+
+// decodeJSON - тело запроса в dst: не больше MaxBodyBytes, без неизвестных полей и лишних данных после объекта,
+// затем dst.Validate(); при ошибке отвечает сам и возвращает false
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, dst Validator) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errors.New("unexpected data after JSON object")
+		}
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", MaxBodyBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		s.writeValidationError(w, r, decodeError(err))
+		return false
+	}
+	if err := dst.Validate(); err != nil {
+		s.writeValidationError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// decodeError - ошибка encoding/json как ошибка поля, где поле известно
+func decodeError(err error) *ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		// пустое поле - не объект на верхнем уровне ([...], число, строка)
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return &ValidationError{Fields: []FieldError{{Field: field, Message: "must be " + jsonType(typeErr.Type.Kind().String())}}}
+	case errors.As(err, &syntaxErr):
+		return &ValidationError{Fields: []FieldError{{Field: "body", Message: fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)}}}
+	case errors.Is(err, io.EOF):
+		return &ValidationError{Fields: []FieldError{{Field: "body", Message: "is required"}}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return &ValidationError{Fields: []FieldError{{Field: field, Message: "unknown field"}}}
+	}
+	return &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+}
+
+// jsonType - тип Go в терминах JSON для сообщения
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "an integer"
+	case strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "slice", kind == "array":
+		return "an array"
+	}
+	return "an object"
+}
+
+// writeValidationError - 400 с перечнем полей; FieldError - одно поле, прочие ошибки - поле body
+func (s *Server) writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var verr *ValidationError
+	var ferr FieldError
+	switch {
+	case errors.As(err, &verr):
+	case errors.As(err, &ferr):
+		verr = &ValidationError{Fields: []FieldError{ferr}}
+	default:
+		verr = &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+	}
+	s.logger.Debug(r.Context(), "invalid request", "error", verr)
+	body, _ := json.Marshal(struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}{Error: verr.Error(), Fields: verr.Fields})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if _, err := w.Write(body); err != nil {
+		s.logger.Warn(r.Context(), "write response", "error", err)
+	}
+}
+
+// queryID - обязательный положительный целый параметр запроса (?userID=1); при ошибке отвечает сам
+func (s *Server) queryID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	var fe fieldErrors
+	raw := r.URL.Query().Get(name)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	switch {
+	case raw == "":
+		fe.add(name, "is required")
+	case err != nil:
+		fe.add(name, "must be an integer")
+	default:
+		fe.positiveID(name, id)
+	}
+	if err := fe.err(); err != nil {
+		s.writeValidationError(w, r, err)
+		return 0, false
+	}
+	return id, true
+}
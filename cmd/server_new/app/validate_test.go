@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServer_Validation(t *testing.T) {
+	_, _, srv := newTestApp(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		fields []FieldError
+	}{
+		{name: "missing user", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "Visa"}`, status: 400,
+			fields: []FieldError{{Field: "user_id", Message: "is required"}}},
+		{name: "bad card type", path: "/purchaseCard", body: `{"card_type": "metal", "user_id": 1}`, status: 400,
+			fields: []FieldError{{Field: "card_type", Message: "must be one of plastic, virtual"}, {Field: "card_issuer", Message: "is required"}}},
+		{name: "issuer not allowed", path: "/purchaseCard", body: `{"card_type": "virtual", "card_issuer": "Amex", "user_id": 1}`, status: 400,
+			fields: []FieldError{{Field: "card_issuer", Message: "must be one of Master, Visa, UnionPay"}}},
+		{name: "bad currency", path: "/purchaseCard", body: `{"product_id": "virtual-visa", "user_id": 1, "currency": "XXX"}`, status: 400,
+			fields: []FieldError{{Field: "currency", Message: "must be one of CNY, EUR, RUB, USD"}}},
+		{name: "incomplete address", path: "/purchaseCard", body: `{"product_id": "plastic-visa", "user_id": 1, "address": {"city": "Moscow"}}`, status: 400,
+			fields: []FieldError{{Field: "address.recipient", Message: "is required"}, {Field: "address.street", Message: "is required"},
+				{Field: "address.postal_code", Message: "is required"}}},
+		{name: "unknown field", path: "/purchaseCard", body: `{"product_id": "virtual-visa", "user_id": 1, "userid": 2}`, status: 400,
+			fields: []FieldError{{Field: "userid", Message: "unknown field"}}},
+		{name: "wrong type", path: "/purchaseCard", body: `{"product_id": "virtual-visa", "user_id": "1"}`, status: 400,
+			fields: []FieldError{{Field: "user_id", Message: "must be an integer"}}},
+		{name: "trailing data", path: "/blockCard", body: `{"card_id": 1} {"card_id": 2}`, status: 400,
+			fields: []FieldError{{Field: "body", Message: "unexpected data after JSON object"}}},
+		{name: "empty body", path: "/blockCard", body: ``, status: 400,
+			fields: []FieldError{{Field: "body", Message: "is required"}}},
+		{name: "not an object", path: "/blockCard", body: `[1, 2]`, status: 400,
+			fields: []FieldError{{Field: "body", Message: "must be an object"}}},
+		{name: "syntax", path: "/blockCard", body: `{"card_id": }`, status: 400,
+			fields: []FieldError{{Field: "body", Message: "invalid JSON at offset 13"}}},
+		{name: "authorize", path: "/authorize", body: `{"card_id": -1, "amount": {"amount": -5, "currency": "RUB"}, "mcc": "54a1", "ttl_seconds": -1}`, status: 400,
+			fields: []FieldError{{Field: "card_id", Message: "must be positive"}, {Field: "amount", Message: "must be positive"},
+				{Field: "mcc", Message: "must be 4 digits"}, {Field: "ttl_seconds", Message: "must not be negative"}}},
+		{name: "refund", path: "/refundTransaction", body: `{"transaction_id": 1}`, status: 400,
+			fields: []FieldError{{Field: "amount", Message: "is required"}}},
+		{name: "capture", path: "/capture", body: `{}`, status: 400, fields: []FieldError{{Field: "hold_id", Message: "is required"}}},
+		{name: "void", path: "/void", body: `{}`, status: 400, fields: []FieldError{{Field: "hold_id", Message: "is required"}}},
+		{name: "reverse", path: "/reverseTransaction", body: `{}`, status: 400, fields: []FieldError{{Field: "transaction_id", Message: "is required"}}},
+		{name: "activate", path: "/activateCard", body: `{}`, status: 400, fields: []FieldError{{Field: "card_id", Message: "is required"}}},
+		{name: "limits", path: "/limits", body: `{"card_id": 1, "limits": {"daily": {"amount": -1, "currency": "RUB"}}}`, status: 400,
+			fields: []FieldError{{Field: "limits.daily", Message: "must not be negative"}}},
+		{name: "too large", path: "/blockCard", body: `{"card_id": 1, "pad": "` + strings.Repeat("x", MaxBodyBytes) + `"}`, status: 413},
+		{name: "valid", path: "/blockCard", body: `{"card_id": 1}`, status: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.fields == nil {
+				return
+			}
+			var got struct {
+				Error  string       `json:"error"`
+				Fields []FieldError `json:"fields"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.fields) || got.Error != (&ValidationError{Fields: tt.fields}).Error() {
+				t.Errorf("got %+v, want %+v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestServer_QueryValidation(t *testing.T) {
+	_, _, srv := newTestApp(t)
+	defer srv.Close()
+
+	tests := []struct {
+		path  string
+		field FieldError
+	}{
+		{path: "/getusercards/", field: FieldError{Field: "userID", Message: "is required"}},
+		{path: "/getusercards/?userID=one", field: FieldError{Field: "userID", Message: "must be an integer"}},
+		{path: "/cardOrder?cardID=0", field: FieldError{Field: "cardID", Message: "is required"}},
+		{path: "/limits?cardID=-3", field: FieldError{Field: "cardID", Message: "must be positive"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got ValidationError
+			if code := getJSON(t, srv.URL+tt.path, &got); code != 400 || len(got.Fields) != 1 || got.Fields[0] != tt.field {
+				t.Errorf("GET %s = %d %+v, want %+v", tt.path, code, got, tt.field)
+			}
+		})
+	}
+}
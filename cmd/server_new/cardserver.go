@@ -1,49 +1,214 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // база часовых зон для /time и /echo в образе без tzdata (alpine)
+
+	"google.golang.org/grpc"
 
 	"github.com/wool/go2hw11/cmd/server_new/app"
+	"github.com/wool/go2hw11/cmd/server_new/config"
+	"github.com/wool/go2hw11/cmd/server_new/grpcapp"
 	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/cardpb"
+	"github.com/wool/go2hw11/pkg/fraud"
+	"github.com/wool/go2hw11/pkg/lifecycle"
+	"github.com/wool/go2hw11/pkg/logging"
+	"github.com/wool/go2hw11/pkg/version"
+	"github.com/wool/go2hw11/pkg/webhook"
 )
 
-const defaultPort = "9999"
-const defaultHost = "0.0.0.0"
-
 func main() {
-	port, ok := os.LookupEnv("PORT")
-	if !ok {
-		port = defaultPort
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		config.Usage(os.Stderr)
+		return
 	}
-
-	host, ok := os.LookupEnv("HOST")
-	if !ok {
-		host = defaultHost
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
 	}
+	log.Printf("server_new %s (commit %s, built %s)", version.Version, version.Commit, version.BuildTime)
+	cfg.Print(log.Writer())
 
-	log.Println(host)
-	log.Println(port)
+	// SIGTERM (деплой) и SIGINT - корректная остановка: дождаться запросов, сбросить файлы
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	if err := execute(net.JoinHostPort(host, port)); err != nil {
+	if err := execute(ctx, cfg); err != nil {
 		log.Println(err)
 		os.Exit(1)
 	}
 }
 
-func execute(addr string) (err error) {
+func execute(ctx context.Context, cfg *config.Config) (err error) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	// JSON-строки в stderr; log.Println из пакетов тоже попадает сюда записями уровня info
+	logger := logging.New(os.Stderr, level)
+	// стандартный log глобальный: после выхода из execute возвращаем прежние настройки
+	prevFlags, prevOutput := log.Flags(), log.Writer()
+	defer func() {
+		log.SetFlags(prevFlags)
+		log.SetOutput(prevOutput)
+	}()
+	log.SetFlags(0)
+	log.SetOutput(logger.StdWriter(logging.LevelInfo))
+
+	lc := lifecycle.New(time.Duration(cfg.ShutdownTimeout))
+
 	cardSvc := card.NewService()
-	cardSvc.SetCards(card.InitCardsHW11()) // инициализация карт - один раз при запуске приложения
+	cardSvc.SetLogger(logger.With("component", "card"))
+	if err := cardSvc.SetIssueSettings(cfg.IssueSettings()); err != nil {
+		return err
+	}
+	if cfg.Bank.ProductsFile != "" {
+		catalog, err := card.LoadCatalogFromJSON(cfg.Bank.ProductsFile)
+		if err != nil {
+			return err
+		}
+		cardSvc.SetCatalog(catalog)
+	}
+	// инициализация карт - один раз при запуске приложения
+	switch cfg.Seed {
+	case config.SeedHW11:
+		cardSvc.SetCards(card.InitCardsHW11())
+	case config.SeedFixture:
+		cards, err := card.LoadFixture(cfg.SeedFile)
+		if err != nil {
+			return fmt.Errorf("seed: %w", err)
+		}
+		cardSvc.SetCards(cards)
+		logger.Info(ctx, "cards seeded", "file", cfg.SeedFile, "cards", len(cards))
+	case config.SeedNone:
+		// пользователей неоткуда узнать - карты выпускаются любому user_id
+		cardSvc.AllowAnyUser(true)
+	}
+
+	rates := card.DefaultRates()
+	if cfg.RatesFile != "" {
+		rates, err = card.LoadRatesFromJSON(cfg.RatesFile)
+		if err != nil {
+			return err
+		}
+	}
+	cardSvc.SetRateProvider(rates)
+
+	// журнал антифрода закрывает хук остановки, но хуки вызывает только lc.Run:
+	// при выходе из execute раньше файл закрывается здесь
+	var auditFile *os.File
+	defer func() {
+		if auditFile != nil {
+			auditFile.Close()
+		}
+	}()
+	if cfg.Fraud.RulesFile != "" {
+		screener, file, err := loadFraudEngine(cfg.Fraud.RulesFile, cfg.Fraud.AuditLog)
+		if err != nil {
+			return err
+		}
+		auditFile = file
+		cardSvc.SetScreener(screener)
+		lc.OnShutdown("fraud audit log", func(ctx context.Context) error {
+			if err := file.Sync(); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		})
+	}
+
+	if cfg.Webhook.URL != "" {
+		dispatcher, err := webhook.NewDispatcher(webhook.Config{
+			Endpoints:   []webhook.Endpoint{{URL: cfg.Webhook.URL, Secret: cfg.Webhook.Secret}},
+			DeadLetters: webhook.NewFileDeadLetters(cfg.Webhook.DeadLetters),
+		})
+		if err != nil {
+			return err
+		}
+		unsubscribe := dispatcher.Subscribe(cardSvc.Events())
+		lc.OnShutdown("webhooks", func(ctx context.Context) error {
+			unsubscribe()
+			return dispatcher.Shutdown(ctx)
+		})
+	}
+
+	// заказы пластиковых карт проходят этапы печати и доставки сами
+	lc.Serve("fulfilment", card.NewFulfilmentWorker(cardSvc, time.Duration(cfg.FulfilmentStep)))
 
 	mux := http.NewServeMux()
 	application := app.NewServer(cardSvc, mux)
+	application.SetLogger(logger.With("component", "http"))
+	application.SetRateLimits(cfg.RateLimit.Routes, cfg.RateLimit.TrustForwardedFor, cfg.RateLimit.TrustUserHeader)
 	application.Init()
 
 	server := &http.Server{
-		Addr:    addr,
-		Handler: application,
+		Addr:              net.JoinHostPort(cfg.Host, cfg.Port),
+		Handler:           application,
+		ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.HTTP.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTP.IdleTimeout),
+	}
+	server.RegisterOnShutdown(application.Shutdown)
+	lc.Serve("http", &lifecycle.HTTPServer{Server: server})
+
+	// gRPC - на отдельном порту поверх того же cardSvc
+	grpcListener, err := net.Listen("tcp", net.JoinHostPort(cfg.Host, cfg.GRPCPort))
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(grpcapp.UnaryInterceptor(logger.With("component", "grpc"))))
+	cardpb.RegisterCardServiceServer(grpcServer, grpcapp.NewServer(cardSvc))
+	lc.Serve("grpc", lifecycle.ServerFuncs{
+		ServeFunc: func() error {
+			return grpcServer.Serve(grpcListener)
+		},
+		ShutdownFunc: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				grpcServer.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+
+	auditFile = nil // дальше файл закрывает хук остановки
+	return lc.Run(ctx)
+}
+
+// loadFraudEngine - правила антифрода из файла, журнал решений - в auditPath; файл закрывает вызывающий
+func loadFraudEngine(rulesFile string, auditPath string) (*fraud.Engine, *os.File, error) {
+	cfg, err := fraud.LoadConfig(rulesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := cfg.Rules()
+	if err != nil {
+		return nil, nil, err
+	}
+	auditFile, err := os.OpenFile(auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, nil, err
 	}
-	return server.ListenAndServe()
+	return fraud.NewEngine(fraud.NewAuditLog(auditFile), rules...), auditFile, nil
 }
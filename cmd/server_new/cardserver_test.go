@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/cmd/server_new/config"
+)
+
+// freePort - свободный локальный порт для сервера
+func freePort(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port
+}
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+// startExecute - execute с настройками args на свободных портах; клиент без keep-alive: запасное соединение
+// без запросов http.Server.Shutdown считает простаивающим только через 5s
+func startExecute(t *testing.T, args ...string) (addr, grpcAddr string, client *http.Client, stop func() error) {
+	port, grpcPort := freePort(t), freePort(t)
+	cfg, err := config.Load(append([]string{"-host", "127.0.0.1", "-port", port, "-grpc-port", grpcPort}, args...), noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, grpcAddr = net.JoinHostPort("127.0.0.1", port), net.JoinHostPort("127.0.0.1", grpcPort)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- execute(ctx, cfg) }()
+	stop = func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Second): // дольше ожидания соединений StateNew в Shutdown
+			return errors.New("execute() did not stop")
+		}
+	}
+
+	client = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	var resp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("http://" + addr + "/healthz"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return addr, grpcAddr, client, stop
+}
+
+func TestExecute_GracefulShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cardserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	auditLog := filepath.Join(dir, "audit.log")
+	addr, grpcAddr, client, stop := startExecute(t, "-fraud-rules-file", "../../test/fraud_rules.json", "-fraud-audit-log", auditLog)
+
+	// покупка проходит через антифрод - в журнал пишется решение
+	resp, err := client.Post("http://"+addr+"/authorize", "application/json",
+		strings.NewReader(`{"card_id": 1, "amount": {"amount": 100, "currency": "RUB"}, "mcc": "5411"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := stop(); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+
+	for _, a := range []string{addr, grpcAddr} {
+		if conn, err := net.Dial("tcp", a); err == nil {
+			conn.Close()
+			t.Errorf("%s is still listening", a)
+		}
+	}
+	content, err := ioutil.ReadFile(auditLog)
+	if err != nil || !strings.Contains(string(content), `"outcome"`) {
+		t.Errorf("audit log = %q, %v", content, err)
+	}
+}
+
+func TestExecute_SeedNone(t *testing.T) {
+	addr, _, client, stop := startExecute(t, "-seed", config.SeedNone)
+	defer func() {
+		if err := stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// без начальных карт выпуск доступен любому пользователю
+	resp, err := client.Post("http://"+addr+"/purchaseCard", "application/json",
+		strings.NewReader(`{"card_type": "virtual", "card_issuer": "Visa", "user_id": 42}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issued struct{ ID, UserID int64 }
+	err = json.NewDecoder(resp.Body).Decode(&issued)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 || issued.ID != 1 || issued.UserID != 42 {
+		t.Fatalf("purchaseCard = %d %+v, %v", resp.StatusCode, issued, err)
+	}
+
+	resp, err = client.Get("http://" + addr + "/getusercards/?userID=42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cards struct{ CardsLength int64 }
+	err = json.NewDecoder(resp.Body).Decode(&cards)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 || cards.CardsLength != 1 {
+		t.Errorf("getusercards = %d %+v, %v", resp.StatusCode, cards, err)
+	}
+}
+
+func TestExecute_RestoresStdLogger(t *testing.T) {
+	prevFlags, prevOutput := log.Flags(), log.Writer()
+	_, _, _, stop := startExecute(t, "-seed", config.SeedNone)
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if log.Flags() != prevFlags || log.Writer() != prevOutput {
+		t.Errorf("std logger = %d %T, want %d %T", log.Flags(), log.Writer(), prevFlags, prevOutput)
+	}
+}
+
+// openFiles - пути файлов, открытых процессом (только Linux)
+func openFiles(t *testing.T) []string {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("open files are not listed: %v", err)
+	}
+	paths := make([]string, 0, len(fds))
+	for _, fd := range fds {
+		if path, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func TestExecute_EarlyReturnClosesAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cardserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	auditLog := filepath.Join(dir, "audit.log")
+
+	// порт gRPC занят - execute выходит после открытия журнала антифрода
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	_, grpcPort, _ := net.SplitHostPort(busy.Addr().String())
+	cfg, err := config.Load([]string{"-host", "127.0.0.1", "-port", freePort(t), "-grpc-port", grpcPort,
+		"-fraud-rules-file", "../../test/fraud_rules.json", "-fraud-audit-log", auditLog}, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := execute(context.Background(), cfg); err == nil {
+		t.Fatal("execute() error = nil, want address in use")
+	}
+	for _, path := range openFiles(t) {
+		if path == auditLog {
+			t.Errorf("audit log %s is still open", auditLog)
+		}
+	}
+}
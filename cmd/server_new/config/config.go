@@ -0,0 +1,361 @@
+// Package config - настройки сервера карт: файл (JSON), затем переменные окружения, затем флаги;
+// каждый следующий источник переопределяет предыдущий
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/logging"
+	"github.com/wool/go2hw11/pkg/ratelimit"
+)
+
+var ErrInvalidConfig = errors.New("config is not valid")
+
+// источники начальных данных
+const (
+	SeedHW11    = "hw11"    // card.InitCardsHW11
+	SeedFixture = "fixture" // card.LoadFixture(SeedFile)
+	SeedNone    = "none"    // без карт; карты выпускаются любому пользователю
+)
+
+// Duration - time.Duration, в файле - строка вида "15s"
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type HTTPConfig struct {
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	ReadTimeout       Duration `json:"read_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"` // SSE-поток продлевает его сам
+	IdleTimeout       Duration `json:"idle_timeout"`
+}
+
+type FraudConfig struct {
+	RulesFile string `json:"rules_file"` // пусто - антифрод выключен
+	AuditLog  string `json:"audit_log"`
+}
+
+type WebhookConfig struct {
+	URL         string `json:"url"` // пусто - вебхуки выключены
+	Secret      string `json:"secret"`
+	DeadLetters string `json:"dead_letters"`
+}
+
+type BankConfig struct {
+	Name            string   `json:"name"`
+	DueDate         string   `json:"due_date"` // срок действия карт без срока в продукте, YYYY-MM-DD
+	CardTypes       []string `json:"card_types"`
+	CardIssuers     []string `json:"card_issuers"`
+	ProductsFile    string   `json:"products_file"`      // каталог продуктов; пусто - card.DefaultCatalog
+	MaxCardsPerUser int      `json:"max_cards_per_user"` // 0 - без предела
+}
+
+// RateLimitConfig - лимиты запросов по шаблону маршрута HTTP ("/purchaseCard"); маршрут из файла заменяет
+// значение по умолчанию, {} - снимает лимит
+type RateLimitConfig struct {
+	TrustForwardedFor bool                        `json:"trust_forwarded_for"` // IP клиента из X-Forwarded-For (за своим прокси)
+	TrustUserHeader   bool                        `json:"trust_user_header"`   // пользователь из X-User-ID (его ставит свой прокси)
+	Routes            map[string]ratelimit.Policy `json:"routes"`
+}
+
+// Config - все настройки сервера
+type Config struct {
+	Host            string          `json:"host"`
+	Port            string          `json:"port"`
+	GRPCPort        string          `json:"grpc_port"`
+	ShutdownTimeout Duration        `json:"shutdown_timeout"`
+	HTTP            HTTPConfig      `json:"http"`
+	RatesFile       string          `json:"rates_file"` // пусто - card.DefaultRates
+	Fraud           FraudConfig     `json:"fraud"`
+	Webhook         WebhookConfig   `json:"webhook"`
+	Bank            BankConfig      `json:"bank"`
+	RateLimit       RateLimitConfig `json:"rate_limit"`
+	Seed            string          `json:"seed"`            // SeedHW11, SeedFixture или SeedNone
+	SeedFile        string          `json:"seed_file"`       // фикстура для SeedFixture: .json, .xml или .csv
+	FulfilmentStep  Duration        `json:"fulfilment_step"` // этап имитации доставки пластиковых карт
+	LogLevel        string          `json:"log_level"`       // debug, info, warn, error
+}
+
+// Default - значения по умолчанию (совпадают с прежними константами сервера)
+func Default() *Config {
+	issue := card.DefaultIssueSettings()
+	return &Config{
+		Host:            "0.0.0.0",
+		Port:            "9999",
+		GRPCPort:        "9998",
+		ShutdownTimeout: Duration(15 * time.Second),
+		HTTP: HTTPConfig{
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(15 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+		},
+		Fraud:   FraudConfig{AuditLog: "fraud_audit.log"},
+		Webhook: WebhookConfig{DeadLetters: "webhook_dead_letters.log"},
+		Bank: BankConfig{Name: issue.BankName, DueDate: issue.DueDate, CardTypes: issue.CardTypes, CardIssuers: issue.CardIssuers,
+			MaxCardsPerUser: issue.MaxCardsPerUser},
+		// выпуск карт в цикле: не больше 10 подряд с IP и 5 на пользователя, дальше - раз в 10 и 30 секунд
+		RateLimit: RateLimitConfig{Routes: map[string]ratelimit.Policy{
+			"/purchaseCard": {PerIP: ratelimit.Rule{Rate: 0.1, Burst: 10}, PerUser: ratelimit.Rule{Rate: 1.0 / 30, Burst: 5}},
+		}},
+		Seed:           SeedHW11,
+		FulfilmentStep: Duration(card.DefaultFulfilmentStep),
+		LogLevel:       "info",
+	}
+}
+
+// setting - одна настройка: имя флага, переменная окружения, чтение и запись строкой
+type setting struct {
+	flag   string
+	env    string
+	usage  string
+	secret bool
+	get    func() string
+	set    func(v string) error
+}
+
+func stringSetting(flag, env, usage string, p *string) setting {
+	return setting{flag: flag, env: env, usage: usage,
+		get: func() string { return *p },
+		set: func(v string) error { *p = v; return nil },
+	}
+}
+
+func durationSetting(flag, env, usage string, p *Duration) setting {
+	return setting{flag: flag, env: env, usage: usage,
+		get: p.String,
+		set: func(v string) error { return p.UnmarshalText([]byte(v)) },
+	}
+}
+
+func intSetting(flag, env, usage string, p *int) setting {
+	return setting{flag: flag, env: env, usage: usage,
+		get: func() string { return strconv.Itoa(*p) },
+		set: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			*p = n
+			return nil
+		},
+	}
+}
+
+// listSetting - список через запятую
+func listSetting(flag, env, usage string, p *[]string) setting {
+	return setting{flag: flag, env: env, usage: usage,
+		get: func() string { return strings.Join(*p, ",") },
+		set: func(v string) error {
+			list := make([]string, 0)
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			*p = list
+			return nil
+		},
+	}
+}
+
+// settings - таблица настроек для окружения, флагов и печати
+func (c *Config) settings() []setting {
+	secret := stringSetting("webhook-secret", "WEBHOOK_SECRET", "HMAC secret for webhook signatures", &c.Webhook.Secret)
+	secret.secret = true
+	return []setting{
+		stringSetting("host", "HOST", "listen host", &c.Host),
+		stringSetting("port", "PORT", "HTTP port", &c.Port),
+		stringSetting("grpc-port", "GRPC_PORT", "gRPC port", &c.GRPCPort),
+		durationSetting("shutdown-timeout", "SHUTDOWN_TIMEOUT", "graceful shutdown deadline", &c.ShutdownTimeout),
+		durationSetting("http-read-header-timeout", "HTTP_READ_HEADER_TIMEOUT", "HTTP read header timeout", &c.HTTP.ReadHeaderTimeout),
+		durationSetting("http-read-timeout", "HTTP_READ_TIMEOUT", "HTTP read timeout", &c.HTTP.ReadTimeout),
+		durationSetting("http-write-timeout", "HTTP_WRITE_TIMEOUT", "HTTP write timeout", &c.HTTP.WriteTimeout),
+		durationSetting("http-idle-timeout", "HTTP_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", &c.HTTP.IdleTimeout),
+		stringSetting("rates-file", "RATES_FILE", "FX rates JSON file (default: built-in rates)", &c.RatesFile),
+		stringSetting("fraud-rules-file", "FRAUD_RULES_FILE", "fraud rules JSON file (empty: screening off)", &c.Fraud.RulesFile),
+		stringSetting("fraud-audit-log", "FRAUD_AUDIT_LOG", "fraud decisions log", &c.Fraud.AuditLog),
+		stringSetting("webhook-url", "WEBHOOK_URL", "webhook receiver URL (empty: webhooks off)", &c.Webhook.URL),
+		secret,
+		stringSetting("webhook-dead-letters", "WEBHOOK_DEAD_LETTERS", "undelivered webhooks log", &c.Webhook.DeadLetters),
+		stringSetting("bank-name", "BANK_NAME", "bank name on new cards", &c.Bank.Name),
+		stringSetting("card-due-date", "CARD_DUE_DATE", "due date of new cards, YYYY-MM-DD", &c.Bank.DueDate),
+		listSetting("card-types", "CARD_TYPES", "allowed card types, comma separated", &c.Bank.CardTypes),
+		listSetting("card-issuers", "CARD_ISSUERS", "allowed card issuers, comma separated", &c.Bank.CardIssuers),
+		intSetting("max-cards-per-user", "MAX_CARDS_PER_USER", "cards a user may hold (0: no cap)", &c.Bank.MaxCardsPerUser),
+		stringSetting("products-file", "PRODUCTS_FILE", "card product catalogue JSON file (default: built-in catalogue)", &c.Bank.ProductsFile),
+		stringSetting("seed", "SEED", "initial cards: hw11, fixture or none", &c.Seed),
+		stringSetting("seed-file", "SEED_FILE", "fixture file for -seed fixture (.json, .xml or .csv)", &c.SeedFile),
+		durationSetting("fulfilment-step", "FULFILMENT_STEP", "simulated plastic card production/delivery step", &c.FulfilmentStep),
+		stringSetting("log-level", "LOG_LEVEL", "log level: debug, info, warn or error", &c.LogLevel),
+	}
+}
+
+// Load - значения по умолчанию, затем файл (-config или CONFIG_FILE), окружение и флаги;
+// lookupEnv - обычно os.LookupEnv; для -h возвращает flag.ErrHelp
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	settings := c.settings()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	configFile := fs.String("config", "", "config file (JSON); env CONFIG_FILE")
+	flags := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flags[s.flag] = fs.String(s.flag, "", s.usage+"; env "+s.env)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("%w: unexpected arguments %v", ErrInvalidConfig, fs.Args())
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	path := *configFile
+	if !set["config"] {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if v, ok := lookupEnv(s.env); ok {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("%w: env %s: %v", ErrInvalidConfig, s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if set[s.flag] {
+			if err := s.set(*flags[s.flag]); err != nil {
+				return nil, fmt.Errorf("%w: flag -%s: %v", ErrInvalidConfig, s.flag, err)
+			}
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile - JSON-файл поверх текущих значений; неизвестные поля - ошибка
+func (c *Config) loadFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+func validPort(p string) bool {
+	n, err := strconv.Atoi(p)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// Validate - проверка значений
+func (c *Config) Validate() error {
+	if !validPort(c.Port) || !validPort(c.GRPCPort) {
+		return fmt.Errorf("%w: ports must be in 1..65535 (port %q, grpc_port %q)", ErrInvalidConfig, c.Port, c.GRPCPort)
+	}
+	if c.Port == c.GRPCPort {
+		return fmt.Errorf("%w: port and grpc_port must differ", ErrInvalidConfig)
+	}
+	for name, d := range map[string]Duration{
+		"shutdown_timeout": c.ShutdownTimeout, "http.read_header_timeout": c.HTTP.ReadHeaderTimeout,
+		"http.read_timeout": c.HTTP.ReadTimeout, "http.write_timeout": c.HTTP.WriteTimeout, "http.idle_timeout": c.HTTP.IdleTimeout,
+		"fulfilment_step": c.FulfilmentStep,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%w: %s must be positive", ErrInvalidConfig, name)
+		}
+	}
+	if c.Webhook.URL != "" && c.Webhook.Secret == "" {
+		return fmt.Errorf("%w: webhook secret is required with webhook url", ErrInvalidConfig)
+	}
+	if err := c.IssueSettings().Validate(); err != nil {
+		return fmt.Errorf("%w: bank: %v", ErrInvalidConfig, err)
+	}
+	for route, p := range c.RateLimit.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("%w: rate_limit route %q must start with /", ErrInvalidConfig, route)
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%w: rate_limit %s: %v", ErrInvalidConfig, route, err)
+		}
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if c.Seed != SeedHW11 && c.Seed != SeedFixture && c.Seed != SeedNone {
+		return fmt.Errorf("%w: seed %q (want %s, %s or %s)", ErrInvalidConfig, c.Seed, SeedHW11, SeedFixture, SeedNone)
+	}
+	if (c.Seed == SeedFixture) != (c.SeedFile != "") {
+		return fmt.Errorf("%w: seed_file is required with seed %s and allowed only with it", ErrInvalidConfig, SeedFixture)
+	}
+	return nil
+}
+
+// IssueSettings - параметры выпуска карт для card.Service
+func (c *Config) IssueSettings() card.IssueSettings {
+	return card.IssueSettings{BankName: c.Bank.Name, DueDate: c.Bank.DueDate, CardTypes: c.Bank.CardTypes, CardIssuers: c.Bank.CardIssuers,
+		MaxCardsPerUser: c.Bank.MaxCardsPerUser}
+}
+
+// Print - действующие значения, секреты скрыты
+func (c *Config) Print(w io.Writer) {
+	for _, s := range c.settings() {
+		v := s.get()
+		if s.secret && v != "" {
+			v = "***"
+		}
+		fmt.Fprintf(w, "%s=%s\n", s.env, v)
+	}
+}
+
+// Usage - описание флагов
+func Usage(w io.Writer) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.String("config", "", "config file (JSON); env CONFIG_FILE")
+	for _, s := range Default().settings() {
+		fs.String(s.flag, s.get(), s.usage+"; env "+s.env)
+	}
+	fs.PrintDefaults()
+}
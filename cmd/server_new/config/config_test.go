@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "config*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `{"port": "8001", "grpc_port": "8002", "shutdown_timeout": "1m", "bank": {"name": "FileBank", "card_issuers": ["Visa"]}}`)
+	defer os.Remove(path)
+
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		port   string
+		bank   string
+		grpc   string
+		issuer string
+	}{
+		{name: "defaults", port: "9999", grpc: "9998", bank: "Tinkoff", issuer: "Master,Visa,UnionPay"},
+		{name: "file", args: []string{"-config", path}, port: "8001", grpc: "8002", bank: "FileBank", issuer: "Visa"},
+		{name: "file from env", env: map[string]string{"CONFIG_FILE": path}, port: "8001", grpc: "8002", bank: "FileBank", issuer: "Visa"},
+		{name: "env over file", args: []string{"-config", path}, env: map[string]string{"PORT": "8101", "CARD_ISSUERS": "Visa, Master"},
+			port: "8101", grpc: "8002", bank: "FileBank", issuer: "Visa,Master"},
+		{name: "flags over env", args: []string{"-config", path, "-port", "8201", "-bank-name", "FlagBank"}, env: map[string]string{"PORT": "8101", "BANK_NAME": "EnvBank"},
+			port: "8201", grpc: "8002", bank: "FlagBank", issuer: "Visa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.args, env(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != tt.port || cfg.GRPCPort != tt.grpc || cfg.Bank.Name != tt.bank || strings.Join(cfg.Bank.CardIssuers, ",") != tt.issuer {
+				t.Errorf("Load() = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	unknown := writeConfig(t, `{"prot": "8001"}`)
+	defer os.Remove(unknown)
+	badLimit := writeConfig(t, `{"rate_limit": {"routes": {"/purchaseCard": {"per_ip": {"rate": 1}}}}}`)
+	defer os.Remove(badLimit)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{name: "unknown file field", args: []string{"-config", unknown}},
+		{name: "missing file", args: []string{"-config", filepath.Join(os.TempDir(), "no-such-config.json")}},
+		{name: "bad port", args: []string{"-port", "http"}},
+		{name: "same ports", args: []string{"-port", "9998"}},
+		{name: "bad duration", env: map[string]string{"SHUTDOWN_TIMEOUT": "soon"}},
+		{name: "zero timeout", args: []string{"-http-write-timeout", "0s"}},
+		{name: "webhook without secret", args: []string{"-webhook-url", "http://localhost/hook"}},
+		{name: "bad due date", env: map[string]string{"CARD_DUE_DATE": "01.01.2030"}},
+		{name: "unknown card type", args: []string{"-card-types", "plastic,metal"}},
+		{name: "no issuers", args: []string{"-card-issuers", ""}},
+		{name: "bad seed", args: []string{"-seed", "random"}},
+		{name: "fixture without file", args: []string{"-seed", "fixture"}},
+		{name: "seed file without fixture", env: map[string]string{"SEED_FILE": "seed.json"}},
+		{name: "negative card cap", env: map[string]string{"MAX_CARDS_PER_USER": "-1"}},
+		{name: "rate limit without burst", args: []string{"-config", badLimit}},
+		{name: "unknown flag", args: []string{"-verbose"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.args, env(tt.env)); err == nil {
+				t.Error("Load() error = nil")
+			}
+		})
+	}
+
+	if _, err := Load([]string{"-h"}, env(nil)); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Load(-h) error = %v, want %v", err, flag.ErrHelp)
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	cfg, err := Load([]string{"-webhook-url", "http://localhost/hook", "-webhook-secret", "s3cret", "-shutdown-timeout", "90s"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(cfg.ShutdownTimeout) != 90*time.Second {
+		t.Errorf("ShutdownTimeout = %v", cfg.ShutdownTimeout)
+	}
+	buf := &bytes.Buffer{}
+	cfg.Print(buf)
+	out := buf.String()
+	for _, want := range []string{"PORT=9999\n", "WEBHOOK_SECRET=***\n", "SHUTDOWN_TIMEOUT=1m30s\n", "CARD_ISSUERS=Master,Visa,UnionPay\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print() = %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Error("Print() shows the webhook secret")
+	}
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	cfg, err := Load([]string{"-config", "../../../test/server.json"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// пути в примере - от корня репозитория
+	if cards, err := card.LoadFixture(filepath.Join("../../..", cfg.SeedFile)); err != nil || len(cards) == 0 {
+		t.Errorf("LoadFixture(%s) = %d cards, %v", cfg.SeedFile, len(cards), err)
+	}
+}
+
+func TestLoad_RateLimit(t *testing.T) {
+	path := writeConfig(t, `{"rate_limit": {"trust_forwarded_for": true, "trust_user_header": true, "routes": {
+		"/authorize": {"per_ip": {"rate": 5, "burst": 20}},
+		"/purchaseCard": {"per_user": {"rate": 1, "burst": 2}}}}}`)
+	defer os.Remove(path)
+
+	cfg, err := Load([]string{"-config", path, "-max-cards-per-user", "3"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := cfg.RateLimit.Routes
+	// маршрут из файла заменяет значение по умолчанию целиком, остальные маршруты по умолчанию остаются
+	if !cfg.RateLimit.TrustForwardedFor || !cfg.RateLimit.TrustUserHeader || len(routes) != 2 || routes["/authorize"].PerIP.Burst != 20 ||
+		routes["/purchaseCard"].PerIP.Enabled() || routes["/purchaseCard"].PerUser.Burst != 2 {
+		t.Errorf("RateLimit = %+v", cfg.RateLimit)
+	}
+	if cfg.IssueSettings().MaxCardsPerUser != 3 {
+		t.Errorf("MaxCardsPerUser = %d, want 3", cfg.IssueSettings().MaxCardsPerUser)
+	}
+}
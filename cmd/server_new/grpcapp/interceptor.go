@@ -0,0 +1,39 @@
+package grpcapp
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/wool/go2hw11/pkg/logging"
+)
+
+// MetadataRequestID - ID запроса в метаданных gRPC (как заголовок X-Request-ID в HTTP)
+const MetadataRequestID = "x-request-id"
+
+// UnaryInterceptor - ID запроса из метаданных (или новый) в контекст и в заголовок ответа, журнал доступа
+func UnaryInterceptor(logger *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(MetadataRequestID); len(v) > 0 && len(v[0]) <= 64 {
+				id = v[0]
+			}
+		}
+		if id == "" {
+			id = logging.NewRequestID()
+		}
+		ctx = logging.WithRequestID(ctx, id)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRequestID, id))
+
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		logger.Info(ctx, "grpc request", "method", info.FullMethod, "code", code.String(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000)
+		return resp, err
+	}
+}
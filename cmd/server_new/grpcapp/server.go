@@ -0,0 +1,161 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/cardpb"
+)
+
+// Server - gRPC-обёртка над тем же card.Service, что и HTTP-сервер app.Server
+type Server struct {
+	cardpb.UnimplementedCardServiceServer
+	cardSvc *card.Service
+}
+
+func NewServer(cardSvc *card.Service) *Server {
+	return &Server{cardSvc: cardSvc}
+}
+
+func (s *Server) IssueCard(ctx context.Context, req *cardpb.IssueCardRequest) (*cardpb.Card, error) {
+	if req.ProductId != "" {
+		issued, err := s.cardSvc.IssueProduct(ctx, req.ProductId, req.UserId)
+		if err != nil {
+			return nil, errorStatus(err)
+		}
+		return toPBCard(issued), nil
+	}
+	currency := card.DefaultCurrency
+	if req.Currency != "" {
+		var err error
+		currency, err = card.ParseCurrency(req.Currency)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	issued, err := s.cardSvc.IssueCard(ctx, req.CardType, req.CardIssuer, req.UserId, currency)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return toPBCard(issued), nil
+}
+
+func (s *Server) ListUserCards(ctx context.Context, req *cardpb.ListUserCardsRequest) (*cardpb.ListUserCardsResponse, error) {
+	cards, err := s.cardSvc.UserCards(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "user %v does not exist", req.UserId)
+	}
+	resp := &cardpb.ListUserCardsResponse{}
+	for _, c := range cards {
+		resp.Cards = append(resp.Cards, toPBCard(c))
+	}
+	return resp, nil
+}
+
+func (s *Server) Transfer(ctx context.Context, req *cardpb.TransferRequest) (*cardpb.TransferResponse, error) {
+	amount, err := fromPBMoney(req.Amount)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.cardSvc.Transfer(ctx, req.FromCardId, req.ToCardId, amount); err != nil {
+		return nil, errorStatus(err)
+	}
+	return &cardpb.TransferResponse{}, nil
+}
+
+func (s *Server) SpendingByCategory(ctx context.Context, req *cardpb.SpendingByCategoryRequest) (*cardpb.SpendingByCategoryResponse, error) {
+	cards, err := s.cardSvc.UserCards(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "user %v does not exist", req.UserId)
+	}
+	var method func(tr []*card.Transaction, ownerID int64) (card.Spending, error)
+	switch req.Method {
+	case cardpb.AnalyticsMethod_ANALYTICS_METHOD_F1:
+		method = card.F1
+	case cardpb.AnalyticsMethod_ANALYTICS_METHOD_F2:
+		method = card.F2
+	case cardpb.AnalyticsMethod_ANALYTICS_METHOD_F3:
+		method = card.F3
+	case cardpb.AnalyticsMethod_ANALYTICS_METHOD_F4:
+		method = card.F4
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown analytics method %v", req.Method)
+	}
+	transactions := make([]*card.Transaction, 0)
+	for _, c := range cards {
+		transactions = append(transactions, c.Transactions...)
+	}
+	spending, err := method(transactions, req.UserId)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	resp := &cardpb.SpendingByCategoryResponse{Spending: make([]*cardpb.CategorySpending, 0, len(spending))}
+	for _, key := range spending.Keys() {
+		resp.Spending = append(resp.Spending, &cardpb.CategorySpending{Category: key.Category, Amount: toPBMoney(spending.Money(key))})
+	}
+	return resp, nil
+}
+
+// errorStatus - ошибки сервиса в коды gRPC
+func errorStatus(err error) error {
+	code := codes.InvalidArgument
+	switch {
+	case errors.Is(err, card.ErrCardNotFound), errors.Is(err, card.ErrCardFromNotFound), errors.Is(err, card.ErrCardToNotFound),
+		errors.Is(err, card.ErrBothCardsNotFound), errors.Is(err, card.ErrNoCardWithUserID), errors.Is(err, card.ErrProductNotFound):
+		code = codes.NotFound
+	case errors.Is(err, card.ErrCardFromBalanceLessThenAmount), errors.Is(err, card.ErrTooManyCards):
+		code = codes.FailedPrecondition
+	case errors.Is(err, card.ErrCardBlocked), errors.Is(err, card.ErrCardNotActivated), errors.Is(err, card.ErrLimitPerTransaction), errors.Is(err, card.ErrLimitDaily),
+		errors.Is(err, card.ErrLimitMonthly), errors.Is(err, card.ErrFraudDeclined):
+		code = codes.PermissionDenied
+	case errors.Is(err, card.ErrNoRateProvider), errors.Is(err, card.ErrRateNotFound):
+		code = codes.Unavailable
+	case errors.Is(err, card.ErrAmountOverflow):
+		code = codes.OutOfRange
+	}
+	return status.Error(code, err.Error())
+}
+
+func toPBMoney(m card.Money) *cardpb.Money {
+	return &cardpb.Money{Amount: m.Amount, Currency: string(m.Currency)}
+}
+
+// fromPBMoney - пустая валюта - RUB
+func fromPBMoney(m *cardpb.Money) (card.Money, error) {
+	if m == nil {
+		return card.Money{}, fmt.Errorf("%w: amount is not set", card.ErrInvalidMoney)
+	}
+	currency := card.DefaultCurrency
+	if m.Currency != "" {
+		var err error
+		currency, err = card.ParseCurrency(m.Currency)
+		if err != nil {
+			return card.Money{}, err
+		}
+	}
+	return card.NewMoney(m.Amount, currency), nil
+}
+
+func toPBTransaction(t *card.Transaction) *cardpb.Transaction {
+	return &cardpb.Transaction{
+		Id: t.ID, TranType: t.TranType, TranSum: toPBMoney(t.TranSum), TranDate: t.TranDate, MccCode: t.MccCode,
+		Status: string(t.Status), OwnerId: t.OwnerID, Related: t.Related,
+	}
+}
+
+func toPBCard(c *card.Card) *cardpb.Card {
+	pb := &cardpb.Card{
+		Id: c.ID, Type: c.Type, BankName: c.BankName, CardNumber: c.CardNumber, CardDueDate: c.CardDueDate,
+		Balance: toPBMoney(c.Balance), Available: toPBMoney(c.Available), UserId: c.UserID, IsVirtual: c.IsVirtual, Blocked: c.Blocked,
+		ProductId: c.ProductID, Inactive: c.Inactive,
+	}
+	for _, t := range c.Transactions {
+		pb.Transactions = append(pb.Transactions, toPBTransaction(t))
+	}
+	return pb
+}
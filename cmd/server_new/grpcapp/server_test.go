@@ -0,0 +1,169 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/cardpb"
+	"github.com/wool/go2hw11/pkg/logging"
+)
+
+func newTestClient(t *testing.T) (*card.Service, cardpb.CardServiceClient, func()) {
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{
+		{ID: 1, UserID: 1, Balance: card.Rub(1000_00), Transactions: []*card.Transaction{
+			{ID: 1, TranType: card.TranTypePurchase, TranSum: card.Rub(100_00), MccCode: "5411", Status: card.StatusDone, OwnerID: 1},
+			{ID: 2, TranType: card.TranTypePurchase, TranSum: card.Rub(50_00), MccCode: "5812", Status: card.StatusDone, OwnerID: 1},
+		}},
+		{ID: 2, UserID: 2, Balance: card.Rub(0)},
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(UnaryInterceptor(logging.Nop())))
+	cardpb.RegisterCardServiceServer(srv, NewServer(svc))
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc, cardpb.NewCardServiceClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestServer_CardService(t *testing.T) {
+	svc, client, stop := newTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	issued, err := client.IssueCard(ctx, &cardpb.IssueCardRequest{CardType: "virtual", CardIssuer: "Visa", UserId: 2, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.Id != 3 || !issued.IsVirtual || issued.Balance.Currency != "USD" || issued.ProductId != "virtual-visa-usd" {
+		t.Errorf("IssueCard() = %v", issued)
+	}
+	issued, err = client.IssueCard(ctx, &cardpb.IssueCardRequest{ProductId: "virtual-master-eur", UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.Id != 4 || issued.Type != "Master" || issued.Balance.Currency != "EUR" || !card.ValidCardNumber(issued.CardNumber) {
+		t.Errorf("IssueCard(product) = %v", issued)
+	}
+
+	if _, err := client.Transfer(ctx, &cardpb.TransferRequest{FromCardId: 1, ToCardId: 2, Amount: &cardpb.Money{Amount: 10_00}}); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := client.ListUserCards(ctx, &cardpb.ListUserCardsRequest{UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards.Cards) != 3 || cards.Cards[0].Balance.Amount != 10_00 || len(cards.Cards[0].Transactions) != 1 {
+		t.Errorf("ListUserCards() = %v", cards)
+	}
+	if c, _ := svc.SearchByID(1); c.Balance.Amount != 990_00 {
+		t.Errorf("balance = %v, want 990.00 RUB", c.Balance)
+	}
+
+	for _, method := range []cardpb.AnalyticsMethod{cardpb.AnalyticsMethod_ANALYTICS_METHOD_F1, cardpb.AnalyticsMethod_ANALYTICS_METHOD_F4} {
+		spending, err := client.SpendingByCategory(ctx, &cardpb.SpendingByCategoryRequest{UserId: 1, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := card.F1(svc.GetCards()[0].Transactions, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(spending.Spending) != len(want) {
+			t.Fatalf("SpendingByCategory(%v) = %v, want %v", method, spending.Spending, want)
+		}
+		for _, s := range spending.Spending {
+			key := card.SpendingKey{Category: s.Category, Currency: card.Currency(s.Amount.Currency)}
+			if s.Amount.Amount != want[key] {
+				t.Errorf("SpendingByCategory(%v) %v = %v, want %d", method, key, s.Amount, want[key])
+			}
+		}
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, client, stop := newTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{name: "unknown user", code: codes.NotFound, call: func() error {
+			_, err := client.ListUserCards(ctx, &cardpb.ListUserCardsRequest{UserId: 42})
+			return err
+		}},
+		{name: "invalid issuer", code: codes.InvalidArgument, call: func() error {
+			_, err := client.IssueCard(ctx, &cardpb.IssueCardRequest{CardType: "virtual", CardIssuer: "Mir", UserId: 1})
+			return err
+		}},
+		{name: "unknown product", code: codes.NotFound, call: func() error {
+			_, err := client.IssueCard(ctx, &cardpb.IssueCardRequest{ProductId: "metal-amex", UserId: 1})
+			return err
+		}},
+		{name: "card not found", code: codes.NotFound, call: func() error {
+			_, err := client.Transfer(ctx, &cardpb.TransferRequest{FromCardId: 1, ToCardId: 42, Amount: &cardpb.Money{Amount: 1}})
+			return err
+		}},
+		{name: "insufficient funds", code: codes.FailedPrecondition, call: func() error {
+			_, err := client.Transfer(ctx, &cardpb.TransferRequest{FromCardId: 2, ToCardId: 1, Amount: &cardpb.Money{Amount: 1}})
+			return err
+		}},
+		{name: "no amount", code: codes.InvalidArgument, call: func() error {
+			_, err := client.Transfer(ctx, &cardpb.TransferRequest{FromCardId: 1, ToCardId: 2})
+			return err
+		}},
+		{name: "unknown method", code: codes.InvalidArgument, call: func() error {
+			_, err := client.SpendingByCategory(ctx, &cardpb.SpendingByCategoryRequest{UserId: 1, Method: 42})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.code {
+				t.Errorf("code = %v, want %v", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptor_RequestID(t *testing.T) {
+	_, client, stop := newTestClient(t)
+	defer stop()
+
+	for _, sent := range []string{"abc-123", ""} {
+		ctx := context.Background()
+		if sent != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, MetadataRequestID, sent)
+		}
+		var header metadata.MD
+		if _, err := client.ListUserCards(ctx, &cardpb.ListUserCardsRequest{UserId: 1}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		got := header.Get(MetadataRequestID)
+		if len(got) != 1 || (sent != "" && got[0] != sent) || got[0] == "" {
+			t.Errorf("%s = %v, sent %q", MetadataRequestID, got, sent)
+		}
+	}
+}
@@ -1,4 +1,18 @@
 module github.com/wool/go2hw11
 
-go 1.14
-// +heroku goVersion go1.14
\ No newline at end of file
+go 1.17
+
+// +heroku goVersion go1.17
+
+require (
+	google.golang.org/grpc v1.54.0
+	google.golang.org/protobuf v1.30.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	golang.org/x/net v0.8.0 // indirect
+	golang.org/x/sys v0.6.0 // indirect
+	golang.org/x/text v0.8.0 // indirect
+	google.golang.org/genproto v0.0.0-20230110181048-76db0878b65f // indirect
+)
@@ -0,0 +1,155 @@
+// Тесты и бенчмарки F1-F4 на данных из datagen; datagen импортирует card, поэтому пакет - card_test
+package card_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wool/go2hw11/pkg/card"
+	"github.com/wool/go2hw11/pkg/datagen"
+)
+
+var methods = []struct {
+	name string
+	f    func(tr []*card.Transaction, ownerID int64) (card.Spending, error)
+}{
+	{name: "F1", f: card.F1},
+	{name: "F2", f: card.F2},
+	{name: "F3", f: card.F3},
+	{name: "F4", f: card.F4},
+}
+
+// spendingByCategory - эталон для F1-F4
+func spendingByCategory(tr []*card.Transaction, ownerID int64) card.Spending {
+	want := make(card.Spending)
+	for _, t := range tr {
+		if t.OwnerID == ownerID {
+			want[card.SpendingKey{Category: card.TranslateMCC(t.MccCode), Currency: t.TranSum.Currency}] += t.TranSum.Amount
+		}
+	}
+	return want
+}
+
+func TestF(t *testing.T) {
+	// размеры вокруг числа частей в F2-F4 (100) и заметно больше
+	for _, n := range []int{0, 1, 99, 100, 101, 10_000} {
+		transactions := datagen.Transactions(int64(n), n, 10)
+		for _, owner := range []int64{1, 7, 11} { // 11 - владельца нет
+			want := spendingByCategory(transactions, owner)
+			for _, m := range methods {
+				t.Run(fmt.Sprintf("%s/%d/owner%d", m.name, n, owner), func(t *testing.T) {
+					if got, err := m.f(transactions, owner); err != nil || !reflect.DeepEqual(got, want) {
+						t.Errorf("%s() = %v, %v, want %v", m.name, got, err, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestF_Currencies(t *testing.T) {
+	grocery := card.TranslateMCC("5411")
+	tests := []struct {
+		name    string
+		tr      []*card.Transaction
+		want    card.Spending
+		wantErr error
+	}{
+		{
+			name: "currencies are not mixed",
+			tr: []*card.Transaction{
+				{OwnerID: 1, MccCode: "5411", TranSum: card.Rub(100_00)},
+				{OwnerID: 1, MccCode: "5411", TranSum: card.NewMoney(5_00, card.USD)},
+				{OwnerID: 1, MccCode: "5411", TranSum: card.Rub(50_00)},
+			},
+			want: card.Spending{{Category: grocery, Currency: card.RUB}: 150_00, {Category: grocery, Currency: card.USD}: 5_00},
+		},
+		{
+			name: "overflow",
+			tr: []*card.Transaction{
+				{OwnerID: 1, MccCode: "5411", TranSum: card.Rub(math.MaxInt64)},
+				{OwnerID: 1, MccCode: "5411", TranSum: card.Rub(1)},
+			},
+			wantErr: card.ErrAmountOverflow,
+		},
+	}
+	for _, tt := range tests {
+		for _, m := range methods {
+			t.Run(tt.name+"/"+m.name, func(t *testing.T) {
+				got, err := m.f(tt.tr, 1)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s() error = %v, want %v", m.name, err, tt.wantErr)
+				}
+				if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s() = %v, want %v", m.name, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// benchSizes - число транзакций в бенчмарках; BENCH_TRANSACTIONS=1000000,5000000 задаёт свои размеры
+func benchSizes(b *testing.B) []int {
+	if env := os.Getenv("BENCH_TRANSACTIONS"); env != "" {
+		sizes := make([]int, 0)
+		for _, s := range strings.Split(env, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 0 {
+				b.Fatalf("BENCH_TRANSACTIONS: invalid size %q", s)
+			}
+			sizes = append(sizes, n)
+		}
+		return sizes
+	}
+	if testing.Short() {
+		return []int{10_000, 100_000}
+	}
+	return []int{10_000, 100_000, 1_000_000}
+}
+
+// benchData - сгенерированные транзакции по размеру: генерация миллионов дороже самого подсчёта
+var benchData = struct {
+	sync.Mutex
+	bySize map[int][]*card.Transaction
+}{bySize: make(map[int][]*card.Transaction)}
+
+func benchTransactions(n int) []*card.Transaction {
+	benchData.Lock()
+	defer benchData.Unlock()
+	if tr, ok := benchData.bySize[n]; ok {
+		return tr
+	}
+	// 1000 владельцев: траты одного - около 0.1% всех транзакций, как у клиента в общей массе банка
+	tr := datagen.Transactions(1, n, 1000)
+	benchData.bySize[n] = tr
+	return tr
+}
+
+// go test -bench=F -benchtime=3x ./pkg/card
+func BenchmarkF(b *testing.B) {
+	const owner = 2
+	for _, n := range benchSizes(b) {
+		transactions := benchTransactions(n)
+		want := spendingByCategory(transactions, owner)
+		for _, m := range methods {
+			b.Run(fmt.Sprintf("%s/%d", m.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					result, err := m.f(transactions, owner)
+					b.StopTimer() // время сравнения не учитывается
+					if err != nil || !reflect.DeepEqual(result, want) {
+						b.Fatalf("invalid result, got %v, want %v", result, want)
+					}
+					b.StartTimer()
+				}
+			})
+		}
+	}
+}
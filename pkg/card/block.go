@@ -0,0 +1,29 @@
+package card
+
+import "context"
+
+// BlockCard - заблокировать карту: покупки, холды и переводы с неё отклоняются; публикует CardBlocked
+func (s *Service) BlockCard(ctx context.Context, cardID int64) (blocked *Card, err error) {
+	defer func() { s.logOp(ctx, "block card", err, "card_id", cardID) }()
+	return s.setBlocked(cardID, true, EventCardBlocked)
+}
+
+// UnblockCard - снять блокировку; публикует CardUnblocked
+func (s *Service) UnblockCard(ctx context.Context, cardID int64) (unblocked *Card, err error) {
+	defer func() { s.logOp(ctx, "unblock card", err, "card_id", cardID) }()
+	return s.setBlocked(cardID, false, EventCardUnblocked)
+}
+
+func (s *Service) setBlocked(cardID int64, blocked bool, event EventType) (*Card, error) {
+	card, ok := s.SearchByID(cardID)
+	if !ok {
+		return nil, ErrCardNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if card.Blocked != blocked {
+		card.Blocked = blocked
+		s.publishCard(event, card)
+	}
+	return cardSnapshot(card), nil
+}
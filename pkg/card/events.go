@@ -0,0 +1,197 @@
+package card
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType - тип события сервиса карт
+type EventType string
+
+const (
+	EventCardIssued         EventType = "CardIssued"
+	EventCardBlocked        EventType = "CardBlocked"
+	EventCardUnblocked      EventType = "CardUnblocked"
+	EventCardOrderUpdated   EventType = "CardOrderUpdated" // новый этап заказа пластиковой карты
+	EventCardActivated      EventType = "CardActivated"
+	EventTransactionPosted  EventType = "TransactionPosted"  // новая транзакция (в т.ч. pending по холду)
+	EventTransactionUpdated EventType = "TransactionUpdated" // смена статуса: capture, void, reversal, refund
+)
+
+// Event - событие; Card, Transaction и Order - копии на момент события
+type Event struct {
+	ID          int64        `json:"id"`
+	Type        EventType    `json:"type"`
+	Time        int64        `json:"time"` // unix timestamp
+	UserID      int64        `json:"user_id"`
+	CardID      int64        `json:"card_id"`
+	Card        *Card        `json:"card,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Order       *CardOrder   `json:"order,omitempty"`
+}
+
+// DefaultEventHistory - сколько последних событий шина хранит для повторной выдачи (Since)
+const DefaultEventHistory = 1000
+
+// DefaultSubscriberQueue - сколько необработанных событий ждёт подписчика; сверх этого события ему не доставляются
+const DefaultSubscriberQueue = 1024
+
+// EventBus - шина событий; Publish не блокируется, каждый подписчик получает события в своей горутине по порядку
+type EventBus struct {
+	mu         sync.Mutex
+	seq        int64
+	history    []Event
+	limit      int
+	queueLimit int
+	dropped    int64
+	subs       map[int64]*subscriber
+	subSeq     int64
+}
+
+func NewEventBus(historyLimit int) *EventBus {
+	if historyLimit <= 0 {
+		historyLimit = DefaultEventHistory
+	}
+	return &EventBus{limit: historyLimit, queueLimit: DefaultSubscriberQueue, subs: make(map[int64]*subscriber)}
+}
+
+// subscriber - очередь событий не длиннее limit и горутина, которая отдаёт их обработчику
+type subscriber struct {
+	mu      sync.Mutex
+	queue   []Event
+	limit   int
+	notify  chan struct{}
+	done    chan struct{}
+	handler func(Event)
+}
+
+func (sub *subscriber) run() {
+	for {
+		sub.mu.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		sub.mu.Unlock()
+		for _, e := range queue {
+			sub.handler(e)
+		}
+		select {
+		case <-sub.notify:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// push - false, если очередь полна и событие отброшено: медленный подписчик не копит память шины
+func (sub *subscriber) push(e Event) bool {
+	sub.mu.Lock()
+	full := len(sub.queue) >= sub.limit
+	if !full {
+		sub.queue = append(sub.queue, e)
+	}
+	sub.mu.Unlock()
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+	return !full
+}
+
+// Subscribe - подписка на все события; возвращает функцию отписки
+func (b *EventBus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	sub := &subscriber{limit: b.queueLimit, notify: make(chan struct{}, 1), done: make(chan struct{}), handler: handler}
+	b.subSeq++
+	id := b.subSeq
+	b.subs[id] = sub
+	b.mu.Unlock()
+	go sub.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// Publish - присвоить событию ID и время, сохранить в истории и разослать подписчикам
+func (b *EventBus) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.ID = b.seq
+	if e.Time == 0 {
+		e.Time = time.Now().Unix()
+	}
+	if len(b.history) == b.limit {
+		// сдвиг вместо среза хвоста: старые события не держат растущий массив
+		copy(b.history, b.history[1:])
+		b.history = b.history[:b.limit-1]
+	}
+	b.history = append(b.history, e)
+	for _, sub := range b.subs {
+		if !sub.push(e) {
+			b.dropped++
+		}
+	}
+	return e
+}
+
+// Dropped - сколько доставок отброшено из-за переполненных очередей подписчиков
+func (b *EventBus) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Subscribers - число активных подписчиков
+func (b *EventBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Since - события с ID больше lastID из сохранённой истории
+func (b *EventBus) Since(lastID int64) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := make([]Event, 0)
+	for _, e := range b.history {
+		if e.ID > lastID {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Events - шина событий сервиса
+func (s *Service) Events() *EventBus {
+	return s.events
+}
+
+// cardSnapshot - копия карты без истории транзакций (вызывать под s.mu)
+func cardSnapshot(c *Card) *Card {
+	cp := *c
+	cp.Transactions = nil
+	return &cp
+}
+
+// publishCard - событие по карте (вызывать под s.mu)
+func (s *Service) publishCard(t EventType, c *Card) {
+	s.events.Publish(Event{Type: t, UserID: c.UserID, CardID: c.ID, Card: cardSnapshot(c)})
+}
+
+// publishTransaction - событие по транзакции (вызывать под s.mu)
+func (s *Service) publishTransaction(t EventType, c *Card, tr *Transaction) {
+	cp := *tr
+	s.events.Publish(Event{Type: t, UserID: c.UserID, CardID: c.ID, Card: cardSnapshot(c), Transaction: &cp})
+}
+
+// publishOrder - событие по заказу карты (вызывать под s.mu)
+func (s *Service) publishOrder(o *CardOrder) {
+	s.events.Publish(Event{Type: EventCardOrderUpdated, UserID: o.UserID, CardID: o.CardID, Order: orderSnapshot(o)})
+}
@@ -0,0 +1,151 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus(2)
+	got := make(chan Event, 10)
+	unsubscribe := bus.Subscribe(func(e Event) { got <- e })
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: EventCardIssued, CardID: int64(i)})
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case e := <-got:
+			if e.ID != int64(i) || e.Time == 0 {
+				t.Errorf("event = %+v, want ID %d", e, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event is not delivered")
+		}
+	}
+	unsubscribe()
+	unsubscribe()
+
+	tests := []struct {
+		name   string
+		lastID int64
+		want   []int64
+	}{
+		{name: "history limit", lastID: 0, want: []int64{2, 3}},
+		{name: "resume", lastID: 2, want: []int64{3}},
+		{name: "up to date", lastID: 3, want: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := bus.Since(tt.lastID)
+			if len(events) != len(tt.want) {
+				t.Fatalf("Since() = %+v, want IDs %v", events, tt.want)
+			}
+			for i, e := range events {
+				if e.ID != tt.want[i] {
+					t.Errorf("Since() = %+v, want IDs %v", events, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	bus := NewEventBus(3)
+	bus.queueLimit = 2
+	started := make(chan struct{})
+	release := make(chan struct{})
+	got := make(chan int64, 10)
+	unsubscribe := bus.Subscribe(func(e Event) {
+		if e.ID == 1 {
+			close(started)
+			<-release
+		}
+		got <- e.ID
+	})
+	defer unsubscribe()
+
+	bus.Publish(Event{Type: EventCardIssued})
+	<-started
+	for i := 0; i < 4; i++ { // 2 и 3 ждут в очереди, 4 и 5 отброшены
+		bus.Publish(Event{Type: EventCardIssued})
+	}
+	if dropped := bus.Dropped(); dropped != 2 {
+		t.Errorf("Dropped() = %d, want 2", dropped)
+	}
+	close(release)
+	for _, want := range []int64{1, 2, 3} {
+		select {
+		case id := <-got:
+			if id != want {
+				t.Errorf("delivered event %d, want %d", id, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d is not delivered", want)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		bus.Publish(Event{Type: EventCardIssued})
+	}
+	if events := bus.Since(0); len(events) != 3 || events[2].ID != 105 {
+		t.Errorf("Since(0) = %d events, want the last 3", len(events))
+	}
+}
+
+func TestService_Events(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{
+		{ID: 1, UserID: 1, Balance: Rub(1000_00)},
+		{ID: 2, UserID: 1, Balance: Rub(100_00)},
+	})
+
+	issued, err := svc.IssueCard(context.Background(), "virtual", "Visa", 1, RUB)
+	if err != nil || issued.ID != 3 || !issued.IsVirtual {
+		t.Fatalf("IssueCard() = %+v, %v", issued, err)
+	}
+	if _, err := svc.IssueCard(context.Background(), "virtual", "Visa", 42, RUB); !errors.Is(err, ErrNoCardWithUserID) {
+		t.Errorf("IssueCard() error = %v, want %v", err, ErrNoCardWithUserID)
+	}
+	tr, err := svc.Purchase(context.Background(), 1, Rub(10_00), "5411")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Reverse(context.Background(), tr.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BlockCard(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BlockCard(context.Background(), 1); err != nil { // повторная блокировка не публикует событие
+		t.Fatal(err)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, Rub(10_00), "5411"); !errors.Is(err, ErrCardBlocked) {
+		t.Errorf("Purchase() error = %v, want %v", err, ErrCardBlocked)
+	}
+	if err := svc.Transfer(context.Background(), 1, 2, Rub(10_00)); !errors.Is(err, ErrCardBlocked) {
+		t.Errorf("Transfer() error = %v, want %v", err, ErrCardBlocked)
+	}
+	if err := svc.Transfer(context.Background(), 2, 1, Rub(1_00)); err != nil { // зачисления на заблокированную карту разрешены
+		t.Errorf("Transfer() to blocked card error = %v", err)
+	}
+	if _, err := svc.UnblockCard(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EventType{EventCardIssued, EventTransactionPosted, EventTransactionPosted, EventTransactionUpdated, EventCardBlocked, EventTransactionPosted, EventTransactionPosted, EventCardUnblocked}
+	events := svc.Events().Since(0)
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %v", events, want)
+	}
+	for i, e := range events {
+		if e.Type != want[i] || e.UserID != 1 || e.Card == nil || e.Card.Transactions != nil {
+			t.Errorf("event %d = %+v, want %v", i, e, want[i])
+		}
+	}
+	if events[3].Transaction.Status != StatusReversed || events[4].Card.Blocked != true {
+		t.Errorf("events = %+v", events)
+	}
+}
@@ -0,0 +1,339 @@
+package card
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFixture = errors.New("seed fixture is not valid")
+
+// форматы файла начальных данных (по расширению)
+const (
+	FixtureJSON = "json" // массив карт, как в ответе /getusercards/ (Cards) и выводе cardgen
+	FixtureXML  = "xml"  // <cards><card>...<transactions><transaction>...</transaction></transactions></card></cards>
+	FixtureCSV  = "csv"  // строка с заголовком fixtureCSVHeader; карта повторяется в строке каждой своей транзакции
+)
+
+// maxFixtureProblems - сколько ошибок проверки перечислять в сообщении
+const maxFixtureProblems = 10
+
+// LoadFixture - карты из файла; формат - по расширению (.json, .xml, .csv)
+func LoadFixture(path string) ([]*Card, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cards, err := ReadFixture(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cards, nil
+}
+
+// ReadFixture - разбор и проверка (ValidateFixture) начальных данных
+func ReadFixture(r io.Reader, format string) ([]*Card, error) {
+	var cards []*Card
+	var err error
+	switch format {
+	case FixtureJSON:
+		err = json.NewDecoder(r).Decode(&cards)
+	case FixtureXML:
+		var decoded fixtureXML
+		if err = xml.NewDecoder(r).Decode(&decoded); err == nil {
+			cards = make([]*Card, len(decoded.Cards))
+			for i, c := range decoded.Cards {
+				cards[i] = c.card()
+			}
+		}
+	case FixtureCSV:
+		cards, err = readFixtureCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q (want %s, %s or %s)", ErrInvalidFixture, format, FixtureJSON, FixtureXML, FixtureCSV)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidFixture) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFixture, err)
+	}
+	if err := ValidateFixture(cards); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// ValidateFixture - ID карт и транзакций положительные и уникальные, номера карт уникальны (без учёта пробелов),
+// валюта и статусы известны; карта без баланса - с нулевым в валюте по умолчанию,
+// у транзакции без владельца им становится владелец карты
+func ValidateFixture(cards []*Card) error {
+	problems := make([]string, 0)
+	cardIDs := make(map[int64]bool)
+	numbers := make(map[string]int64)
+	tranIDs := make(map[int64]int64)
+	for i, c := range cards {
+		if c == nil {
+			problems = append(problems, fmt.Sprintf("card #%d is empty", i+1))
+			continue
+		}
+		name := fmt.Sprintf("card %d", c.ID)
+		switch {
+		case c.ID <= 0:
+			problems = append(problems, fmt.Sprintf("card #%d: id must be positive", i+1))
+		case cardIDs[c.ID]:
+			problems = append(problems, name+": duplicate id")
+		}
+		cardIDs[c.ID] = true
+		if c.UserID <= 0 {
+			problems = append(problems, name+": user id must be positive")
+		}
+		number := strings.ReplaceAll(c.CardNumber, " ", "")
+		if number == "" {
+			problems = append(problems, name+": card number is empty")
+		} else if other, ok := numbers[number]; ok {
+			problems = append(problems, fmt.Sprintf("%s: duplicate card number %s (card %d)", name, c.CardNumber, other))
+		} else {
+			numbers[number] = c.ID
+		}
+		if c.Balance.untyped() {
+			c.Balance = NewMoney(0, DefaultCurrency)
+		}
+		if !c.Balance.Currency.Valid() {
+			problems = append(problems, fmt.Sprintf("%s: balance currency %q is not valid", name, c.Balance.Currency))
+		}
+		for _, tr := range c.Transactions {
+			if tr == nil {
+				problems = append(problems, name+": empty transaction")
+				continue
+			}
+			if tr.ID <= 0 {
+				problems = append(problems, fmt.Sprintf("%s: transaction id %d must be positive", name, tr.ID))
+			} else if other, ok := tranIDs[tr.ID]; ok {
+				problems = append(problems, fmt.Sprintf("%s: duplicate transaction id %d (card %d)", name, tr.ID, other))
+			} else {
+				tranIDs[tr.ID] = c.ID
+			}
+			if tr.OwnerID == 0 {
+				tr.OwnerID = c.UserID
+			} else if tr.OwnerID != c.UserID {
+				problems = append(problems, fmt.Sprintf("%s: transaction %d owner %d is not the card user %d", name, tr.ID, tr.OwnerID, c.UserID))
+			}
+			if tr.Status == "" {
+				tr.Status = StatusDone
+			} else if !tr.Status.Valid() {
+				problems = append(problems, fmt.Sprintf("%s: transaction %d status %q is not valid", name, tr.ID, tr.Status))
+			}
+			if !tr.TranSum.Currency.Valid() {
+				problems = append(problems, fmt.Sprintf("%s: transaction %d currency %q is not valid", name, tr.ID, tr.TranSum.Currency))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxFixtureProblems {
+		problems = append(problems[:maxFixtureProblems], fmt.Sprintf("and %d more", len(problems)-maxFixtureProblems))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidFixture, strings.Join(problems, "; "))
+}
+
+// WriteFixture - карты в формате, который читает ReadFixture
+func WriteFixture(w io.Writer, cards []*Card, format string) error {
+	switch format {
+	case FixtureJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cards)
+	case FixtureXML:
+		fx := fixtureXML{Cards: make([]xmlCard, len(cards))}
+		for i, c := range cards {
+			fx.Cards[i] = newXMLCard(c)
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(fx); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	case FixtureCSV:
+		return writeFixtureCSV(w, cards)
+	}
+	return fmt.Errorf("%w: unknown format %q", ErrInvalidFixture, format)
+}
+
+// fixtureXML - у Card нет XML-тегов, поэтому в XML карта описана отдельно
+type fixtureXML struct {
+	XMLName xml.Name  `xml:"cards"`
+	Cards   []xmlCard `xml:"card"`
+}
+
+type xmlCard struct {
+	ID           int64          `xml:"id"`
+	UserID       int64          `xml:"user_id"`
+	Issuer       string         `xml:"issuer"`
+	BankName     string         `xml:"bank_name"`
+	CardNumber   string         `xml:"card_number"`
+	DueDate      string         `xml:"due_date"`
+	Balance      Money          `xml:"balance"`
+	Virtual      bool           `xml:"virtual"`
+	Blocked      bool           `xml:"blocked,omitempty"`
+	Inactive     bool           `xml:"inactive,omitempty"`
+	ProductID    string         `xml:"product_id,omitempty"`
+	Transactions []*Transaction `xml:"transactions>transaction"`
+}
+
+func newXMLCard(c *Card) xmlCard {
+	return xmlCard{ID: c.ID, UserID: c.UserID, Issuer: c.Type, BankName: c.BankName, CardNumber: c.CardNumber, DueDate: c.CardDueDate,
+		Balance: c.Balance, Virtual: c.IsVirtual, Blocked: c.Blocked, Inactive: c.Inactive, ProductID: c.ProductID, Transactions: c.Transactions}
+}
+
+func (x xmlCard) card() *Card {
+	return &Card{ID: x.ID, UserID: x.UserID, Type: x.Issuer, BankName: x.BankName, CardNumber: x.CardNumber, CardDueDate: x.DueDate,
+		Balance: x.Balance, IsVirtual: x.Virtual, Blocked: x.Blocked, Inactive: x.Inactive, ProductID: x.ProductID, Transactions: x.Transactions}
+}
+
+// fixtureCSVHeader - колонки CSV; колонки транзакции пустые у карты без транзакций, дата - RFC 3339
+var fixtureCSVHeader = []string{
+	"card_id", "user_id", "issuer", "bank_name", "card_number", "due_date", "balance", "currency", "virtual", "blocked", "inactive", "product_id",
+	"tran_id", "tran_type", "tran_sum", "tran_currency", "tran_date", "mcc", "status",
+}
+
+// fixtureCSVCardColumns - колонки карты в начале строки, дальше - колонки транзакции
+const fixtureCSVCardColumns = 12
+
+func writeFixtureCSV(w io.Writer, cards []*Card) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fixtureCSVHeader); err != nil {
+		return err
+	}
+	for _, c := range cards {
+		cardFields := append([]string{strconv.FormatInt(c.ID, 10), strconv.FormatInt(c.UserID, 10), c.Type, c.BankName, c.CardNumber,
+			c.CardDueDate}, c.Balance.CSVFields()...)
+		cardFields = append(cardFields, strconv.FormatBool(c.IsVirtual), strconv.FormatBool(c.Blocked), strconv.FormatBool(c.Inactive), c.ProductID)
+		if len(c.Transactions) == 0 {
+			if err := cw.Write(append(cardFields, make([]string, len(fixtureCSVHeader)-fixtureCSVCardColumns)...)); err != nil {
+				return err
+			}
+		}
+		for _, tr := range c.Transactions {
+			row := append(append([]string{}, cardFields...), strconv.FormatInt(tr.ID, 10), tr.TranType)
+			row = append(row, tr.TranSum.CSVFields()...)
+			row = append(row, time.Unix(tr.TranDate, 0).UTC().Format(time.RFC3339), tr.MccCode, string(tr.Status))
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func readFixtureCSV(r io.Reader) ([]*Card, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Join(header, ",") != strings.Join(fixtureCSVHeader, ",") {
+		return nil, fmt.Errorf("%w: csv header must be %s", ErrInvalidFixture, strings.Join(fixtureCSVHeader, ","))
+	}
+	cr.FieldsPerRecord = len(fixtureCSVHeader)
+	cards := make([]*Card, 0)
+	var last *Card
+	var lastFields string // колонки карты из предыдущей строки
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		c, err := fixtureCSVCard(row)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFixture, line, err)
+		}
+		// строки одной карты идут подряд и повторяют её колонки; та же карта через другие строки - дубликат ID
+		fields := strings.Join(row[:fixtureCSVCardColumns], "\x00")
+		switch {
+		case last != nil && last.ID == c.ID && fields == lastFields:
+			c = last
+		case last != nil && last.ID == c.ID:
+			return nil, fmt.Errorf("%w: line %d: card %d columns differ from the previous line", ErrInvalidFixture, line, c.ID)
+		default:
+			cards = append(cards, c)
+		}
+		last, lastFields = c, fields
+		if row[fixtureCSVCardColumns] == "" {
+			continue
+		}
+		tr, err := fixtureCSVTransaction(row[fixtureCSVCardColumns:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFixture, line, err)
+		}
+		c.Transactions = append(c.Transactions, tr)
+	}
+	return cards, nil
+}
+
+func fixtureCSVCard(row []string) (*Card, error) {
+	id, err := strconv.ParseInt(row[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("card_id: %v", err)
+	}
+	userID, err := strconv.ParseInt(row[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user_id: %v", err)
+	}
+	balance, err := MoneyFromCSV(row[6], row[7])
+	if err != nil {
+		return nil, fmt.Errorf("balance: %v", err)
+	}
+	flags := make([]bool, 3)
+	for i, name := range []string{"virtual", "blocked", "inactive"} {
+		if flags[i], err = strconv.ParseBool(row[8+i]); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return &Card{ID: id, UserID: userID, Type: row[2], BankName: row[3], CardNumber: row[4], CardDueDate: row[5],
+		Balance: balance, IsVirtual: flags[0], Blocked: flags[1], Inactive: flags[2], ProductID: row[11]}, nil
+}
+
+// fixtureCSVTransaction - колонки с tran_id по status
+func fixtureCSVTransaction(row []string) (*Transaction, error) {
+	id, err := strconv.ParseInt(row[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("tran_id: %v", err)
+	}
+	sum, err := MoneyFromCSV(row[2], row[3])
+	if err != nil {
+		return nil, fmt.Errorf("tran_sum: %v", err)
+	}
+	date, err := time.Parse(time.RFC3339, row[4])
+	if err != nil {
+		return nil, fmt.Errorf("tran_date: %v", err)
+	}
+	var status TranStatus // пустой статус ValidateFixture заменит на done
+	if row[6] != "" {
+		if status, err = ParseStatus(row[6]); err != nil {
+			return nil, fmt.Errorf("status: %v", err)
+		}
+	}
+	return &Transaction{ID: id, TranType: row[1], TranSum: sum, TranDate: date.Unix(), MccCode: row[5], Status: status}, nil
+}
@@ -0,0 +1,171 @@
+package card
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFixture() []*Card {
+	date := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC).Unix()
+	return []*Card{
+		{ID: 1, UserID: 1, Type: "Visa", BankName: "Citi", CardNumber: "4000 0000 0000 0002", CardDueDate: "2030-01-01", Balance: Rub(1000_00),
+			Transactions: []*Transaction{
+				{ID: 1, TranType: "purchase", TranSum: Rub(150_50), TranDate: date, MccCode: "5411", Status: StatusDone, OwnerID: 1},
+				{ID: 2, TranType: "purchase", TranSum: Rub(20_00), TranDate: date, MccCode: "5912", Status: StatusPending, OwnerID: 1},
+			}},
+		{ID: 2, UserID: 2, Type: "Master", BankName: "Citi", CardNumber: "5100 0000 0000 0003", CardDueDate: "2031-06-01", Balance: NewMoney(50_00, USD),
+			Blocked: true, Inactive: true, ProductID: "plastic-usd"},
+	}
+}
+
+func TestFixture_RoundTrip(t *testing.T) {
+	for _, format := range []string{FixtureJSON, FixtureXML, FixtureCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFixture(&buf, testFixture(), format); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFixture(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadFixture() error = %v\n%s", err, buf.String())
+			}
+			want := testFixture()
+			if len(got) != len(want) {
+				t.Fatalf("ReadFixture() = %d cards, want %d", len(got), len(want))
+			}
+			for i := range want {
+				// пустой и nil-список транзакций равнозначны; Available пересчитывает сервис
+				if len(got[i].Transactions) == 0 && len(want[i].Transactions) == 0 {
+					got[i].Transactions, want[i].Transactions = nil, nil
+				}
+				got[i].Available = want[i].Available
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("card %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "seed.csv")
+	var buf bytes.Buffer
+	if err := WriteFixture(&buf, testFixture(), FixtureCSV); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := LoadFixture(path)
+	if err != nil || len(cards) != 2 || len(cards[0].Transactions) != 2 {
+		t.Fatalf("LoadFixture() = %v, %v", cards, err)
+	}
+
+	// сервис принимает карты и продолжает нумерацию транзакций после фикстуры
+	svc := NewService()
+	svc.SetCards(cards)
+	if tr, err := svc.Purchase(context.Background(), 1, Rub(1_00), "5411"); err != nil || tr.ID != 3 {
+		t.Errorf("Purchase() after seed = %+v, %v; want transaction 3", tr, err)
+	}
+
+	if _, err := LoadFixture(filepath.Join(dir, "seed.yaml")); err == nil {
+		t.Error("LoadFixture(missing .yaml) error = nil")
+	}
+	yaml := filepath.Join(dir, "cards.yaml")
+	if err := ioutil.WriteFile(yaml, []byte("cards: []"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixture(yaml); !errors.Is(err, ErrInvalidFixture) {
+		t.Errorf("LoadFixture(.yaml) error = %v, want %v", err, ErrInvalidFixture)
+	}
+}
+
+func TestReadFixture_DefaultStatus(t *testing.T) {
+	header := strings.Join(fixtureCSVHeader, ",") + "\n"
+	tests := []struct {
+		format string
+		data   string
+	}{
+		{format: FixtureCSV, data: header + "1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,false,false,,1,purchase,100,RUB,2020-01-01T00:00:00Z,5411,\n"},
+		{format: FixtureJSON, data: `[{"ID": 1, "UserID": 1, "CardNumber": "1111", "Transactions": [{"id": 1, "transum": 100}]}]`},
+		{format: FixtureXML, data: `<cards><card><id>1</id><user_id>1</user_id><card_number>1111</card_number>` +
+			`<transactions><transaction><id>1</id><transum>100</transum></transaction></transactions></card></cards>`},
+	}
+	for _, tt := range tests {
+		cards, err := ReadFixture(strings.NewReader(tt.data), tt.format)
+		if err != nil {
+			t.Fatalf("%s: ReadFixture() error = %v", tt.format, err)
+		}
+		if st := cards[0].Transactions[0].Status; st != StatusDone {
+			t.Errorf("%s: status = %q, want %q", tt.format, st, StatusDone)
+		}
+	}
+}
+
+func TestReadFixture_Invalid(t *testing.T) {
+	header := strings.Join(fixtureCSVHeader, ",") + "\n"
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   string // часть сообщения об ошибке
+	}{
+		{name: "duplicate id", format: FixtureJSON,
+			data: `[{"ID": 1, "UserID": 1, "CardNumber": "1111"}, {"ID": 1, "UserID": 2, "CardNumber": "2222"}]`,
+			want: "card 1: duplicate id"},
+		{name: "duplicate number", format: FixtureJSON,
+			data: `[{"ID": 1, "UserID": 1, "CardNumber": "1111 2222"}, {"ID": 2, "UserID": 1, "CardNumber": "11112222"}]`,
+			want: "card 2: duplicate card number 11112222 (card 1)"},
+		{name: "hw11 numbers", format: FixtureJSON,
+			data: `[{"ID": 1, "UserID": 1, "CardNumber": "1111 2222 3333 4444"}, {"ID": 2, "UserID": 1, "CardNumber": "1111 2222 3333 4444"}]`,
+			want: "duplicate card number"},
+		{name: "duplicate transaction", format: FixtureJSON,
+			data: `[{"ID": 1, "UserID": 1, "CardNumber": "1", "Transactions": [{"id": 5}]}, {"ID": 2, "UserID": 1, "CardNumber": "2", "Transactions": [{"id": 5}]}]`,
+			want: "card 2: duplicate transaction id 5 (card 1)"},
+		{name: "foreign owner", format: FixtureJSON,
+			data: `[{"ID": 1, "UserID": 1, "CardNumber": "1", "Transactions": [{"id": 5, "ownerid": 2}]}]`,
+			want: "owner 2 is not the card user 1"},
+		{name: "no user", format: FixtureJSON, data: `[{"ID": 1, "CardNumber": "1"}]`, want: "user id must be positive"},
+		{name: "zero id", format: FixtureJSON, data: `[{"UserID": 1, "CardNumber": "1"}]`, want: "card #1: id must be positive"},
+		{name: "malformed json", format: FixtureJSON, data: `[{"ID": "one"}]`, want: "cannot unmarshal"},
+		{name: "malformed xml", format: FixtureXML, data: `<cards><card><id>x</id></card></cards>`, want: "invalid syntax"},
+		{name: "csv header", format: FixtureCSV, data: "id,user\n", want: "csv header must be"},
+		{name: "csv bad date", format: FixtureCSV,
+			data: header + "1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,false,false,,1,purchase,100,RUB,yesterday,5411,done\n",
+			want: "line 2: tran_date"},
+		{name: "csv card changed", format: FixtureCSV,
+			data: header + "1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,false,false,,1,purchase,100,RUB,2020-01-01T00:00:00Z,5411,done\n" +
+				"1,1,Visa,Citi,2222,2030-01-01,0,RUB,false,false,false,,2,purchase,100,RUB,2020-01-01T00:00:00Z,5411,done\n",
+			want: "line 3: card 1 columns differ"},
+		{name: "csv bad flag", format: FixtureCSV,
+			data: header + "1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,no,false,,,,,,,,\n",
+			want: "line 2: blocked"},
+		{name: "csv card split", format: FixtureCSV,
+			data: header + "1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,false,false,,,,,,,,\n" +
+				"2,1,Visa,Citi,2222,2030-01-01,0,RUB,false,false,false,,,,,,,,\n" +
+				"1,1,Visa,Citi,1111,2030-01-01,0,RUB,false,false,false,,,,,,,,\n",
+			want: "card 1: duplicate id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFixture(strings.NewReader(tt.data), tt.format)
+			if !errors.Is(err, ErrInvalidFixture) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadFixture() error = %v, want %v containing %q", err, ErrInvalidFixture, tt.want)
+			}
+		})
+	}
+}
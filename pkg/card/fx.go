@@ -0,0 +1,256 @@
+package card
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrRateNotFound   = errors.New("exchange rate not found")
+	ErrInvalidRate    = errors.New("exchange rate is not valid")
+	ErrNoRateProvider = errors.New("exchange rate provider is not set")
+)
+
+// RateScale - курс хранится целым числом с 6 знаками после запятой
+const RateScale = 1_000_000
+
+const rateDigits = 6
+
+// Rate - курс обмена: сколько единиц валюты To дают за одну единицу валюты From
+type Rate int64
+
+// ParseRate - "92.5" -> 92_500_000
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.SplitN(s, ".", 2)
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+	if parts[0] == "" || strings.HasPrefix(s, "-") || len(frac) > rateDigits {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	frac += strings.Repeat("0", rateDigits-len(frac))
+	value, err := strconv.ParseInt(parts[0]+frac, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	return Rate(value), nil
+}
+
+// String - "92.500000"
+func (r Rate) String() string {
+	return fmt.Sprintf("%d.%0*d", int64(r)/RateScale, rateDigits, int64(r)%RateScale)
+}
+
+// MarshalText - курс в JSON/XML пишется строкой, чтобы не терять точность
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText - обратная операция к MarshalText
+func (r *Rate) UnmarshalText(data []byte) error {
+	parsed, err := ParseRate(string(data))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Inverse - обратный курс (To -> From)
+func (r Rate) Inverse() Rate {
+	num := big.NewInt(RateScale * RateScale)
+	return Rate(divRound(num, big.NewInt(int64(r))).Int64())
+}
+
+// divRound - деление с округлением half away from zero
+func divRound(num *big.Int, den *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	rem.Abs(rem).Mul(rem, big.NewInt(2))
+	if rem.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Convert - пересчёт суммы в валюту to по курсу rate (с учётом разрядности валют)
+func Convert(m Money, to Currency, rate Rate) (Money, error) {
+	if !to.Valid() {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, to)
+	}
+	if rate <= 0 {
+		return Money{}, ErrInvalidRate
+	}
+	num := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(rate)))
+	den := big.NewInt(RateScale)
+	if diff := minorUnits[to] - minorUnits[m.Currency]; diff > 0 {
+		num.Mul(num, pow10(diff))
+	} else if diff < 0 {
+		den.Mul(den, pow10(-diff))
+	}
+	amount := divRound(num, den)
+	if !amount.IsInt64() {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: amount.Int64(), Currency: to}, nil
+}
+
+// RateProvider - источник курсов валют
+type RateProvider interface {
+	Rate(from Currency, to Currency) (Rate, error)
+}
+
+// FxInfo - сведения о конвертации, сохраняемые в транзакции
+type FxInfo struct {
+	Original Money    `json:"original" xml:"original"`
+	From     Currency `json:"from" xml:"from"`
+	To       Currency `json:"to" xml:"to"`
+	Rate     Rate     `json:"rate" xml:"rate"`
+}
+
+// ConvertWith - пересчёт через провайдера; для одной валюты возвращает сумму как есть и nil вместо FxInfo
+func ConvertWith(p RateProvider, m Money, to Currency) (Money, *FxInfo, error) {
+	if m.Currency == to {
+		return m, nil, nil
+	}
+	if p == nil {
+		return Money{}, nil, ErrNoRateProvider
+	}
+	rate, err := p.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, nil, err
+	}
+	converted, err := Convert(m, to, rate)
+	if err != nil {
+		return Money{}, nil, err
+	}
+	return converted, &FxInfo{Original: m, From: m.Currency, To: to, Rate: rate}, nil
+}
+
+// scaleFx - FxInfo для части part сконвертированной суммы whole: исходная сумма уменьшается пропорционально
+func scaleFx(fx *FxInfo, part Money, whole Money) (*FxInfo, error) {
+	if fx == nil || part == whole {
+		return fx, nil
+	}
+	if whole.Amount == 0 {
+		return nil, ErrInvalidAmount
+	}
+	num := new(big.Int).Mul(big.NewInt(fx.Original.Amount), big.NewInt(part.Amount))
+	amount := divRound(num, big.NewInt(whole.Amount))
+	if !amount.IsInt64() {
+		return nil, ErrAmountOverflow
+	}
+	scaled := *fx
+	scaled.Original = NewMoney(amount.Int64(), fx.Original.Currency)
+	return &scaled, nil
+}
+
+type currencyPair struct {
+	From Currency
+	To   Currency
+}
+
+// StaticRateProvider - фиксированные курсы (для работы без внешних сервисов)
+type StaticRateProvider struct {
+	mu    sync.RWMutex
+	rates map[currencyPair]Rate
+}
+
+func NewStaticRateProvider() *StaticRateProvider {
+	return &StaticRateProvider{rates: make(map[currencyPair]Rate)}
+}
+
+// SetRate - задать курс from -> to; обратный курс вычисляется автоматически, если не задан явно
+func (p *StaticRateProvider) SetRate(from Currency, to Currency, rate Rate) error {
+	if !from.Valid() || !to.Valid() {
+		return fmt.Errorf("%w: %s/%s", ErrInvalidCurrency, from, to)
+	}
+	if rate <= 0 {
+		return ErrInvalidRate
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rates[currencyPair{From: from, To: to}] = rate
+	return nil
+}
+
+func (p *StaticRateProvider) Rate(from Currency, to Currency) (Rate, error) {
+	if from == to {
+		return RateScale, nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if rate, ok := p.direct(from, to); ok {
+		return rate, nil
+	}
+	// кросс-курс через рубль
+	if from != DefaultCurrency && to != DefaultCurrency {
+		fromBase, okFrom := p.direct(from, DefaultCurrency)
+		toBase, okTo := p.direct(to, DefaultCurrency)
+		if okFrom && okTo {
+			num := new(big.Int).Mul(big.NewInt(int64(fromBase)), big.NewInt(RateScale))
+			return Rate(divRound(num, big.NewInt(int64(toBase))).Int64()), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+}
+
+// direct - курс, заданный явно в любом направлении (без кросс-курсов)
+func (p *StaticRateProvider) direct(from Currency, to Currency) (Rate, bool) {
+	if rate, ok := p.rates[currencyPair{From: from, To: to}]; ok {
+		return rate, true
+	}
+	if rate, ok := p.rates[currencyPair{From: to, To: from}]; ok {
+		return rate.Inverse(), true
+	}
+	return 0, false
+}
+
+type rateRecord struct {
+	From Currency `json:"from"`
+	To   Currency `json:"to"`
+	Rate Rate     `json:"rate"`
+}
+
+// LoadRatesFromJSON - курсы из файла вида [{"from":"USD","to":"RUB","rate":"92.5"}]
+func LoadRatesFromJSON(importPath string) (*StaticRateProvider, error) {
+	content, err := ioutil.ReadFile(importPath)
+	if err != nil {
+		return nil, err
+	}
+	var records []rateRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		return nil, err
+	}
+	p := NewStaticRateProvider()
+	for _, v := range records {
+		if err := p.SetRate(v.From, v.To, v.Rate); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// DefaultRates - встроенные курсы к рублю на случай, когда файл курсов не задан
+func DefaultRates() *StaticRateProvider {
+	p := NewStaticRateProvider()
+	_ = p.SetRate(USD, RUB, 92_500000)
+	_ = p.SetRate(EUR, RUB, 100_250000)
+	_ = p.SetRate(CNY, RUB, 12_750000)
+	return p
+}
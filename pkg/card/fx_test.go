@@ -0,0 +1,66 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		to   Currency
+		rate Rate
+		want Money
+	}{
+		{name: "usd to rub", m: NewMoney(10_00, USD), to: RUB, rate: 92_500000, want: Rub(925_00)},
+		{name: "rounding half up", m: Rub(1), to: USD, rate: 500000, want: NewMoney(1, USD)},
+		{name: "negative rounding", m: Rub(-1), to: USD, rate: 500000, want: NewMoney(-1, USD)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.m, tt.to, tt.rate)
+			if err != nil || got != tt.want {
+				t.Errorf("Convert() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	p := DefaultRates()
+
+	rate, err := p.Rate(RUB, USD)
+	if err != nil || rate != 10811 {
+		t.Errorf("Rate(RUB, USD) = %v, %v, want inverse of 92.5", rate, err)
+	}
+	rate, err = p.Rate(USD, EUR)
+	if err != nil || rate != 922693 {
+		t.Errorf("Rate(USD, EUR) = %v, %v, want cross rate via RUB", rate, err)
+	}
+	if _, err := NewStaticRateProvider().Rate(USD, EUR); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("Rate() error = %v, want %v", err, ErrRateNotFound)
+	}
+}
+
+func TestService_PurchaseFx(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 2, Balance: Rub(1000_00)}})
+	svc.SetRateProvider(DefaultRates())
+
+	tr, err := svc.Purchase(context.Background(), 1, NewMoney(5_00, USD), "5411")
+	if err != nil {
+		t.Fatalf("Purchase() error = %v", err)
+	}
+	if tr.TranSum != Rub(462_50) {
+		t.Errorf("Purchase() TranSum = %v, want %v", tr.TranSum, Rub(462_50))
+	}
+	if tr.Fx == nil || tr.Fx.Rate != 92_500000 || tr.Fx.Original != NewMoney(5_00, USD) {
+		t.Errorf("Purchase() Fx = %+v", tr.Fx)
+	}
+	c, _ := svc.SearchByID(1)
+	if c.Balance != Rub(537_50) {
+		t.Errorf("balance = %v, want %v", c.Balance, Rub(537_50))
+	}
+}
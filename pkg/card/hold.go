@@ -0,0 +1,271 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds hold amount")
+)
+
+// DefaultHoldTTL - через сколько неподтверждённый холд снимается автоматически
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// ClosedHoldRetention - сколько закрытый холд ещё виден в GetHold/Capture/Void; потом он удаляется из памяти
+const ClosedHoldRetention = 24 * time.Hour
+
+// HoldStatus - состояние холда (авторизации)
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldVoided   HoldStatus = "voided"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold - авторизация: сумма заблокирована на карте (уменьшает Available), но ещё не списана (Balance прежний)
+type Hold struct {
+	ID            int64      `json:"id"`
+	CardID        int64      `json:"card_id"`
+	Amount        Money      `json:"amount"`   // в валюте карты
+	Captured      Money      `json:"captured"` // фактически списано при подтверждении
+	Status        HoldStatus `json:"status"`
+	CreatedAt     int64      `json:"created_at"` // unix timestamp
+	ExpiresAt     int64      `json:"expires_at"` // unix timestamp
+	TransactionID int64      `json:"transaction_id"`
+
+	timer    *time.Timer
+	closedAt time.Time // когда холд перестал быть активным
+}
+
+// heldAmount - сумма активных холдов по карте (вызывать под s.mu)
+func (s *Service) heldAmount(c *Card) (Money, error) {
+	total := NewMoney(0, c.Balance.Currency)
+	for _, h := range s.holds {
+		if h.CardID != c.ID || h.Status != HoldActive {
+			continue
+		}
+		sum, err := total.Add(h.Amount)
+		if err != nil {
+			return Money{}, err
+		}
+		total = sum
+	}
+	return total, nil
+}
+
+// available - доступный остаток: Balance минус активные холды (вызывать под s.mu)
+func (s *Service) available(c *Card) (Money, error) {
+	held, err := s.heldAmount(c)
+	if err != nil {
+		return Money{}, err
+	}
+	return c.Balance.Sub(held)
+}
+
+// refreshAvailable - пересчитать Card.Available после изменения баланса или холдов (вызывать под s.mu)
+func (s *Service) refreshAvailable(c *Card) {
+	available, err := s.available(c)
+	if err != nil {
+		available = c.Balance
+	}
+	c.Available = available
+}
+
+// checkAvailable - хватает ли доступного остатка на amount (вызывать под s.mu)
+func (s *Service) checkAvailable(c *Card, amount Money) error {
+	available, err := s.available(c)
+	if err != nil {
+		return err
+	}
+	cmp, err := available.Cmp(amount)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		return ErrCardFromBalanceLessThenAmount
+	}
+	return nil
+}
+
+// Authorize - поставить холд на сумму покупки; создаётся транзакция в статусе pending
+func (s *Service) Authorize(ctx context.Context, cardID int64, amount Money, mcc string, ttl time.Duration) (hold *Hold, err error) {
+	defer func() { s.logOp(ctx, "authorize", err, "card_id", cardID, "amount", amount, "mcc", mcc) }()
+	card, ok := s.SearchByID(cardID)
+	if !ok {
+		return nil, ErrCardNotFound
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return nil, ErrInvalidAmount
+	}
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	var screened *screening
+	defer func() { s.audit(screened) }() // после s.mu.Unlock: отложенные вызовы идут в обратном порядке
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held, fx, err := ConvertWith(s.rates, amount, card.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := card.usable(); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(card, held, mcc, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.checkAvailable(card, held); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tr := &Transaction{ID: s.nextTransactionID(), TranType: TranTypePurchase, TranSum: held, TranDate: now.Unix(),
+		MccCode: mcc, Status: StatusPending, OwnerID: card.UserID, Fx: fx}
+	sc, err := s.screen(card, tr)
+	screened = sc // и отказ, и принятый холд: после проверки ошибок уже нет
+	if err != nil {
+		return nil, err
+	}
+	AddTransaction(card, tr)
+
+	s.pruneHolds(now)
+	s.holdSeq++
+	h := &Hold{ID: s.holdSeq, CardID: card.ID, Amount: held, Captured: NewMoney(0, held.Currency), Status: HoldActive,
+		CreatedAt: now.Unix(), ExpiresAt: now.Add(ttl).Unix(), TransactionID: tr.ID}
+	id := h.ID
+	h.timer = time.AfterFunc(ttl, func() { s.expireHold(id) })
+	s.holds[h.ID] = h
+	s.refreshAvailable(card)
+	s.publishTransaction(EventTransactionPosted, card, tr)
+	cp := *h
+	return &cp, nil
+}
+
+// activeHold - активный холд, его карта и транзакция (вызывать под s.mu)
+func (s *Service) activeHold(holdID int64) (*Hold, *Card, *Transaction, error) {
+	h, ok := s.holds[holdID]
+	if !ok {
+		return nil, nil, nil, ErrHoldNotFound
+	}
+	if h.Status != HoldActive {
+		return nil, nil, nil, ErrHoldNotActive
+	}
+	c, tr, ok := s.findTransaction(h.TransactionID)
+	if !ok {
+		return nil, nil, nil, ErrTransactionNotFound
+	}
+	return h, c, tr, nil
+}
+
+// Capture - подтвердить холд на всю сумму или её часть (нулевое Money{} - вся сумма); остаток холда освобождается
+func (s *Service) Capture(ctx context.Context, holdID int64, amount Money) (tr *Transaction, err error) {
+	defer func() { s.logOp(ctx, "capture", err, "hold_id", holdID, "amount", amount) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, c, tr, err := s.activeHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	// карту могли заблокировать после авторизации: холд остаётся активным, его можно отменить
+	if err := c.usable(); err != nil {
+		return nil, err
+	}
+	if amount.untyped() {
+		amount = h.Amount
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return nil, ErrInvalidAmount
+	}
+	cmp, err := amount.Cmp(h.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if cmp > 0 {
+		return nil, ErrCaptureExceedsHold
+	}
+	balance, err := c.Balance.Sub(amount)
+	if err != nil {
+		return nil, err
+	}
+	fx, err := scaleFx(tr.Fx, amount, h.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ledger.Move(time.Now().Unix(), "capture", CardAccount(c.ID), MerchantsAccount(amount.Currency), amount, tr.ID, 0); err != nil {
+		return nil, err
+	}
+	c.Balance = balance
+	tr.TranSum = amount
+	tr.Fx = fx
+	tr.Status = StatusDone
+	h.Captured = amount
+	s.closeHold(h, HoldCaptured, c)
+	s.publishTransaction(EventTransactionUpdated, c, tr)
+	return tr, nil
+}
+
+// Void - отменить холд целиком; транзакция получает статус reversed
+func (s *Service) Void(ctx context.Context, holdID int64) (hold *Hold, err error) {
+	defer func() { s.logOp(ctx, "void", err, "hold_id", holdID) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, c, tr, err := s.activeHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	tr.Status = StatusReversed
+	s.closeHold(h, HoldVoided, c)
+	s.publishTransaction(EventTransactionUpdated, c, tr)
+	cp := *h
+	return &cp, nil
+}
+
+// expireHold - холд не подтверждён вовремя; транзакция получает статус declined
+func (s *Service) expireHold(holdID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, c, tr, err := s.activeHold(holdID)
+	if err != nil {
+		return
+	}
+	tr.Status = StatusDeclined
+	s.closeHold(h, HoldExpired, c)
+	s.publishTransaction(EventTransactionUpdated, c, tr)
+}
+
+// closeHold - вызывать под s.mu
+func (s *Service) closeHold(h *Hold, status HoldStatus, c *Card) {
+	h.Status = status
+	h.closedAt = time.Now()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	s.refreshAvailable(c)
+}
+
+// pruneHolds - удалить холды, закрытые раньше now минус ClosedHoldRetention (вызывать под s.mu)
+func (s *Service) pruneHolds(now time.Time) {
+	for id, h := range s.holds {
+		if h.Status != HoldActive && now.Sub(h.closedAt) > ClosedHoldRetention {
+			delete(s.holds, id)
+		}
+	}
+}
+
+// GetHold - копия холда по ID
+func (s *Service) GetHold(holdID int64) (Hold, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.holds[holdID]
+	if !ok {
+		return Hold{}, false
+	}
+	return *h, true
+}
@@ -0,0 +1,166 @@
+package card
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestService_Holds(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+	c, _ := svc.SearchByID(1)
+	if c.Available != Rub(100_00) {
+		t.Fatalf("Available = %v, want %v", c.Available, Rub(100_00))
+	}
+
+	h, err := svc.Authorize(context.Background(), 1, Rub(60_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Available != Rub(40_00) || c.Balance != Rub(100_00) {
+		t.Errorf("after Authorize() Available = %v, Balance = %v", c.Available, c.Balance)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, Rub(50_00), "5411"); err != ErrCardFromBalanceLessThenAmount {
+		t.Errorf("Purchase() over available error = %v, want %v", err, ErrCardFromBalanceLessThenAmount)
+	}
+	if _, err := svc.Capture(context.Background(), h.ID, Rub(60_01)); err != ErrCaptureExceedsHold {
+		t.Errorf("Capture() error = %v, want %v", err, ErrCaptureExceedsHold)
+	}
+
+	tr, err := svc.Capture(context.Background(), h.ID, Rub(55_00))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Status != StatusDone || tr.TranSum != Rub(55_00) {
+		t.Errorf("Capture() = %+v", tr)
+	}
+	if c.Available != Rub(45_00) || c.Balance != Rub(45_00) {
+		t.Errorf("after Capture() Available = %v, Balance = %v", c.Available, c.Balance)
+	}
+	if _, err := svc.Void(context.Background(), h.ID); err != ErrHoldNotActive {
+		t.Errorf("Void() captured hold error = %v, want %v", err, ErrHoldNotActive)
+	}
+
+	voided, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Void(context.Background(), voided.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c.Available != Rub(45_00) {
+		t.Errorf("after Void() Available = %v, want %v", c.Available, Rub(45_00))
+	}
+
+	expiring, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := svc.GetHold(expiring.ID)
+		if got.Status == HoldExpired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("hold status = %v, want %v", got.Status, HoldExpired)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	svc.mu.RLock()
+	available := c.Available
+	svc.mu.RUnlock()
+	if available != Rub(45_00) {
+		t.Errorf("after expiry Available = %v, want %v", available, Rub(45_00))
+	}
+	if report := svc.Reconcile(); !report.OK {
+		t.Errorf("Reconcile() = %+v", report)
+	}
+}
+
+func TestService_CaptureFx(t *testing.T) {
+	rates := NewStaticRateProvider()
+	if err := rates.SetRate(USD, RUB, 90*RateScale); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		capture      Money
+		wantOriginal Money
+	}{
+		{name: "full", capture: Rub(900_00), wantOriginal: NewMoney(10_00, USD)},
+		{name: "part", capture: Rub(450_00), wantOriginal: NewMoney(5_00, USD)},
+		{name: "rounded", capture: Rub(1_00), wantOriginal: NewMoney(1, USD)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService()
+			svc.SetRateProvider(rates)
+			svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(1000_00)}})
+			h, err := svc.Authorize(context.Background(), 1, NewMoney(10_00, USD), "5411", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, err := svc.Capture(context.Background(), h.ID, tt.capture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.Fx == nil || tr.Fx.Original != tt.wantOriginal || tr.Fx.Rate != 90*RateScale {
+				t.Errorf("Capture() fx = %+v, want original %v", tr.Fx, tt.wantOriginal)
+			}
+		})
+	}
+}
+
+func TestService_CaptureBlockedCard(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+	h, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BlockCard(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Capture(context.Background(), h.ID, Money{}); err != ErrCardBlocked {
+		t.Errorf("Capture() error = %v, want %v", err, ErrCardBlocked)
+	}
+	if got, _ := svc.GetHold(h.ID); got.Status != HoldActive {
+		t.Errorf("hold status = %v, want %v", got.Status, HoldActive)
+	}
+	if _, err := svc.Void(context.Background(), h.ID); err != nil {
+		t.Errorf("Void() error = %v", err)
+	}
+}
+
+func TestService_PruneHolds(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+	closed, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Void(context.Background(), closed.ID); err != nil {
+		t.Fatal(err)
+	}
+	active, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.mu.Lock()
+	svc.pruneHolds(time.Now())
+	kept := len(svc.holds)
+	svc.pruneHolds(time.Now().Add(ClosedHoldRetention + time.Minute))
+	svc.mu.Unlock()
+	if kept != 2 {
+		t.Errorf("holds within retention = %d, want 2", kept)
+	}
+	if _, ok := svc.GetHold(closed.ID); ok {
+		t.Errorf("closed hold %d was not pruned", closed.ID)
+	}
+	if _, ok := svc.GetHold(active.ID); !ok {
+		t.Errorf("active hold %d was pruned", active.ID)
+	}
+}
@@ -0,0 +1,149 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidIssueSettings = errors.New("card issue settings are not valid")
+	ErrTooManyCards         = errors.New("User has reached the maximum number of cards")
+)
+
+// DefaultMaxCardsPerUser - сколько карт (любых, включая заблокированные) может быть у одного пользователя
+const DefaultMaxCardsPerUser = 10
+
+// DueDateLayout - формат CardDueDate
+const DueDateLayout = "2006-01-02"
+
+// IssueSettings - параметры выпуска новых карт
+type IssueSettings struct {
+	BankName    string
+	DueDate     string   // срок действия новых карт, DueDateLayout
+	CardTypes   []string // допустимые типы: подмножество CardTypes
+	CardIssuers []string // допустимые платёжные системы
+	// MaxCardsPerUser - предел числа карт пользователя для IssueCard, IssueProduct и OrderCard; 0 - без предела
+	MaxCardsPerUser int
+}
+
+// DefaultIssueSettings - значения, которые раньше были зашиты в AddParamCardToCardslice
+func DefaultIssueSettings() IssueSettings {
+	return IssueSettings{
+		BankName:        "Tinkoff",
+		DueDate:         "2030-01-01",
+		CardTypes:       append([]string(nil), CardTypes...),
+		CardIssuers:     append([]string(nil), CardIssuer...),
+		MaxCardsPerUser: DefaultMaxCardsPerUser,
+	}
+}
+
+// Validate - проверка настроек
+func (st IssueSettings) Validate() error {
+	if st.BankName == "" {
+		return fmt.Errorf("%w: bank name is empty", ErrInvalidIssueSettings)
+	}
+	if _, err := time.Parse(DueDateLayout, st.DueDate); err != nil {
+		return fmt.Errorf("%w: due date %q: %v", ErrInvalidIssueSettings, st.DueDate, err)
+	}
+	if len(st.CardTypes) == 0 || len(st.CardIssuers) == 0 {
+		return fmt.Errorf("%w: card types and issuers must not be empty", ErrInvalidIssueSettings)
+	}
+	for _, ct := range st.CardTypes {
+		if _, ok := Find(CardTypes, ct); !ok {
+			return fmt.Errorf("%w: card type %q (known: %v)", ErrInvalidIssueSettings, ct, CardTypes)
+		}
+	}
+	for _, ci := range st.CardIssuers {
+		if ci == "" {
+			return fmt.Errorf("%w: empty card issuer", ErrInvalidIssueSettings)
+		}
+		// без BIN карту вне каталога не выпустить: ошибка при старте, а не при первом выпуске
+		if _, ok := issuerBINs[ci]; !ok {
+			return fmt.Errorf("%w: card issuer %q has no BIN range (known: %v)", ErrInvalidIssueSettings, ci, knownIssuers())
+		}
+	}
+	if st.MaxCardsPerUser < 0 {
+		return fmt.Errorf("%w: max cards per user %d is negative", ErrInvalidIssueSettings, st.MaxCardsPerUser)
+	}
+	return nil
+}
+
+// check - тип и платёжная система разрешены настройками
+func (st IssueSettings) check(cardtype string, cardissuer string) error {
+	if _, ok := Find(st.CardTypes, cardtype); !ok {
+		return ErrInvaildCardType
+	}
+	if _, ok := Find(st.CardIssuers, cardissuer); !ok {
+		return ErrInvaildCardIssuer
+	}
+	return nil
+}
+
+// checkCardCap - у пользователя ещё нет MaxCardsPerUser карт
+func (st IssueSettings) checkCardCap(cards []*Card, userID int64) error {
+	if st.MaxCardsPerUser == 0 {
+		return nil
+	}
+	n := 0
+	for _, c := range cards {
+		if c.UserID == userID {
+			n++
+		}
+	}
+	if n >= st.MaxCardsPerUser {
+		return fmt.Errorf("%w: user %d has %d cards (max %d)", ErrTooManyCards, userID, n, st.MaxCardsPerUser)
+	}
+	return nil
+}
+
+// issuerBINs - первые цифры номеров платёжных систем для BIN каталога по умолчанию и карт вне каталога
+var issuerBINs = map[string]string{"Master": "5100", "Visa": "4000", "UnionPay": "6200", "Mir": "2200"}
+
+// knownIssuers - платёжные системы с BIN по алфавиту
+func knownIssuers() []string {
+	issuers := make([]string, 0, len(issuerBINs))
+	for issuer := range issuerBINs {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+	return issuers
+}
+
+// newCard - новая карта по настройкам выпуска, когда в каталоге нет продукта; BIN - платёжная система,
+// индекс типа и "90" (в DefaultCatalog заняты 00-49); без известного BIN - ErrProductNotFound
+func (st IssueSettings) newCard(cardtype string, cardissuer string, userID int64, cardID int64, currency Currency) (*Card, error) {
+	prefix, ok := issuerBINs[cardissuer]
+	typeIndex, _ := Find(CardTypes, cardtype)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s %s, and %s has no BIN", ErrProductNotFound, cardtype, cardissuer, currency, cardissuer)
+	}
+	number, err := CardNumber(prefix+strconv.Itoa(typeIndex)+"90", cardID)
+	if err != nil {
+		return nil, err
+	}
+	return &Card{
+		ID: cardID, Type: cardissuer, BankName: st.BankName, CardNumber: number,
+		Balance: NewMoney(0, currency), CardDueDate: st.DueDate, UserID: userID, IsVirtual: cardtype == "virtual",
+	}, nil
+}
+
+// SetIssueSettings - параметры выпуска карт для IssueCard
+func (s *Service) SetIssueSettings(settings IssueSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issue = settings
+	return nil
+}
+
+// IssueSettings - текущие параметры выпуска карт
+func (s *Service) IssueSettings() IssueSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.issue
+}
@@ -0,0 +1,126 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestService_IssueSettings(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(0)}})
+	if err := svc.SetIssueSettings(IssueSettings{BankName: "Alfa", DueDate: "2031-12-31", CardTypes: []string{"virtual"}, CardIssuers: []string{"Mir"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cardtype string
+		issuer   string
+		err      error
+	}{
+		{name: "allowed", cardtype: "virtual", issuer: "Mir"},
+		{name: "type not allowed", cardtype: "plastic", issuer: "Mir", err: ErrInvaildCardType},
+		{name: "issuer not allowed", cardtype: "virtual", issuer: "Visa", err: ErrInvaildCardIssuer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := svc.IssueCard(context.Background(), tt.cardtype, tt.issuer, 1, RUB)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("IssueCard() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && (c.BankName != "Alfa" || c.CardDueDate != "2031-12-31" || c.Type != "Mir" || !c.IsVirtual ||
+				c.CardNumber != "2200 1900 0000 0029") {
+				t.Errorf("IssueCard() = %+v", c)
+			}
+		})
+	}
+
+	invalid := []IssueSettings{
+		{DueDate: "2030-01-01", CardTypes: CardTypes, CardIssuers: CardIssuer},
+		{BankName: "Alfa", DueDate: "2030-13-01", CardTypes: CardTypes, CardIssuers: CardIssuer},
+		{BankName: "Alfa", DueDate: "2030-01-01", CardTypes: []string{"metal"}, CardIssuers: CardIssuer},
+		{BankName: "Alfa", DueDate: "2030-01-01", CardTypes: CardTypes},
+		{BankName: "Alfa", DueDate: "2030-01-01", CardTypes: CardTypes, CardIssuers: CardIssuer, MaxCardsPerUser: -1},
+		// платёжная система без известного BIN - номер не из чего составить
+		{BankName: "Alfa", DueDate: "2030-01-01", CardTypes: CardTypes, CardIssuers: []string{"Visa", "Amex"}},
+	}
+	for _, st := range invalid {
+		if err := svc.SetIssueSettings(st); !errors.Is(err, ErrInvalidIssueSettings) {
+			t.Errorf("SetIssueSettings(%+v) error = %v, want %v", st, err, ErrInvalidIssueSettings)
+		}
+	}
+}
+
+func TestService_MaxCardsPerUser(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(0)}, {ID: 2, UserID: 2, Balance: Rub(0)}})
+	settings := DefaultIssueSettings()
+	settings.MaxCardsPerUser = 3
+	if err := svc.SetIssueSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.IssueCard(context.Background(), "virtual", "Visa", 1, RUB); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.OrderCard(context.Background(), "plastic-master", 1, testAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		err  error
+	}{
+		{name: "issue card", err: ErrTooManyCards, call: func() error {
+			_, err := svc.IssueCard(context.Background(), "virtual", "UnionPay", 1, RUB)
+			return err
+		}},
+		{name: "issue product", err: ErrTooManyCards, call: func() error {
+			_, err := svc.IssueProduct(context.Background(), "virtual-visa", 1)
+			return err
+		}},
+		{name: "order card", err: ErrTooManyCards, call: func() error {
+			_, err := svc.OrderCard(context.Background(), "plastic-visa", 1, testAddress)
+			return err
+		}},
+		{name: "other user", call: func() error {
+			_, err := svc.IssueProduct(context.Background(), "virtual-visa", 2)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestService_AllowAnyUser(t *testing.T) {
+	svc := NewService()
+	if _, err := svc.IssueCard(context.Background(), "virtual", "Visa", 7, RUB); !errors.Is(err, ErrNoCardWithUserID) {
+		t.Fatalf("IssueCard() to unknown user error = %v, want %v", err, ErrNoCardWithUserID)
+	}
+
+	// без начальных карт пользователей знать неоткуда: карты выпускаются любому
+	svc.AllowAnyUser(true)
+	if cards, err := svc.UserCards(7); err != nil || len(cards) != 0 {
+		t.Errorf("UserCards(7) before issue = %v, %v", cards, err)
+	}
+	c, err := svc.IssueCard(context.Background(), "virtual", "Visa", 7, RUB)
+	if err != nil || c.ID != 1 || c.UserID != 7 {
+		t.Fatalf("IssueCard() = %+v, %v", c, err)
+	}
+	if _, err := svc.IssueProduct(context.Background(), "virtual-master", 8); err != nil {
+		t.Errorf("IssueProduct() error = %v", err)
+	}
+	if cards, err := svc.UserCards(7); err != nil || len(cards) != 1 {
+		t.Errorf("UserCards(7) = %v, %v", cards, err)
+	}
+	if err := svc.CheckUser(0); !errors.Is(err, ErrNoCardWithUserID) {
+		t.Errorf("CheckUser(0) error = %v, want %v", err, ErrNoCardWithUserID)
+	}
+}
@@ -0,0 +1,268 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrUnbalancedPosting = errors.New("posting is not balanced")
+	ErrSameAccount       = errors.New("posting debit and credit accounts are the same")
+)
+
+// Account - счёт в книге проводок ("card:1", "bank:equity:RUB", ...)
+type Account string
+
+// EquityAccount - счёт начальных остатков (у каждого счёта одна валюта)
+func EquityAccount(currency Currency) Account {
+	return Account("bank:equity:" + string(currency))
+}
+
+// MerchantsAccount - счёт расчётов с торговыми точками
+func MerchantsAccount(currency Currency) Account {
+	return Account("bank:merchants:" + string(currency))
+}
+
+// CardAccount - счёт карты
+func CardAccount(cardID int64) Account {
+	return Account("card:" + strconv.FormatInt(cardID, 10))
+}
+
+// FxAccount - транзитный счёт конвертации в валюте currency
+func FxAccount(currency Currency) Account {
+	return Account("fx:" + string(currency))
+}
+
+// Entry - одна сторона проводки; Amount > 0 - поступление на счёт, < 0 - списание
+type Entry struct {
+	Account       Account `json:"account"`
+	Amount        Money   `json:"amount"`
+	TransactionID int64   `json:"transaction_id,omitempty"` // 0 - проводка без транзакции (например, начальный остаток)
+}
+
+// Posting - проводка: пара записей с нулевой суммой
+type Posting struct {
+	ID      int64    `json:"id"`
+	Date    int64    `json:"date"` // unix timestamp
+	Memo    string   `json:"memo"`
+	Entries [2]Entry `json:"entries"`
+}
+
+// Ledger - книга проводок (double-entry)
+type Ledger struct {
+	mu       sync.RWMutex
+	postings []*Posting
+	balances map[Account]Money
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{balances: make(map[Account]Money)}
+}
+
+// Post - перенос amount со счёта from на счёт to
+func (l *Ledger) Post(date int64, memo string, from Entry, to Entry) (*Posting, error) {
+	postings, err := l.post(date, memo, [][2]Entry{{from, to}})
+	if err != nil {
+		return nil, err
+	}
+	return postings[0], nil
+}
+
+// Move - проводка на сумму amount (amount > 0) со счёта from на счёт to
+func (l *Ledger) Move(date int64, memo string, from Account, to Account, amount Money, fromTxID int64, toTxID int64) (*Posting, error) {
+	postings, err := l.MoveAll(date, memo, Leg{From: from, To: to, Amount: amount, FromTxID: fromTxID, ToTxID: toTxID})
+	if err != nil {
+		return nil, err
+	}
+	return postings[0], nil
+}
+
+// Leg - одна проводка для MoveAll, поля - как у аргументов Move
+type Leg struct {
+	From     Account
+	To       Account
+	Amount   Money
+	FromTxID int64
+	ToTxID   int64
+}
+
+// MoveAll - несколько проводок атомарно: если хоть одна не проходит, книга не меняется
+// (например, перевод с конвертацией - списание на счёт конвертации и зачисление с него)
+func (l *Ledger) MoveAll(date int64, memo string, legs ...Leg) ([]*Posting, error) {
+	pairs := make([][2]Entry, 0, len(legs))
+	for _, leg := range legs {
+		neg, err := leg.Amount.Neg()
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]Entry{
+			{Account: leg.From, Amount: neg, TransactionID: leg.FromTxID},
+			{Account: leg.To, Amount: leg.Amount, TransactionID: leg.ToTxID},
+		})
+	}
+	return l.post(date, memo, pairs)
+}
+
+// post - проводки по парам записей: сначала считаются все новые остатки, потом они применяются разом
+func (l *Ledger) post(date int64, memo string, pairs [][2]Entry) ([]*Posting, error) {
+	for _, p := range pairs {
+		from, to := p[0], p[1]
+		if from.Account == to.Account {
+			return nil, ErrSameAccount
+		}
+		sum, err := from.Amount.Add(to.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnbalancedPosting, err)
+		}
+		if !sum.IsZero() {
+			return nil, ErrUnbalancedPosting
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	balances := make(map[Account]Money)
+	for _, p := range pairs {
+		for _, e := range p {
+			balance, ok := balances[e.Account]
+			if !ok {
+				balance = l.balances[e.Account]
+			}
+			updated, err := balance.Add(e.Amount)
+			if err != nil {
+				return nil, err
+			}
+			balances[e.Account] = updated
+		}
+	}
+	postings := make([]*Posting, 0, len(pairs))
+	for _, p := range pairs {
+		posting := &Posting{ID: int64(len(l.postings)) + 1, Date: date, Memo: memo, Entries: p}
+		l.postings = append(l.postings, posting)
+		postings = append(postings, posting)
+	}
+	for acc, balance := range balances {
+		l.balances[acc] = balance
+	}
+	return postings, nil
+}
+
+// Open - завести счёт с нулевым остатком в валюте currency (если его ещё нет)
+func (l *Ledger) Open(acc Account, currency Currency) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.balances[acc]; !ok {
+		l.balances[acc] = NewMoney(0, currency)
+	}
+}
+
+// HasAccount - счёт заведён
+func (l *Ledger) HasAccount(acc Account) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.balances[acc]
+	return ok
+}
+
+// Balance - остаток по счёту
+func (l *Ledger) Balance(acc Account) Money {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.balances[acc]
+}
+
+// Entries - все записи по счёту в порядке проводок
+func (l *Ledger) Entries(acc Account) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]Entry, 0)
+	for _, p := range l.postings {
+		for _, e := range p.Entries {
+			if e.Account == acc {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+// Postings - копия списка проводок
+func (l *Ledger) Postings() []*Posting {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	postings := make([]*Posting, len(l.postings))
+	copy(postings, l.postings)
+	return postings
+}
+
+// ReconciliationItem - сверка одной карты с книгой проводок
+type ReconciliationItem struct {
+	CardID        int64   `json:"card_id"`
+	Balance       Money   `json:"balance"`        // Card.Balance
+	LedgerBalance Money   `json:"ledger_balance"` // остаток по счёту карты в книге
+	Drift         Money   `json:"drift"`          // Balance - LedgerBalance
+	Unposted      []int64 `json:"unposted"`       // транзакции карты без проводок
+	Mismatched    []int64 `json:"mismatched"`     // транзакции, сумма которых не совпадает с проводками
+	Orphaned      []int64 `json:"orphaned"`       // проводки по транзакциям, которых нет у карты
+}
+
+// OK - расхождений нет
+func (i ReconciliationItem) OK() bool {
+	return i.Drift.IsZero() && i.Balance.Currency == i.LedgerBalance.Currency &&
+		len(i.Mismatched) == 0 && len(i.Orphaned) == 0
+}
+
+// ReconciliationReport - результат сверки всех карт
+type ReconciliationReport struct {
+	OK    bool                 `json:"ok"`
+	Items []ReconciliationItem `json:"items"` // только карты с расхождениями
+}
+
+// reconcileCard - сверка баланса карты и истории её транзакций с книгой
+func reconcileCard(l *Ledger, c *Card) ReconciliationItem {
+	acc := CardAccount(c.ID)
+	item := ReconciliationItem{
+		CardID:        c.ID,
+		Balance:       c.Balance,
+		LedgerBalance: l.Balance(acc),
+		Unposted:      make([]int64, 0),
+		Mismatched:    make([]int64, 0),
+		Orphaned:      make([]int64, 0),
+	}
+	drift, err := c.Balance.Sub(item.LedgerBalance)
+	if err != nil {
+		// разные валюты у карты и в книге - расхождение на весь баланс
+		drift = c.Balance
+	}
+	item.Drift = drift
+
+	posted := make(map[int64]int64) // ID транзакции -> сумма записей (по модулю)
+	for _, e := range l.Entries(acc) {
+		if e.TransactionID == 0 {
+			continue
+		}
+		amount := e.Amount.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		posted[e.TransactionID] += amount
+	}
+	for _, t := range c.Transactions {
+		sum, ok := posted[t.ID]
+		switch {
+		case !ok:
+			item.Unposted = append(item.Unposted, t.ID)
+		case sum != t.TranSum.Amount:
+			item.Mismatched = append(item.Mismatched, t.ID)
+		}
+		delete(posted, t.ID)
+	}
+	for id := range posted {
+		item.Orphaned = append(item.Orphaned, id)
+	}
+	sort.Slice(item.Orphaned, func(i, j int) bool { return item.Orphaned[i] < item.Orphaned[j] })
+	return item
+}
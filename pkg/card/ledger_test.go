@@ -0,0 +1,101 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLedger_Post(t *testing.T) {
+	l := NewLedger()
+	if _, err := l.Move(0, "opening", EquityAccount(RUB), CardAccount(1), Rub(100_00), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Post(0, "bad", Entry{Account: CardAccount(1), Amount: Rub(-1)}, Entry{Account: MerchantsAccount(RUB), Amount: Rub(2)}); err != ErrUnbalancedPosting {
+		t.Errorf("Post() error = %v, want %v", err, ErrUnbalancedPosting)
+	}
+	if got := l.Balance(CardAccount(1)); got != Rub(100_00) {
+		t.Errorf("Balance() = %v, want %v", got, Rub(100_00))
+	}
+	if got := l.Balance(EquityAccount(RUB)); got != Rub(-100_00) {
+		t.Errorf("Balance(equity) = %v, want %v", got, Rub(-100_00))
+	}
+}
+
+func TestLedger_MoveAll(t *testing.T) {
+	l := NewLedger()
+	if _, err := l.Move(0, "opening", EquityAccount(RUB), CardAccount(1), Rub(100_00), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Move(0, "opening", EquityAccount(USD), CardAccount(2), NewMoney(math.MaxInt64, USD), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	postings := len(l.Postings())
+
+	// первая проводка проходит, вторая переполняет счёт карты 2 - книга не меняется
+	_, err := l.MoveAll(0, "transfer fx",
+		Leg{From: CardAccount(1), To: FxAccount(RUB), Amount: Rub(10_00), FromTxID: 1},
+		Leg{From: FxAccount(USD), To: CardAccount(2), Amount: NewMoney(1, USD), ToTxID: 2},
+	)
+	if !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("MoveAll() error = %v, want %v", err, ErrAmountOverflow)
+	}
+	if got := len(l.Postings()); got != postings {
+		t.Errorf("postings = %d, want %d", got, postings)
+	}
+	if got := l.Balance(CardAccount(1)); got != Rub(100_00) {
+		t.Errorf("Balance(card 1) = %v, want %v", got, Rub(100_00))
+	}
+	if l.HasAccount(FxAccount(RUB)) {
+		t.Errorf("fx account is opened by a failed MoveAll")
+	}
+
+	got, err := l.MoveAll(0, "transfer fx",
+		Leg{From: CardAccount(1), To: FxAccount(RUB), Amount: Rub(10_00), FromTxID: 1},
+		Leg{From: FxAccount(USD), To: EquityAccount(USD), Amount: NewMoney(1, USD)},
+	)
+	if err != nil || len(got) != 2 || got[1].ID != int64(postings)+2 {
+		t.Fatalf("MoveAll() = %v, %v", got, err)
+	}
+	if b := l.Balance(FxAccount(RUB)); b != Rub(10_00) {
+		t.Errorf("Balance(fx) = %v, want %v", b, Rub(10_00))
+	}
+}
+
+func TestService_Reconcile(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{
+		{ID: 1, UserID: 1, Balance: Rub(100_00)},
+		{ID: 2, UserID: 1, Balance: NewMoney(10_00, USD)},
+	})
+	svc.SetRateProvider(DefaultRates())
+
+	if _, err := svc.Purchase(context.Background(), 1, Rub(10_00), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Transfer(context.Background(), 1, 2, NewMoney(50, USD)); err != nil {
+		t.Fatal(err)
+	}
+	if report := svc.Reconcile(); !report.OK || len(report.Items) != 0 {
+		t.Fatalf("Reconcile() = %+v, want no drift", report)
+	}
+
+	// баланс изменён в обход книги, плюс транзакция без проводок
+	c, _ := svc.SearchByID(1)
+	c.Balance = Rub(1_000_00)
+	AddTransaction(c, &Transaction{ID: 100, TranSum: Rub(5_00)})
+
+	report := svc.Reconcile()
+	if report.OK || len(report.Items) != 1 {
+		t.Fatalf("Reconcile() = %+v, want drift on card 1", report)
+	}
+	item := report.Items[0]
+	if item.Drift != Rub(1_000_00-(100_00-10_00-46_25)) {
+		t.Errorf("Drift = %v", item.Drift)
+	}
+	if !reflect.DeepEqual(item.Unposted, []int64{100}) {
+		t.Errorf("Unposted = %v, want [100]", item.Unposted)
+	}
+}
@@ -0,0 +1,163 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrLimitPerTransaction = errors.New("per-transaction limit exceeded")
+	ErrLimitDaily          = errors.New("daily limit exceeded")
+	ErrLimitMonthly        = errors.New("monthly limit exceeded")
+	ErrMCCGroupDenied      = errors.New("MCC group is denied for this card")
+	ErrMCCGroupNotAllowed  = errors.New("MCC group is not in the allow list for this card")
+	ErrInvalidLimits       = errors.New("limits are not valid")
+)
+
+// Limits - лимиты карты; нулевая сумма - без ограничения
+type Limits struct {
+	PerTransaction Money    `json:"per_transaction"`
+	Daily          Money    `json:"daily"`
+	Monthly        Money    `json:"monthly"`
+	AllowMCCGroups []string `json:"allow_mcc_groups"` // если не пусто - разрешены только эти группы
+	DenyMCCGroups  []string `json:"deny_mcc_groups"`
+}
+
+// validate - суммы в валюте карты, неотрицательные; группы MCC известны
+func (l Limits) validate(currency Currency) error {
+	for _, m := range []Money{l.PerTransaction, l.Daily, l.Monthly} {
+		if m.untyped() {
+			continue
+		}
+		if m.Currency != currency {
+			return fmt.Errorf("%w: %v, card currency %s", ErrInvalidLimits, m, currency)
+		}
+		if m.IsNegative() {
+			return fmt.Errorf("%w: negative amount %v", ErrInvalidLimits, m)
+		}
+	}
+	for _, groups := range [][]string{l.AllowMCCGroups, l.DenyMCCGroups} {
+		for _, g := range groups {
+			if !ValidMCCGroup(g) {
+				return fmt.Errorf("%w: unknown MCC group %q", ErrInvalidLimits, g)
+			}
+		}
+	}
+	return nil
+}
+
+// SetLimits - задать лимиты карты (пустые Limits{} снимают ограничения)
+func (s *Service) SetLimits(ctx context.Context, cardID int64, limits Limits) (err error) {
+	defer func() { s.logOp(ctx, "set limits", err, "card_id", cardID) }()
+	card, ok := s.SearchByID(cardID)
+	if !ok {
+		return ErrCardNotFound
+	}
+	if err := limits.validate(card.Balance.Currency); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits[cardID] = limits
+	return nil
+}
+
+// GetLimits - лимиты карты
+func (s *Service) GetLimits(cardID int64) (Limits, error) {
+	if _, ok := s.SearchByID(cardID); !ok {
+		return Limits{}, ErrCardNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.limits[cardID], nil
+}
+
+// spent - сумма покупок карты (проведённых и в холде) в интервале [since, until) (вызывать под s.mu)
+func spent(c *Card, since time.Time, until time.Time) (Money, error) {
+	total := NewMoney(0, c.Balance.Currency)
+	for _, t := range c.Transactions {
+		if t.TranType != TranTypePurchase || t.TranDate < since.Unix() || t.TranDate >= until.Unix() {
+			continue
+		}
+		if t.Status != StatusDone && t.Status != StatusPending {
+			continue
+		}
+		sum, err := total.Add(t.TranSum)
+		if err != nil {
+			return Money{}, err
+		}
+		total = sum
+	}
+	return total, nil
+}
+
+// exceeds - spent + amount > limit (нулевой лимит - без ограничения)
+func exceeds(limit Money, spent Money, amount Money) (bool, error) {
+	if limit.IsZero() {
+		return false, nil
+	}
+	total, err := spent.Add(amount)
+	if err != nil {
+		return false, err
+	}
+	cmp, err := total.Cmp(limit)
+	if err != nil {
+		return false, err
+	}
+	return cmp > 0, nil
+}
+
+// checkLimits - проверка покупки amount (в валюте карты) с кодом mcc в момент now (вызывать под s.mu)
+func (s *Service) checkLimits(c *Card, amount Money, mcc string, now time.Time) error {
+	limits, ok := s.limits[c.ID]
+	if !ok {
+		return nil
+	}
+
+	group := MCCGroup(mcc)
+	if _, denied := Find(limits.DenyMCCGroups, group); denied {
+		return fmt.Errorf("%w: %s", ErrMCCGroupDenied, group)
+	}
+	if len(limits.AllowMCCGroups) > 0 {
+		if _, allowed := Find(limits.AllowMCCGroups, group); !allowed {
+			return fmt.Errorf("%w: %s", ErrMCCGroupNotAllowed, group)
+		}
+	}
+
+	if over, err := exceeds(limits.PerTransaction, NewMoney(0, amount.Currency), amount); err != nil {
+		return err
+	} else if over {
+		return fmt.Errorf("%w: limit %v, amount %v", ErrLimitPerTransaction, limits.PerTransaction, amount)
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	windows := []struct {
+		limit Money
+		since time.Time
+		until time.Time
+		err   error
+	}{
+		{limits.Daily, day, day.AddDate(0, 0, 1), ErrLimitDaily},
+		{limits.Monthly, month, month.AddDate(0, 1, 0), ErrLimitMonthly},
+	}
+	for _, w := range windows {
+		if w.limit.IsZero() {
+			continue
+		}
+		total, err := spent(c, w.since, w.until)
+		if err != nil {
+			return err
+		}
+		over, err := exceeds(w.limit, total, amount)
+		if err != nil {
+			return err
+		}
+		if over {
+			return fmt.Errorf("%w: limit %v, spent %v, amount %v", w.err, w.limit, total, amount)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_Limits(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(1000_00), IsVirtual: true}})
+
+	if err := svc.SetLimits(context.Background(), 1, Limits{Daily: NewMoney(1, USD)}); !errors.Is(err, ErrInvalidLimits) {
+		t.Errorf("SetLimits() with foreign currency error = %v, want %v", err, ErrInvalidLimits)
+	}
+	if err := svc.SetLimits(context.Background(), 1, Limits{DenyMCCGroups: []string{"unknown"}}); !errors.Is(err, ErrInvalidLimits) {
+		t.Errorf("SetLimits() with unknown group error = %v, want %v", err, ErrInvalidLimits)
+	}
+	err := svc.SetLimits(context.Background(), 1, Limits{
+		PerTransaction: Rub(100_00),
+		Daily:          Rub(150_00),
+		DenyMCCGroups:  []string{"gambling"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		amount  Money
+		mcc     string
+		wantErr error
+	}{
+		{name: "within limits", amount: Rub(90_00), mcc: "5411"},
+		{name: "per transaction", amount: Rub(100_01), mcc: "5411", wantErr: ErrLimitPerTransaction},
+		{name: "denied group", amount: Rub(1_00), mcc: "7995", wantErr: ErrMCCGroupDenied},
+		{name: "daily", amount: Rub(60_01), mcc: "5411", wantErr: ErrLimitDaily},
+		{name: "up to daily", amount: Rub(60_00), mcc: "5411"},
+	}
+	for _, tt := range tests {
+		_, err := svc.Purchase(context.Background(), 1, tt.amount, tt.mcc)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Purchase() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if _, err := svc.Authorize(context.Background(), 1, Rub(1_00), "5411", time.Hour); !errors.Is(err, ErrLimitDaily) {
+		t.Errorf("Authorize() error = %v, want %v", err, ErrLimitDaily)
+	}
+
+	if err := svc.SetLimits(context.Background(), 1, Limits{AllowMCCGroups: []string{"pharmacy"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, Rub(1_00), "5411"); !errors.Is(err, ErrMCCGroupNotAllowed) {
+		t.Errorf("Purchase() error = %v, want %v", err, ErrMCCGroupNotAllowed)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, Rub(1_00), "5912"); err != nil {
+		t.Errorf("Purchase() error = %v", err)
+	}
+}
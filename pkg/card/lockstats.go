@@ -0,0 +1,75 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStorageUnavailable - хранилище карт (данные в памяти под s.mu) не ответило вовремя
+var ErrStorageUnavailable = errors.New("card storage is unavailable")
+
+// LockStats - захваты блокировки Service и суммарное время их ожидания; рост ожидания - признак конкуренции
+type LockStats struct {
+	ReadAcquisitions  int64
+	WriteAcquisitions int64
+	ReadWait          time.Duration
+	WriteWait         time.Duration
+}
+
+// rwMutex - sync.RWMutex со счётчиками захватов и ожидания
+type rwMutex struct {
+	sync.RWMutex
+	reads     int64
+	writes    int64
+	readWait  int64 // наносекунды
+	writeWait int64
+}
+
+func (m *rwMutex) Lock() {
+	start := time.Now()
+	m.RWMutex.Lock()
+	atomic.AddInt64(&m.writeWait, int64(time.Since(start)))
+	atomic.AddInt64(&m.writes, 1)
+}
+
+func (m *rwMutex) RLock() {
+	start := time.Now()
+	m.RWMutex.RLock()
+	atomic.AddInt64(&m.readWait, int64(time.Since(start)))
+	atomic.AddInt64(&m.reads, 1)
+}
+
+func (m *rwMutex) stats() LockStats {
+	return LockStats{
+		ReadAcquisitions:  atomic.LoadInt64(&m.reads),
+		WriteAcquisitions: atomic.LoadInt64(&m.writes),
+		ReadWait:          time.Duration(atomic.LoadInt64(&m.readWait)),
+		WriteWait:         time.Duration(atomic.LoadInt64(&m.writeWait)),
+	}
+}
+
+// LockStats - статистика блокировки сервиса с момента создания
+func (s *Service) LockStats() LockStats {
+	return s.mu.stats()
+}
+
+// Ping - проверка готовности: блокировка хранилища берётся до истечения ctx.
+// Если блокировка зависла, горутина проверки ждёт её и после возврата ошибки
+func (s *Service) Ping(ctx context.Context) error {
+	acquired := make(chan struct{})
+	go func() {
+		s.mu.RLock()
+		s.mu.RUnlock()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, ctx.Err())
+	}
+}
@@ -0,0 +1,43 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_LockStats(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(1000_00)}})
+	before := svc.LockStats()
+
+	if _, err := svc.Purchase(context.Background(), 1, Rub(10_00), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	svc.GetCards()
+
+	after := svc.LockStats()
+	if after.WriteAcquisitions <= before.WriteAcquisitions || after.ReadAcquisitions <= before.ReadAcquisitions {
+		t.Errorf("LockStats() = %+v, before %+v", after, before)
+	}
+	if after.ReadWait < before.ReadWait || after.WriteWait < before.WriteWait {
+		t.Errorf("wait time decreased: %+v, before %+v", after, before)
+	}
+}
+
+func TestService_Ping(t *testing.T) {
+	svc := NewService()
+	if err := svc.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() = %v", err)
+	}
+
+	svc.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := svc.Ping(ctx)
+	svc.mu.Unlock()
+	if !errors.Is(err, ErrStorageUnavailable) {
+		t.Errorf("Ping() with held lock = %v, want %v", err, ErrStorageUnavailable)
+	}
+}
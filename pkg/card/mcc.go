@@ -2,18 +2,49 @@ package card
 
 const categoryNotFound = "Категория не найдена"
 
+// mccNames - названия категорий; общий map, чтобы F1-F4 не создавали его на каждую транзакцию
+var mccNames = map[string]string{
+	"5411": "Супермаркеты",
+	"5533": "Автоуслуги",
+	"5912": "Аптеки",
+	"1111": "Категория 1111",
+	"3333": "Категория 3333",
+	"5555": "Категория 5555",
+}
+
 // TranslateMCC - TranslateMCC
 func TranslateMCC(code string) string {
-	mcc := map[string]string{
-		"5411": "Супермаркеты",
-		"5533": "Автоуслуги",
-		"5912": "Аптеки",
-		"1111": "Категория 1111",
-		"3333": "Категория 3333",
-		"5555": "Категория 5555",
-	}
-	if value, ok := mcc[code]; ok {
+	if value, ok := mccNames[code]; ok {
 		return value
 	}
 	return categoryNotFound
 }
+
+// MCCGroupOther - группа для кодов, не попавших ни в одну группу
+const MCCGroupOther = "other"
+
+// mccGroups - группы MCC для лимитов карт
+var mccGroups = map[string][]string{
+	"groceries":   {"5411", "5499"},
+	"auto":        {"5533", "5541", "5542"},
+	"pharmacy":    {"5912"},
+	"restaurants": {"5812", "5814"},
+	"cash":        {"6010", "6011"},
+	"gambling":    {"7995"},
+}
+
+// MCCGroup - группа, в которую входит код
+func MCCGroup(code string) string {
+	for group, codes := range mccGroups {
+		if _, ok := Find(codes, code); ok {
+			return group
+		}
+	}
+	return MCCGroupOther
+}
+
+// ValidMCCGroup - группа существует
+func ValidMCCGroup(group string) bool {
+	_, ok := mccGroups[group]
+	return ok || group == MCCGroupOther
+}
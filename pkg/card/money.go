@@ -0,0 +1,304 @@
+package card
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrInvalidCurrency  = errors.New("currency is not valid")
+	ErrAmountOverflow   = errors.New("amount overflow")
+	ErrInvalidMoney     = errors.New("money value is not valid")
+)
+
+// Currency - код валюты по ISO 4217
+type Currency string
+
+const (
+	RUB Currency = "RUB"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	CNY Currency = "CNY"
+)
+
+// DefaultCurrency - валюта для старых данных, где сумма была просто int64 в копейках
+const DefaultCurrency = RUB
+
+// minorUnits - количество знаков после запятой для поддерживаемых валют
+var minorUnits = map[Currency]int{
+	RUB: 2,
+	USD: 2,
+	EUR: 2,
+	CNY: 2,
+}
+
+// Valid - валюта поддерживается
+func (c Currency) Valid() bool {
+	_, ok := minorUnits[c]
+	return ok
+}
+
+// ParseCurrency - разбор кода валюты ("rub", " RUB" -> RUB)
+func ParseCurrency(s string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	if !c.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, s)
+	}
+	return c, nil
+}
+
+// Money - сумма в минимальных единицах валюты (копейки, центы) вместе с валютой
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// NewMoney - конструктор суммы
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Rub - сумма в копейках
+func Rub(kopecks int64) Money {
+	return Money{Amount: kopecks, Currency: RUB}
+}
+
+// IsZero - нулевая сумма
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative - отрицательная сумма
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// untyped - нулевое значение Money{} без валюты, годится как начальное значение сумматора
+func (m Money) untyped() bool {
+	return m.Currency == "" && m.Amount == 0
+}
+
+// sameCurrency - приводит валюты двух сумм; нулевое Money{} принимает валюту второго слагаемого
+func sameCurrency(a, b Money) (Currency, error) {
+	switch {
+	case a.Currency == b.Currency:
+		return a.Currency, nil
+	case a.untyped():
+		return b.Currency, nil
+	case b.untyped():
+		return a.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+}
+
+func addInt64(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrAmountOverflow
+	}
+	return a + b, nil
+}
+
+// Add - сложение с проверкой валюты и переполнения
+func (m Money) Add(other Money) (Money, error) {
+	cur, err := sameCurrency(m, other)
+	if err != nil {
+		return Money{}, err
+	}
+	amount, err := addInt64(m.Amount, other.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: cur}, nil
+}
+
+// Neg - сумма с обратным знаком
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+// Sub - вычитание с проверкой валюты и переполнения
+func (m Money) Sub(other Money) (Money, error) {
+	neg, err := other.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+	return m.Add(neg)
+}
+
+// Cmp - сравнение сумм одной валюты: -1, 0, 1
+func (m Money) Cmp(other Money) (int, error) {
+	if _, err := sameCurrency(m, other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String - "1735.55 RUB"
+func (m Money) String() string {
+	digits := minorUnits[m.Currency]
+	if digits == 0 && m.Currency == "" {
+		digits = minorUnits[DefaultCurrency]
+	}
+	sign := ""
+	amount := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		amount = uint64(-(m.Amount + 1)) + 1
+	}
+	unit := uint64(1)
+	for i := 0; i < digits; i++ {
+		unit *= 10
+	}
+	s := sign + strconv.FormatUint(amount/unit, 10)
+	if digits > 0 {
+		s += fmt.Sprintf(".%0*d", digits, amount%unit)
+	}
+	if m.Currency != "" {
+		s += " " + string(m.Currency)
+	}
+	return s
+}
+
+// Major - сумма в основных единицах валюты (1735.55) как float64, для метрик; для расчётов - только Amount
+func (m Money) Major() float64 {
+	digits, ok := minorUnits[m.Currency]
+	if !ok {
+		digits = minorUnits[DefaultCurrency]
+	}
+	return float64(m.Amount) / math.Pow10(digits)
+}
+
+// ParseMoney - разбор строки вида "1735.55 RUB" (обратная операция к String)
+func ParseMoney(s string) (Money, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	cur, err := ParseCurrency(fields[1])
+	if err != nil {
+		return Money{}, err
+	}
+
+	value := fields[0]
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign = "-"
+		value = value[1:]
+	}
+	parts := strings.SplitN(value, ".", 2)
+	digits := minorUnits[cur]
+
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+	if len(frac) > digits || parts[0] == "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	amount, err := strconv.ParseInt(sign+parts[0]+frac, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrAmountOverflow
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	return Money{Amount: amount, Currency: cur}, nil
+}
+
+type moneyJSON struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// MarshalJSON - {"amount":173555,"currency":"RUB"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON - принимает объект или старый формат (число копеек, валюта по умолчанию)
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var amount int64
+	if err := json.Unmarshal(data, &amount); err == nil {
+		*m = Money{Amount: amount, Currency: DefaultCurrency}
+		return nil
+	}
+	var decoded moneyJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Currency == "" {
+		decoded.Currency = DefaultCurrency
+	}
+	cur, err := ParseCurrency(string(decoded.Currency))
+	if err != nil {
+		return err
+	}
+	*m = Money{Amount: decoded.Amount, Currency: cur}
+	return nil
+}
+
+// MarshalXML - <transum currency="RUB">173555</transum>
+func (m Money) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "currency"}, Value: string(m.Currency)})
+	return e.EncodeElement(m.Amount, start)
+}
+
+// UnmarshalXML - атрибут currency необязателен (валюта по умолчанию)
+func (m *Money) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var decoded struct {
+		Amount   int64  `xml:",chardata"`
+		Currency string `xml:"currency,attr"`
+	}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	cur := DefaultCurrency
+	if decoded.Currency != "" {
+		var err error
+		if cur, err = ParseCurrency(decoded.Currency); err != nil {
+			return err
+		}
+	}
+	*m = Money{Amount: decoded.Amount, Currency: cur}
+	return nil
+}
+
+// CSVFields - сумма и валюта отдельными колонками
+func (m Money) CSVFields() []string {
+	return []string{strconv.FormatInt(m.Amount, 10), string(m.Currency)}
+}
+
+// MoneyFromCSV - обратная операция к CSVFields; пустая валюта - валюта по умолчанию
+func MoneyFromCSV(amount string, currency string) (Money, error) {
+	value, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrAmountOverflow
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, amount)
+	}
+	cur := DefaultCurrency
+	if strings.TrimSpace(currency) != "" {
+		if cur, err = ParseCurrency(currency); err != nil {
+			return Money{}, err
+		}
+	}
+	return Money{Amount: value, Currency: cur}, nil
+}
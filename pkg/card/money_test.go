@@ -0,0 +1,136 @@
+package card
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMoney_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{name: "same currency", a: Rub(1_00), b: Rub(2_50), want: Rub(3_50)},
+		{name: "zero value adopts currency", a: Money{}, b: NewMoney(5, USD), want: NewMoney(5, USD)},
+		{name: "currency mismatch", a: Rub(1), b: NewMoney(1, USD), wantErr: ErrCurrencyMismatch},
+		{name: "overflow", a: Rub(math.MaxInt64), b: Rub(1), wantErr: ErrAmountOverflow},
+		{name: "underflow", a: Rub(math.MinInt64), b: Rub(-1), wantErr: ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_StringParse(t *testing.T) {
+	tests := []struct {
+		money Money
+		str   string
+	}{
+		{Rub(1735_55), "1735.55 RUB"},
+		{NewMoney(-5, USD), "-0.05 USD"},
+		{NewMoney(100_00, CNY), "100.00 CNY"},
+		{NewMoney(math.MinInt64, EUR), "-92233720368547758.08 EUR"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.str {
+			t.Errorf("String() = %q, want %q", got, tt.str)
+		}
+		got, err := ParseMoney(tt.str)
+		if err != nil || got != tt.money {
+			t.Errorf("ParseMoney(%q) = %v, %v, want %v", tt.str, got, err, tt.money)
+		}
+	}
+
+	for _, s := range []string{"", "10", "1.005 RUB", "1.00 XXX", "abc RUB"} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Errorf("ParseMoney(%q) expected error", s)
+		}
+	}
+}
+
+func TestMoney_Encoding(t *testing.T) {
+	tr := &Transaction{ID: 1, TranType: "purchase", TranSum: NewMoney(12_34, EUR), MccCode: "5411", Status: StatusDone, OwnerID: 2}
+
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Transaction
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&fromJSON, tr) {
+		t.Errorf("json round trip = %+v, want %+v", fromJSON, tr)
+	}
+
+	var legacy Transaction
+	if err := json.Unmarshal([]byte(`{"id":1,"transum":173555}`), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy.TranSum != Rub(1735_55) {
+		t.Errorf("legacy json transum = %v, want %v", legacy.TranSum, Rub(1735_55))
+	}
+
+	data, err = xml.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromXML Transaction
+	if err := xml.Unmarshal(data, &fromXML); err != nil {
+		t.Fatal(err)
+	}
+	if fromXML.TranSum != tr.TranSum {
+		t.Errorf("xml round trip transum = %v, want %v", fromXML.TranSum, tr.TranSum)
+	}
+
+	rows, err := MapRowToTransaction([][]string{
+		{"1", "purchase", "1234", "2020-01-01 00:00:00 +0300 MSK", "5411", "done", "2", "EUR"},
+		{"2", "purchase", "1234", "2020-01-01 00:00:00 +0300 MSK", "5411", "done", "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].TranSum != NewMoney(12_34, EUR) || rows[1].TranSum != Rub(12_34) {
+		t.Errorf("csv transum = %v, %v", rows[0].TranSum, rows[1].TranSum)
+	}
+}
+
+func TestService_Transfer(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{
+		{ID: 1, Balance: Rub(100_00)},
+		{ID: 2, Balance: Rub(0)},
+		{ID: 3, Balance: NewMoney(0, USD)},
+	})
+
+	if err := svc.Transfer(context.Background(), 1, 2, Rub(40_00)); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	from, _ := svc.SearchByID(1)
+	to, _ := svc.SearchByID(2)
+	if from.Balance != Rub(60_00) || to.Balance != Rub(40_00) {
+		t.Errorf("balances after transfer = %v, %v", from.Balance, to.Balance)
+	}
+
+	if err := svc.Transfer(context.Background(), 1, 3, Rub(1_00)); err != ErrNoRateProvider {
+		t.Errorf("Transfer() to USD card error = %v, want %v", err, ErrNoRateProvider)
+	}
+	if err := svc.Transfer(context.Background(), 1, 2, Rub(1000_00)); err != ErrCardFromBalanceLessThenAmount {
+		t.Errorf("Transfer() error = %v, want %v", err, ErrCardFromBalanceLessThenAmount)
+	}
+}
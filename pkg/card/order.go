@@ -0,0 +1,235 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAddressRequired   = errors.New("delivery address is required for plastic card")
+	ErrNotPlasticCard    = errors.New("card is not plastic")
+	ErrOrderNotFound     = errors.New("card order not found")
+	ErrOrderNotDelivered = errors.New("card order is not delivered yet")
+	ErrOrderDelivered    = errors.New("card order is already delivered")
+	ErrCardNotActivated  = errors.New("Card is not activated")
+)
+
+// OrderStatus - этап выпуска пластиковой карты
+type OrderStatus string
+
+const (
+	OrderOrdered   OrderStatus = "ordered"
+	OrderPrinted   OrderStatus = "printed"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderActivated OrderStatus = "activated" // только через ActivateCard
+)
+
+// orderFlow - следующий этап производства и доставки
+var orderFlow = map[OrderStatus]OrderStatus{
+	OrderOrdered: OrderPrinted,
+	OrderPrinted: OrderShipped,
+	OrderShipped: OrderDelivered,
+}
+
+// Address - адрес доставки карты
+type Address struct {
+	Recipient  string `json:"recipient"`
+	City       string `json:"city"`
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code"`
+}
+
+func (a Address) validate() error {
+	for name, v := range map[string]string{"recipient": a.Recipient, "city": a.City, "street": a.Street, "postal_code": a.PostalCode} {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("%w: %s is empty", ErrAddressRequired, name)
+		}
+	}
+	return nil
+}
+
+// OrderStatusChange - запись истории заказа
+type OrderStatusChange struct {
+	Status OrderStatus `json:"status"`
+	Time   int64       `json:"time"` // unix timestamp
+}
+
+// CardOrder - заказ пластиковой карты; карта неактивна, пока заказ не дойдёт до OrderActivated
+type CardOrder struct {
+	ID      int64               `json:"id"`
+	CardID  int64               `json:"card_id"`
+	UserID  int64               `json:"user_id"`
+	Address Address             `json:"address"`
+	Status  OrderStatus         `json:"status"`
+	History []OrderStatusChange `json:"history"`
+}
+
+func (o *CardOrder) setStatus(status OrderStatus, now time.Time) {
+	o.Status = status
+	o.History = append(o.History, OrderStatusChange{Status: status, Time: now.Unix()})
+}
+
+// orderSnapshot - копия заказа (вызывать под s.mu)
+func orderSnapshot(o *CardOrder) *CardOrder {
+	cp := *o
+	cp.History = append([]OrderStatusChange(nil), o.History...)
+	return &cp
+}
+
+// usable - можно ли списывать с карты (вызывать под s.mu)
+func (c *Card) usable() error {
+	if c.Blocked {
+		return ErrCardBlocked
+	}
+	if c.Inactive {
+		return ErrCardNotActivated
+	}
+	return nil
+}
+
+// OrderCard - заказ пластиковой карты по продукту с доставкой; карта выпускается сразу, но неактивной
+func (s *Service) OrderCard(ctx context.Context, productID string, userID int64, address Address) (order *CardOrder, err error) {
+	defer func() { s.logOp(ctx, "order card", err, "product_id", productID, "user_id", userID) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.catalog.Product(productID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProductNotFound, productID)
+	}
+	if p.Type != "plastic" {
+		return nil, fmt.Errorf("%w: product %q", ErrNotPlasticCard, productID)
+	}
+	card, err := s.issueProduct(p, userID, &address, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return orderSnapshot(s.orders[card.ID]), nil
+}
+
+// GetOrder - заказ по ID карты
+func (s *Service) GetOrder(cardID int64) (*CardOrder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.orders[cardID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return orderSnapshot(o), nil
+}
+
+// AdvanceOrder - перевести заказ на следующий этап (до OrderDelivered); публикует CardOrderUpdated
+func (s *Service) AdvanceOrder(ctx context.Context, cardID int64) (order *CardOrder, err error) {
+	defer func() { s.logOp(ctx, "advance order", err, "card_id", cardID) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[cardID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	if err := s.advanceOrder(o, time.Now()); err != nil {
+		return nil, err
+	}
+	return orderSnapshot(o), nil
+}
+
+// advanceOrder - вызывать под s.mu
+func (s *Service) advanceOrder(o *CardOrder, now time.Time) error {
+	next, ok := orderFlow[o.Status]
+	if !ok {
+		return ErrOrderDelivered
+	}
+	o.setStatus(next, now)
+	s.publishOrder(o)
+	return nil
+}
+
+// advanceOrders - один шаг для всех незавершённых заказов; возвращает число изменённых
+func (s *Service) advanceOrders(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, o := range s.orders {
+		if s.advanceOrder(o, now) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// ActivateCard - активация доставленной пластиковой карты; публикует CardActivated
+func (s *Service) ActivateCard(ctx context.Context, cardID int64) (activated *Card, err error) {
+	defer func() { s.logOp(ctx, "activate card", err, "card_id", cardID) }()
+	card, ok := s.SearchByID(cardID)
+	if !ok {
+		return nil, ErrCardNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[cardID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	switch o.Status {
+	case OrderActivated:
+		return cardSnapshot(card), nil
+	case OrderDelivered:
+	default:
+		return nil, fmt.Errorf("%w: status %s", ErrOrderNotDelivered, o.Status)
+	}
+	o.setStatus(OrderActivated, time.Now())
+	card.Inactive = false
+	s.publishOrder(o)
+	s.publishCard(EventCardActivated, card)
+	return cardSnapshot(card), nil
+}
+
+// DefaultFulfilmentStep - пауза между этапами имитации доставки
+const DefaultFulfilmentStep = 10 * time.Second
+
+// FulfilmentWorker - имитация производства и доставки: каждые step незавершённые заказы переходят на следующий этап;
+// реализует Serve/Shutdown для lifecycle.Manager
+type FulfilmentWorker struct {
+	svc  *Service
+	step time.Duration
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewFulfilmentWorker(svc *Service, step time.Duration) *FulfilmentWorker {
+	if step <= 0 {
+		step = DefaultFulfilmentStep
+	}
+	return &FulfilmentWorker{svc: svc, step: step, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Serve - работает до Shutdown
+func (w *FulfilmentWorker) Serve() error {
+	defer close(w.done)
+	ticker := time.NewTicker(w.step)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.svc.advanceOrders(now)
+		case <-w.stop:
+			return nil
+		}
+	}
+}
+
+// Shutdown - остановить Serve и дождаться его завершения
+func (w *FulfilmentWorker) Shutdown(ctx context.Context) error {
+	w.once.Do(func() { close(w.stop) })
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,145 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testAddress = Address{Recipient: "Ivan Ivanov", City: "Moscow", Street: "Tverskaya 1", PostalCode: "125009"}
+
+func TestService_OrderCard(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(1000_00)}})
+
+	order, err := svc.OrderCard(context.Background(), "plastic-master", 1, testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.CardID != 2 || order.Status != OrderOrdered || len(order.History) != 1 {
+		t.Fatalf("OrderCard() = %+v", order)
+	}
+	c, _ := svc.SearchByID(order.CardID)
+	if c.IsVirtual || !c.Inactive {
+		t.Errorf("ordered card = %+v, want inactive plastic", c)
+	}
+
+	// до активации с карты нельзя списывать, но пополнить можно
+	if err := svc.Transfer(context.Background(), 1, 2, Rub(100_00)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Purchase(context.Background(), 2, Rub(10_00), "5411"); !errors.Is(err, ErrCardNotActivated) {
+		t.Errorf("Purchase() error = %v, want %v", err, ErrCardNotActivated)
+	}
+	if _, err := svc.ActivateCard(context.Background(), 2); !errors.Is(err, ErrOrderNotDelivered) {
+		t.Errorf("ActivateCard() error = %v, want %v", err, ErrOrderNotDelivered)
+	}
+
+	for _, want := range []OrderStatus{OrderPrinted, OrderShipped, OrderDelivered} {
+		order, err = svc.AdvanceOrder(context.Background(), 2)
+		if err != nil || order.Status != want {
+			t.Fatalf("AdvanceOrder() = %+v, %v, want %s", order, err, want)
+		}
+	}
+	if _, err := svc.AdvanceOrder(context.Background(), 2); !errors.Is(err, ErrOrderDelivered) {
+		t.Errorf("AdvanceOrder() error = %v, want %v", err, ErrOrderDelivered)
+	}
+
+	c, err = svc.ActivateCard(context.Background(), 2)
+	if err != nil || c.Inactive {
+		t.Fatalf("ActivateCard() = %+v, %v", c, err)
+	}
+	if _, err := svc.Purchase(context.Background(), 2, Rub(10_00), "5411"); err != nil {
+		t.Errorf("Purchase() after activation error = %v", err)
+	}
+	order, _ = svc.GetOrder(2)
+	if order.Status != OrderActivated || len(order.History) != 5 {
+		t.Errorf("GetOrder() = %+v", order)
+	}
+	if _, err := svc.GetOrder(1); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder(1) error = %v, want %v", err, ErrOrderNotFound)
+	}
+}
+
+func TestService_OrderCardErrors(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(0)}})
+
+	tests := []struct {
+		name string
+		call func() error
+		err  error
+	}{
+		{name: "no street", err: ErrAddressRequired, call: func() error {
+			_, err := svc.OrderCard(context.Background(), "plastic-visa", 1, Address{Recipient: "Ivan", City: "Moscow", PostalCode: "125009"})
+			return err
+		}},
+		{name: "virtual product", err: ErrNotPlasticCard, call: func() error {
+			_, err := svc.OrderCard(context.Background(), "virtual-visa", 1, testAddress)
+			return err
+		}},
+		{name: "unknown product", err: ErrProductNotFound, call: func() error {
+			_, err := svc.OrderCard(context.Background(), "metal-amex", 1, testAddress)
+			return err
+		}},
+		{name: "plastic without address", err: ErrAddressRequired, call: func() error {
+			_, err := svc.IssueProduct(context.Background(), "plastic-visa", 1)
+			return err
+		}},
+		{name: "plastic by type", err: ErrAddressRequired, call: func() error {
+			_, err := svc.IssueCard(context.Background(), "plastic", "Visa", 1, RUB)
+			return err
+		}},
+		{name: "activate virtual", err: ErrOrderNotFound, call: func() error {
+			_, err := svc.ActivateCard(context.Background(), 1)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFulfilmentWorker(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(0)}})
+	events := make(chan Event, 16)
+	defer svc.Events().Subscribe(func(e Event) {
+		if e.Type == EventCardOrderUpdated {
+			events <- e
+		}
+	})()
+	if _, err := svc.OrderCard(context.Background(), "plastic-unionpay", 1, testAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	worker := NewFulfilmentWorker(svc, 5*time.Millisecond)
+	served := make(chan error, 1)
+	go func() { served <- worker.Serve() }()
+
+	for _, want := range []OrderStatus{OrderOrdered, OrderPrinted, OrderShipped, OrderDelivered} {
+		select {
+		case e := <-events:
+			if e.Order.Status != want || e.CardID != 2 {
+				t.Fatalf("event = %+v, want status %s", e.Order, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s event", want)
+		}
+	}
+
+	if err := worker.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+	if order, _ := svc.GetOrder(2); order.Status != OrderDelivered {
+		t.Errorf("status = %s, want %s", order.Status, OrderDelivered)
+	}
+}
@@ -0,0 +1,334 @@
+package card
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProductNotFound = errors.New("card product not found")
+	ErrInvalidProduct  = errors.New("card product is not valid")
+	// ErrCardNumberOverflow - BIN и ID карты не помещаются в 16-значный номер
+	ErrCardNumberOverflow = errors.New("card id does not fit into the card number")
+)
+
+// BINRange - диапазон BIN (первые цифры номера карты); From и To одной длины, 6-8 цифр
+type BINRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (r BINRange) validate() error {
+	if len(r.From) < 6 || len(r.From) > 8 || len(r.From) != len(r.To) {
+		return fmt.Errorf("BIN range %s-%s: want 6-8 digits of the same length", r.From, r.To)
+	}
+	from, err1 := strconv.ParseInt(r.From, 10, 64)
+	to, err2 := strconv.ParseInt(r.To, 10, 64)
+	if err1 != nil || err2 != nil || from > to || r.From[0] == '0' {
+		return fmt.Errorf("BIN range %s-%s is not valid", r.From, r.To)
+	}
+	return nil
+}
+
+// bin - BIN из диапазона для карты с номером seq
+func (r BINRange) bin(seq int64) string {
+	from, _ := strconv.ParseInt(r.From, 10, 64)
+	to, _ := strconv.ParseInt(r.To, 10, 64)
+	return strconv.FormatInt(from+seq%(to-from+1), 10)
+}
+
+// Fees - тарифы продукта в валюте продукта; нулевая сумма - бесплатно.
+// Тарифы только показываются в каталоге: сервис их не списывает
+type Fees struct {
+	Issue   Money `json:"issue"`
+	Monthly Money `json:"monthly"`
+}
+
+// Product - продукт каталога: что именно выпускается по заказу
+type Product struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`   // plastic, virtual
+	Issuer         string   `json:"issuer"` // Master, Visa, UnionPay
+	Currency       Currency `json:"currency"`
+	BIN            BINRange `json:"bin"`
+	ValidityMonths int      `json:"validity_months"` // 0 - срок из IssueSettings.DueDate
+	Fees           Fees     `json:"fees"`
+	Limits         Limits   `json:"limits"` // лимиты новой карты
+}
+
+func (p Product) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %q: %s", ErrInvalidProduct, p.ID, fmt.Sprintf(format, args...))
+	}
+	if p.ID == "" || p.Name == "" {
+		return invalid("id and name are required")
+	}
+	if _, ok := Find(CardTypes, p.Type); !ok {
+		return invalid("card type %q", p.Type)
+	}
+	if p.Issuer == "" {
+		return invalid("issuer is required")
+	}
+	if !p.Currency.Valid() {
+		return invalid("currency %q", p.Currency)
+	}
+	if err := p.BIN.validate(); err != nil {
+		return invalid("%v", err)
+	}
+	if p.ValidityMonths < 0 {
+		return invalid("negative validity")
+	}
+	for _, fee := range []Money{p.Fees.Issue, p.Fees.Monthly} {
+		if !fee.untyped() && (fee.Currency != p.Currency || fee.IsNegative()) {
+			return invalid("fee %v", fee)
+		}
+	}
+	if err := p.Limits.validate(p.Currency); err != nil {
+		return invalid("%v", err)
+	}
+	return nil
+}
+
+// Catalog - каталог продуктов в порядке добавления
+type Catalog struct {
+	products []Product
+}
+
+// NewCatalog - каталог с проверкой продуктов и уникальности ID
+func NewCatalog(products []Product) (*Catalog, error) {
+	seen := make(map[string]bool, len(products))
+	for _, p := range products {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("%w: duplicate id %q", ErrInvalidProduct, p.ID)
+		}
+		seen[p.ID] = true
+	}
+	return &Catalog{products: append([]Product(nil), products...)}, nil
+}
+
+// Products - копия списка продуктов
+func (c *Catalog) Products() []Product {
+	return append([]Product(nil), c.products...)
+}
+
+func (c *Catalog) Product(id string) (Product, bool) {
+	for _, p := range c.products {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Product{}, false
+}
+
+// find - первый продукт с таким типом, платёжной системой и валютой (для заказа без ID продукта)
+func (c *Catalog) find(cardtype string, issuer string, currency Currency) (Product, bool) {
+	for _, p := range c.products {
+		if p.Type == cardtype && p.Issuer == issuer && p.Currency == currency {
+			return p, true
+		}
+	}
+	return Product{}, false
+}
+
+// capitalize - первая латинская буква заглавная: "virtual" -> "Virtual"
+func capitalize(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
+
+// DefaultCatalog - рублёвые карты всех типов и платёжных систем плюс валютные виртуальные
+func DefaultCatalog() *Catalog {
+	products := make([]Product, 0)
+	for i, cardtype := range CardTypes {
+		for _, issuer := range CardIssuer {
+			prefix := issuerBINs[issuer] + strconv.Itoa(i)
+			products = append(products, Product{
+				ID:             cardtype + "-" + strings.ToLower(issuer),
+				Name:           capitalize(cardtype) + " " + issuer,
+				Type:           cardtype,
+				Issuer:         issuer,
+				Currency:       RUB,
+				BIN:            BINRange{From: prefix + "00", To: prefix + "49"},
+				ValidityMonths: 60,
+			})
+		}
+	}
+	products = append(products,
+		Product{ID: "virtual-visa-usd", Name: "Virtual Visa USD", Type: "virtual", Issuer: "Visa", Currency: USD,
+			BIN: BINRange{From: "400150", To: "400199"}, ValidityMonths: 36, Fees: Fees{Monthly: NewMoney(1_00, USD)}},
+		Product{ID: "virtual-master-eur", Name: "Virtual Master EUR", Type: "virtual", Issuer: "Master", Currency: EUR,
+			BIN: BINRange{From: "510150", To: "510199"}, ValidityMonths: 36, Fees: Fees{Monthly: NewMoney(1_00, EUR)}},
+	)
+	catalog, err := NewCatalog(products)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// LoadCatalogFromJSON - каталог из файла {"products": [...]}, например test/products.json
+func LoadCatalogFromJSON(importPath string) (*Catalog, error) {
+	content, err := ioutil.ReadFile(importPath)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Products []Product `json:"products"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	return NewCatalog(file.Products)
+}
+
+// CardNumber - номер карты: BIN, номер счёта (ID карты) и контрольная цифра Луна, группами по 4;
+// ErrCardNumberOverflow, если BIN и ID вместе длиннее 15 цифр
+func CardNumber(bin string, cardID int64) (string, error) {
+	account := strconv.FormatInt(cardID, 10)
+	if cardID <= 0 || len(bin)+len(account) > 15 {
+		return "", fmt.Errorf("%w: BIN %s, card %d", ErrCardNumberOverflow, bin, cardID)
+	}
+	digits := bin + strings.Repeat("0", 15-len(bin)-len(account)) + account
+	digits += strconv.Itoa(luhnCheckDigit(digits))
+	groups := make([]string, 0, 4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, " "), nil
+}
+
+// luhnCheckDigit - контрольная цифра для номера без неё
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// ValidCardNumber - проверка номера по алгоритму Луна (пробелы игнорируются)
+func ValidCardNumber(number string) bool {
+	digits := strings.ReplaceAll(number, " ", "")
+	if len(digits) < 12 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(digits[:len(digits)-1]) == int(digits[len(digits)-1]-'0')
+}
+
+// SetCatalog - каталог продуктов для IssueProduct и IssueCard
+func (s *Service) SetCatalog(catalog *Catalog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalog = catalog
+}
+
+// Products - продукты, которые можно заказать при текущих IssueSettings
+func (s *Service) Products() []Product {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	products := make([]Product, 0)
+	for _, p := range s.catalog.Products() {
+		if s.issue.check(p.Type, p.Issuer) == nil {
+			products = append(products, p)
+		}
+	}
+	return products
+}
+
+// IssueProduct - выпуск карты по продукту каталога; публикует CardIssued
+func (s *Service) IssueProduct(ctx context.Context, productID string, userID int64) (issued *Card, err error) {
+	defer func() { s.logOp(ctx, "issue card", err, "product_id", productID, "user_id", userID) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.catalog.Product(productID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProductNotFound, productID)
+	}
+	card, err := s.issueProduct(p, userID, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return cardSnapshot(card), nil
+}
+
+// FindProduct - первый продукт каталога с таким типом, платёжной системой и валютой
+func (s *Service) FindProduct(cardtype string, issuer string, currency Currency) (Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.catalog.find(cardtype, issuer, currency)
+	if !ok {
+		return Product{}, fmt.Errorf("%w: %s %s %s", ErrProductNotFound, cardtype, issuer, currency)
+	}
+	return p, nil
+}
+
+// issueProduct - вызывать под s.mu; пластиковая карта требует адрес доставки,
+// выпускается неактивной и получает заказ (OrderCard)
+func (s *Service) issueProduct(p Product, userID int64, address *Address, now time.Time) (*Card, error) {
+	if err := s.issue.check(p.Type, p.Issuer); err != nil {
+		return nil, err
+	}
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+	if err := s.issue.checkCardCap(s.cards, userID); err != nil {
+		return nil, err
+	}
+	plastic := p.Type == "plastic"
+	if plastic && address == nil {
+		return nil, ErrAddressRequired
+	}
+	if plastic {
+		if err := address.validate(); err != nil {
+			return nil, err
+		}
+	}
+	id := GetMaxIDFromcards(s.cards)
+	number, err := CardNumber(p.BIN.bin(id), id)
+	if err != nil {
+		return nil, err
+	}
+	card := &Card{
+		ID: id, Type: p.Issuer, BankName: s.issue.BankName, CardNumber: number,
+		Balance: NewMoney(0, p.Currency), CardDueDate: s.issue.DueDate, UserID: userID, IsVirtual: !plastic, Inactive: plastic, ProductID: p.ID,
+	}
+	if p.ValidityMonths > 0 {
+		card.CardDueDate = now.AddDate(0, p.ValidityMonths, 0).Format(DueDateLayout)
+	}
+	s.cards = append(s.cards, card)
+	s.openAccount(card)
+	s.limits[card.ID] = p.Limits
+	s.publishCard(EventCardIssued, card)
+	if plastic {
+		s.orderSeq++
+		order := &CardOrder{ID: s.orderSeq, CardID: card.ID, UserID: userID, Address: *address}
+		order.setStatus(OrderOrdered, now)
+		s.orders[card.ID] = order
+		s.publishOrder(order)
+	}
+	return card, nil
+}
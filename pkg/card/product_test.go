@@ -0,0 +1,150 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardNumber(t *testing.T) {
+	tests := []struct {
+		bin    string
+		cardID int64
+		want   string
+	}{
+		{bin: "400000", cardID: 1, want: "4000 0000 0000 0010"},
+		{bin: "510050", cardID: 42, want: "5100 5000 0000 0429"},
+		{bin: "62000012", cardID: 123456, want: "6200 0012 0123 4567"},
+	}
+	for _, tt := range tests {
+		got, err := CardNumber(tt.bin, tt.cardID)
+		if err != nil || got != tt.want {
+			t.Errorf("CardNumber(%s, %d) = %s, %v; want %s", tt.bin, tt.cardID, got, err, tt.want)
+		}
+		if !ValidCardNumber(got) {
+			t.Errorf("ValidCardNumber(%s) = false", got)
+		}
+	}
+	// 8 цифр BIN и 8 цифр ID в 15 цифр без контрольной не помещаются
+	for _, tt := range []struct {
+		bin    string
+		cardID int64
+	}{{"62000012", 12_345_678}, {"400000", 1_000_000_000}, {"400000", 0}} {
+		if got, err := CardNumber(tt.bin, tt.cardID); !errors.Is(err, ErrCardNumberOverflow) {
+			t.Errorf("CardNumber(%s, %d) = %s, %v; want %v", tt.bin, tt.cardID, got, err, ErrCardNumberOverflow)
+		}
+	}
+	for _, number := range []string{"4000 0000 0000 0011", "1111 2222 3333 4445", "4000 0000 000A 0010", "4000"} {
+		if ValidCardNumber(number) {
+			t.Errorf("ValidCardNumber(%s) = true", number)
+		}
+	}
+}
+
+func TestNewCatalog(t *testing.T) {
+	valid := Product{ID: "p", Name: "P", Type: "virtual", Issuer: "Visa", Currency: RUB, BIN: BINRange{From: "400000", To: "400099"}}
+	with := func(change func(p *Product)) Product {
+		p := valid
+		change(&p)
+		return p
+	}
+	tests := []struct {
+		name     string
+		products []Product
+		err      error
+	}{
+		{name: "valid", products: []Product{valid, with(func(p *Product) { p.ID = "q" })}},
+		{name: "duplicate id", products: []Product{valid, valid}, err: ErrInvalidProduct},
+		{name: "no name", products: []Product{with(func(p *Product) { p.Name = "" })}, err: ErrInvalidProduct},
+		{name: "unknown type", products: []Product{with(func(p *Product) { p.Type = "metal" })}, err: ErrInvalidProduct},
+		{name: "bad currency", products: []Product{with(func(p *Product) { p.Currency = "XXX" })}, err: ErrInvalidProduct},
+		{name: "short BIN", products: []Product{with(func(p *Product) { p.BIN = BINRange{From: "4000", To: "4099"} })}, err: ErrInvalidProduct},
+		{name: "reversed BIN", products: []Product{with(func(p *Product) { p.BIN = BINRange{From: "400099", To: "400000"} })}, err: ErrInvalidProduct},
+		{name: "fee in other currency", products: []Product{with(func(p *Product) { p.Fees.Monthly = NewMoney(1_00, USD) })}, err: ErrInvalidProduct},
+		{name: "limit in other currency", products: []Product{with(func(p *Product) { p.Limits.Daily = NewMoney(1_00, USD) })}, err: ErrInvalidProduct},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCatalog(tt.products); !errors.Is(err, tt.err) {
+				t.Errorf("NewCatalog() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestService_IssueProduct(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(0)}})
+	catalog, err := NewCatalog([]Product{
+		{ID: "gold", Name: "Gold", Type: "virtual", Issuer: "Master", Currency: RUB, BIN: BINRange{From: "510000", To: "510009"},
+			ValidityMonths: 24, Limits: Limits{Daily: Rub(1_000_00)}},
+		{ID: "mir", Name: "Mir", Type: "virtual", Issuer: "Mir", Currency: RUB, BIN: BINRange{From: "220000", To: "220099"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetCatalog(catalog)
+
+	c, err := svc.IssueProduct(context.Background(), "gold", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDue := time.Now().AddDate(0, 24, 0).Format(DueDateLayout)
+	if c.ID != 2 || c.ProductID != "gold" || c.Type != "Master" || !c.IsVirtual || c.CardDueDate != wantDue ||
+		!strings.HasPrefix(c.CardNumber, "5100 02") || !ValidCardNumber(c.CardNumber) {
+		t.Errorf("IssueProduct() = %+v", c)
+	}
+	if got, _ := svc.GetLimits(c.ID); got.Daily != Rub(1_000_00) {
+		t.Errorf("GetLimits() = %+v, want product limits", got)
+	}
+
+	// Mir не разрешена настройками выпуска: продукта нет в списке и выпустить его нельзя
+	if products := svc.Products(); len(products) != 1 || products[0].ID != "gold" {
+		t.Errorf("Products() = %+v", products)
+	}
+	if _, err := svc.IssueProduct(context.Background(), "mir", 1); !errors.Is(err, ErrInvaildCardIssuer) {
+		t.Errorf("IssueProduct(mir) error = %v, want %v", err, ErrInvaildCardIssuer)
+	}
+	if _, err := svc.IssueProduct(context.Background(), "silver", 1); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("IssueProduct(silver) error = %v, want %v", err, ErrProductNotFound)
+	}
+	if _, err := svc.IssueProduct(context.Background(), "gold", 42); !errors.Is(err, ErrNoCardWithUserID) {
+		t.Errorf("IssueProduct(user 42) error = %v, want %v", err, ErrNoCardWithUserID)
+	}
+
+	// заказ по типу и платёжной системе выбирает продукт каталога
+	c, err = svc.IssueCard(context.Background(), "virtual", "Master", 1, RUB)
+	if err != nil || c.ProductID != "gold" {
+		t.Errorf("IssueCard() = %+v, %v", c, err)
+	}
+}
+
+func TestLoadCatalogFromJSON(t *testing.T) {
+	catalog, err := LoadCatalogFromJSON("../../test/products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := catalog.Product("plastic-master")
+	if !ok || p.Fees.Issue != Rub(499_00) || p.Limits.Daily != Rub(100_000_00) {
+		t.Errorf("Product(plastic-master) = %+v, %v", p, ok)
+	}
+	if len(DefaultCatalog().Products()) == 0 {
+		t.Error("DefaultCatalog() is empty")
+	}
+}
+
+func TestCapitalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{in: "virtual", want: "Virtual"},
+		{in: "Plastic", want: "Plastic"},
+		{in: "", want: ""},
+		{in: "мир", want: "мир"},
+	}
+	for _, tt := range tests {
+		if got := capitalize(tt.in); got != tt.want {
+			t.Errorf("capitalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
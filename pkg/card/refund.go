@@ -0,0 +1,131 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrNotReversible       = errors.New("transaction can not be reversed")
+	ErrNotRefundable       = errors.New("transaction can not be refunded")
+	ErrRefundExceedsAmount = errors.New("refund amount exceeds transaction amount")
+)
+
+// findTransaction - карта и транзакция по ID транзакции (вызывать под s.mu)
+func (s *Service) findTransaction(tranID int64) (*Card, *Transaction, bool) {
+	for _, c := range s.cards {
+		for _, t := range c.Transactions {
+			if t.ID == tranID {
+				return c, t, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// refunded - сумма уже сделанных возвратов по транзакции (вызывать под s.mu)
+func refunded(c *Card, tr *Transaction) (Money, error) {
+	total := NewMoney(0, tr.TranSum.Currency)
+	for _, t := range c.Transactions {
+		if t.Related == tr.ID && t.TranType == TranTypeRefund && t.Status == StatusDone {
+			sum, err := total.Add(t.TranSum)
+			if err != nil {
+				return Money{}, err
+			}
+			total = sum
+		}
+	}
+	return total, nil
+}
+
+// compensate - компенсирующая транзакция: возврат amount на карту со счёта торговых точек (вызывать под s.mu)
+func (s *Service) compensate(c *Card, tr *Transaction, tranType string, amount Money) (*Transaction, error) {
+	balance, err := c.Balance.Add(amount)
+	if err != nil {
+		return nil, err
+	}
+	comp := &Transaction{ID: s.nextTransactionID(), TranType: tranType, TranSum: amount, TranDate: time.Now().Unix(),
+		MccCode: tr.MccCode, Status: StatusDone, OwnerID: c.UserID, Related: tr.ID}
+	if _, err := s.ledger.Move(comp.TranDate, tranType, MerchantsAccount(amount.Currency), CardAccount(c.ID), amount, 0, comp.ID); err != nil {
+		return nil, err
+	}
+	c.Balance = balance
+	s.refreshAvailable(c)
+	AddTransaction(c, comp)
+	s.publishTransaction(EventTransactionPosted, c, comp)
+	return comp, nil
+}
+
+// Reverse - полная отмена покупки: компенсирующая транзакция на всю сумму, исходная получает статус reversed
+func (s *Service) Reverse(ctx context.Context, tranID int64) (tr *Transaction, err error) {
+	defer func() { s.logOp(ctx, "reverse", err, "transaction_id", tranID) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, tr, ok := s.findTransaction(tranID)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	if tr.TranType != TranTypePurchase || tr.Status != StatusDone {
+		return nil, ErrNotReversible
+	}
+	done, err := refunded(c, tr)
+	if err != nil {
+		return nil, err
+	}
+	if !done.IsZero() {
+		// после частичного возврата остаток возвращается только через Refund
+		return nil, ErrNotReversible
+	}
+
+	comp, err := s.compensate(c, tr, TranTypeReversal, tr.TranSum)
+	if err != nil {
+		return nil, err
+	}
+	tr.Status = StatusReversed
+	s.publishTransaction(EventTransactionUpdated, c, tr)
+	return comp, nil
+}
+
+// Refund - возврат (в т.ч. частичный) по покупке; когда возвращена вся сумма, исходная получает статус refunded
+func (s *Service) Refund(ctx context.Context, tranID int64, amount Money) (tr *Transaction, err error) {
+	defer func() { s.logOp(ctx, "refund", err, "transaction_id", tranID, "amount", amount) }()
+	if amount.IsNegative() || amount.IsZero() {
+		return nil, ErrInvalidAmount
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, tr, ok := s.findTransaction(tranID)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	if tr.TranType != TranTypePurchase || tr.Status != StatusDone {
+		return nil, ErrNotRefundable
+	}
+	done, err := refunded(c, tr)
+	if err != nil {
+		return nil, err
+	}
+	total, err := done.Add(amount)
+	if err != nil {
+		return nil, err
+	}
+	cmp, err := total.Cmp(tr.TranSum)
+	if err != nil {
+		return nil, err
+	}
+	if cmp > 0 {
+		return nil, ErrRefundExceedsAmount
+	}
+
+	comp, err := s.compensate(c, tr, TranTypeRefund, amount)
+	if err != nil {
+		return nil, err
+	}
+	if cmp == 0 {
+		tr.Status = StatusRefunded
+		s.publishTransaction(EventTransactionUpdated, c, tr)
+	}
+	return comp, nil
+}
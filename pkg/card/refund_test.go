@@ -0,0 +1,84 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TranStatus
+	}{
+		{"done", StatusDone},
+		{"done Супермаркеты", StatusDone},
+		{"Reversed", StatusReversed},
+	}
+	for _, tt := range tests {
+		if got, err := ParseStatus(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseStatus(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"unknown", "", "  "} {
+		if _, err := ParseStatus(in); !errors.Is(err, ErrInvalidStatus) {
+			t.Errorf("ParseStatus(%q) error = %v, want %v", in, err, ErrInvalidStatus)
+		}
+	}
+}
+
+func TestService_ReverseRefund(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+
+	purchase, err := svc.Purchase(context.Background(), 1, Rub(30_00), "5411")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := svc.Refund(context.Background(), purchase.ID, Rub(10_00))
+	if err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if refund.Related != purchase.ID || refund.TranType != TranTypeRefund || purchase.Status != StatusDone {
+		t.Errorf("Refund() = %+v, purchase status %v", refund, purchase.Status)
+	}
+	if _, err := svc.Reverse(context.Background(), purchase.ID); err != ErrNotReversible {
+		t.Errorf("Reverse() after refund error = %v, want %v", err, ErrNotReversible)
+	}
+	if _, err := svc.Refund(context.Background(), purchase.ID, Rub(20_01)); err != ErrRefundExceedsAmount {
+		t.Errorf("Refund() error = %v, want %v", err, ErrRefundExceedsAmount)
+	}
+	if _, err := svc.Refund(context.Background(), purchase.ID, Rub(20_00)); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if purchase.Status != StatusRefunded {
+		t.Errorf("purchase status = %v, want %v", purchase.Status, StatusRefunded)
+	}
+
+	second, err := svc.Purchase(context.Background(), 1, Rub(50_00), "5411")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversal, err := svc.Reverse(context.Background(), second.ID)
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	if reversal.TranSum != second.TranSum || second.Status != StatusReversed {
+		t.Errorf("Reverse() = %+v, purchase status %v", reversal, second.Status)
+	}
+	if _, err := svc.Reverse(context.Background(), second.ID); err != ErrNotReversible {
+		t.Errorf("second Reverse() error = %v, want %v", err, ErrNotReversible)
+	}
+
+	c, _ := svc.SearchByID(1)
+	if c.Balance != Rub(100_00) {
+		t.Errorf("balance = %v, want %v", c.Balance, Rub(100_00))
+	}
+	if report := svc.Reconcile(); !report.OK {
+		t.Errorf("Reconcile() = %+v", report)
+	}
+	if _, err := svc.Refund(context.Background(), 999, Rub(1)); err != ErrTransactionNotFound {
+		t.Errorf("Refund() error = %v, want %v", err, ErrTransactionNotFound)
+	}
+}
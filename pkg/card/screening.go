@@ -0,0 +1,104 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrFraudDeclined = errors.New("transaction declined by fraud screening")
+
+// Outcome - решение проверки транзакции
+type Outcome string
+
+const (
+	OutcomeApprove Outcome = "approve"
+	OutcomeReview  Outcome = "review"
+	OutcomeDecline Outcome = "decline"
+)
+
+// Severity - для выбора самого строгого решения из нескольких
+func (o Outcome) Severity() int {
+	switch o {
+	case OutcomeReview:
+		return 1
+	case OutcomeDecline:
+		return 2
+	}
+	return 0
+}
+
+// Decision - решение с причинами
+type Decision struct {
+	Outcome Outcome  `json:"outcome"`
+	Reasons []string `json:"reasons"`
+}
+
+// ScreeningInput - данные для проверки новой транзакции
+type ScreeningInput struct {
+	Card        *Card
+	Transaction *Transaction   // ещё не добавлена к карте
+	History     []*Transaction // все транзакции владельца по всем его картам
+	Now         time.Time
+}
+
+// Screener - проверка транзакции до её принятия (антифрод)
+type Screener interface {
+	Screen(in ScreeningInput) Decision
+}
+
+// ScreeningAuditor - журнал решений; если Screener его реализует, Service пишет в него решение
+// после фиксации транзакции (или после отказа) и уже без своей блокировки. History во входе не передаётся
+type ScreeningAuditor interface {
+	Audit(in ScreeningInput, decision Decision)
+}
+
+// SetScreener - подключить антифрод; nil - проверка отключена
+func (s *Service) SetScreener(screener Screener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.screener = screener
+}
+
+// screening - решение по транзакции для журнала; копии карты и транзакции, чтобы читать их без s.mu
+type screening struct {
+	auditor  ScreeningAuditor
+	in       ScreeningInput
+	decision Decision
+}
+
+// screen - проверка транзакции tr по карте c (вызывать под s.mu): decline - ErrFraudDeclined,
+// review - транзакция принимается с пометкой Review. Решение возвращается и при отказе - для s.audit
+func (s *Service) screen(c *Card, tr *Transaction) (*screening, error) {
+	if s.screener == nil {
+		return nil, nil
+	}
+	history := make([]*Transaction, 0)
+	for _, v := range s.cards {
+		if v.UserID == c.UserID {
+			history = append(history, v.Transactions...)
+		}
+	}
+	now := time.Now()
+	decision := s.screener.Screen(ScreeningInput{Card: c, Transaction: tr, History: history, Now: now})
+	if decision.Outcome == OutcomeReview {
+		tr.Review = true
+	}
+	var sc *screening
+	if auditor, ok := s.screener.(ScreeningAuditor); ok {
+		trCopy := *tr
+		sc = &screening{auditor: auditor, in: ScreeningInput{Card: cardSnapshot(c), Transaction: &trCopy, Now: now}, decision: decision}
+	}
+	if decision.Outcome == OutcomeDecline {
+		return sc, fmt.Errorf("%w: %s", ErrFraudDeclined, strings.Join(decision.Reasons, "; "))
+	}
+	return sc, nil
+}
+
+// audit - запись решения в журнал; вызывать без s.mu (журнал пишет в файл), nil - записывать нечего
+func (s *Service) audit(sc *screening) {
+	if sc != nil {
+		sc.auditor.Audit(sc.in, sc.decision)
+	}
+}
@@ -0,0 +1,91 @@
+package card
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeScreener - одно решение на все транзакции; Audit запоминает, что видел сервис в момент записи
+type fakeScreener struct {
+	svc      *Service
+	outcome  Outcome
+	audited  []Decision
+	balances []Money // баланс карты на момент Audit
+}
+
+func (f *fakeScreener) Screen(in ScreeningInput) Decision {
+	return Decision{Outcome: f.outcome, Reasons: []string{"fake"}}
+}
+
+func (f *fakeScreener) Audit(in ScreeningInput, decision Decision) {
+	f.audited = append(f.audited, decision)
+	// под s.mu здесь была бы взаимоблокировка
+	c, _ := f.svc.SearchByID(in.Card.ID)
+	f.balances = append(f.balances, c.Balance)
+}
+
+func TestService_Screen(t *testing.T) {
+	tests := []struct {
+		name      string
+		outcome   Outcome
+		wantErr   error
+		review    bool
+		wantAfter Money // баланс, который видит журнал
+	}{
+		{name: "approve", outcome: OutcomeApprove, wantAfter: Rub(90_00)},
+		{name: "review", outcome: OutcomeReview, review: true, wantAfter: Rub(90_00)},
+		{name: "decline", outcome: OutcomeDecline, wantErr: ErrFraudDeclined, wantAfter: Rub(100_00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService()
+			svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+			screener := &fakeScreener{svc: svc, outcome: tt.outcome}
+			svc.SetScreener(screener)
+
+			done := make(chan struct{})
+			var tr *Transaction
+			var err error
+			go func() {
+				defer close(done)
+				tr, err = svc.Purchase(context.Background(), 1, Rub(10_00), "5411")
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Purchase() blocked: audit is written under the service lock")
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Purchase() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && tr.Review != tt.review {
+				t.Errorf("Purchase() review = %v, want %v", tr.Review, tt.review)
+			}
+			if len(screener.audited) != 1 || screener.audited[0].Outcome != tt.outcome {
+				t.Fatalf("audited = %+v, want one %s", screener.audited, tt.outcome)
+			}
+			if screener.balances[0] != tt.wantAfter {
+				t.Errorf("balance at audit = %v, want %v", screener.balances[0], tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestService_ScreenHold(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}})
+	screener := &fakeScreener{svc: svc, outcome: OutcomeReview}
+	svc.SetScreener(screener)
+
+	hold, err := svc.Authorize(context.Background(), 1, Rub(10_00), "5411", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tr, _ := svc.findTransaction(hold.TransactionID)
+	if !tr.Review || len(screener.audited) != 1 {
+		t.Errorf("hold transaction review = %v, audited = %+v", tr.Review, screener.audited)
+	}
+}
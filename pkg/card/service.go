@@ -2,6 +2,7 @@ package card
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
@@ -13,8 +14,11 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/wool/go2hw11/pkg/logging"
 )
 
 type Card struct {
@@ -23,21 +27,28 @@ type Card struct {
 	BankName     string
 	CardNumber   string
 	CardDueDate  string
-	Balance      int64
+	Balance      Money // проведённый остаток (по книге проводок)
+	Available    Money // доступный остаток: Balance минус активные холды
 	UserID       int64
 	IsVirtual    bool
+	Blocked      bool   // заблокированная карта не принимает списаний
+	Inactive     bool   // пластиковая карта до активации (ActivateCard) не принимает списаний
+	ProductID    string // продукт каталога; пусто - карта выпущена не по каталогу
 	Transactions []*Transaction
 }
 
 type Transaction struct {
-	XMLName  string `xml:"transaction"`              //
-	ID       int64  `json:"id" xml:"id"`             //
-	TranType string `json:"trantype" xml:"trantype"` //
-	TranSum  int64  `json:"transum" xml:"transum"`   //
-	TranDate int64  `json:"trandate" xml:"trandate"` //  unix timestamp
-	MccCode  string `json:"mcccode" xml:"mcccode"`   //
-	Status   string `json:"status" xml:"status"`     //
-	OwnerID  int64  `json:"ownerid" xml:"ownerid"`   //
+	XMLName  string     `xml:"transaction"`                                //
+	ID       int64      `json:"id" xml:"id"`                               //
+	TranType string     `json:"trantype" xml:"trantype"`                   //
+	TranSum  Money      `json:"transum" xml:"transum"`                     //
+	TranDate int64      `json:"trandate" xml:"trandate"`                   //  unix timestamp
+	MccCode  string     `json:"mcccode" xml:"mcccode"`                     //
+	Status   TranStatus `json:"status" xml:"status"`                       //
+	OwnerID  int64      `json:"ownerid" xml:"ownerid"`                     //
+	Fx       *FxInfo    `json:"fx,omitempty" xml:"fx,omitempty"`           // курс, если сумма пересчитана из другой валюты
+	Related  int64      `json:"related,omitempty" xml:"related,omitempty"` // для reversal/refund - ID исходной транзакции
+	Review   bool       `json:"review,omitempty" xml:"review,omitempty"`   // антифрод принял транзакцию с решением review
 }
 
 type Transactions struct {
@@ -45,19 +56,84 @@ type Transactions struct {
 	Transactions []*Transaction `xml:"transaction"`
 }
 
+// типы транзакций, которые создаёт сервис
+const (
+	TranTypePurchase = "purchase"
+	TranTypeTransfer = "transfer"
+	TranTypeRefill   = "refill"
+	TranTypeReversal = "reversal"
+	TranTypeRefund   = "refund"
+)
+
 type Service struct {
-	mu    sync.RWMutex
-	cards []*Card
+	mu     rwMutex
+	cards  []*Card
+	rates  RateProvider
+	ledger *Ledger
+
+	holds   map[int64]*Hold
+	holdSeq int64
+	limits  map[int64]Limits
+
+	screener Screener
+	events   *EventBus
+	issue    IssueSettings
+	catalog  *Catalog
+	orders   map[int64]*CardOrder // заказы пластиковых карт по ID карты
+	orderSeq int64
+	logger   *logging.Logger
+	anyUser  bool // AllowAnyUser
 }
 
 func NewService() *Service {
-	return &Service{}
+	return &Service{ledger: NewLedger(), holds: make(map[int64]*Hold), limits: make(map[int64]Limits), events: NewEventBus(DefaultEventHistory),
+		issue: DefaultIssueSettings(), catalog: DefaultCatalog(), orders: make(map[int64]*CardOrder),
+		logger: logging.Nop()}
 }
 
 func (s *Service) AddCard(card *Card) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cards = append(s.cards, card)
+	s.openAccount(card)
+}
+
+// IssueCard - выпуск новой карты пользователю по типу и платёжной системе: первый подходящий продукт каталога,
+// без такого продукта - карта по IssueSettings; публикует CardIssued
+func (s *Service) IssueCard(ctx context.Context, cardtype string, cardissuer string, userID int64, currency Currency) (issued *Card, err error) {
+	defer func() {
+		s.logOp(ctx, "issue card", err, "card_type", cardtype, "card_issuer", cardissuer, "user_id", userID, "currency", currency)
+	}()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.catalog.find(cardtype, cardissuer, currency); ok {
+		card, err := s.issueProduct(p, userID, nil, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return cardSnapshot(card), nil
+	}
+	if err := s.issue.check(cardtype, cardissuer); err != nil {
+		return nil, err
+	}
+	if cardtype == "plastic" {
+		// пластик - только заказом с доставкой (OrderCard)
+		return nil, ErrAddressRequired
+	}
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+	if err := s.issue.checkCardCap(s.cards, userID); err != nil {
+		return nil, err
+	}
+	card, err := s.issue.newCard(cardtype, cardissuer, userID, GetMaxIDFromcards(s.cards), currency)
+	if err != nil {
+		return nil, err
+	}
+	s.cards = append(s.cards, card)
+	s.openAccount(card)
+	s.publishCard(EventCardIssued, card)
+	return cardSnapshot(card), nil
 }
 
 func (s *Service) GetCards() []*Card {
@@ -66,10 +142,133 @@ func (s *Service) GetCards() []*Card {
 	return s.cards
 }
 
+// AllowAnyUser - выпускать карты любому пользователю с положительным ID, а не только владельцам карт;
+// без начальных данных (seed none) других сведений о пользователях у сервиса нет
+func (s *Service) AllowAnyUser(allow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anyUser = allow
+}
+
+// CheckUser - пользователь известен сервису: владеет картой или разрешены любые пользователи (AllowAnyUser)
+func (s *Service) CheckUser(userID int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkUser(userID)
+}
+
+// checkUser - вызывать под s.mu
+func (s *Service) checkUser(userID int64) error {
+	if s.anyUser && userID > 0 {
+		return nil
+	}
+	return CheckUserID(s.cards, userID)
+}
+
+// UserCards - копии карт пользователя с транзакциями, снятые под блокировкой: их можно сериализовать,
+// пока сервис меняет остатки; ErrNoCardWithUserID, если пользователь неизвестен (CheckUser)
+func (s *Service) UserCards(userID int64) ([]*Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cards := make([]*Card, 0)
+	for _, c := range s.cards {
+		if c.UserID != userID {
+			continue
+		}
+		cp := cardSnapshot(c)
+		for _, tr := range c.Transactions {
+			trCopy := *tr
+			cp.Transactions = append(cp.Transactions, &trCopy)
+		}
+		cards = append(cards, cp)
+	}
+	if len(cards) == 0 {
+		if err := s.checkUser(userID); err != nil {
+			return nil, err
+		}
+	}
+	return cards, nil
+}
+
 func (s *Service) SetCards(cards []*Card) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cards = cards
+	for _, card := range cards {
+		s.openAccount(card)
+	}
+}
+
+// Ledger - книга проводок, из которой выводятся балансы карт
+func (s *Service) Ledger() *Ledger {
+	return s.ledger
+}
+
+// openAccount - завести счёт новой карты и провести начальный остаток (вызывать под s.mu)
+func (s *Service) openAccount(card *Card) {
+	acc := CardAccount(card.ID)
+	if s.ledger.HasAccount(acc) {
+		return
+	}
+	s.refreshAvailable(card)
+	s.ledger.Open(acc, card.Balance.Currency)
+	if card.Balance.IsZero() {
+		return
+	}
+	if _, err := s.ledger.Move(time.Now().Unix(), "opening balance", EquityAccount(card.Balance.Currency), acc, card.Balance, 0, 0); err != nil {
+		log.Println(err)
+	}
+}
+
+// LedgerBalance - баланс карты по книге проводок
+func (s *Service) LedgerBalance(cardID int64) (Money, error) {
+	if _, ok := s.SearchByID(cardID); !ok {
+		return Money{}, ErrCardNotFound
+	}
+	return s.ledger.Balance(CardAccount(cardID)), nil
+}
+
+// Reconcile - сверка Card.Balance и истории транзакций с книгой проводок
+func (s *Service) Reconcile() ReconciliationReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report := ReconciliationReport{OK: true, Items: make([]ReconciliationItem, 0)}
+	for _, card := range s.cards {
+		item := reconcileCard(s.ledger, card)
+		if !item.OK() {
+			report.OK = false
+		}
+		if !item.OK() || len(item.Unposted) > 0 {
+			report.Items = append(report.Items, item)
+		}
+	}
+	return report
+}
+
+// SetRateProvider - источник курсов для переводов и покупок в другой валюте
+func (s *Service) SetRateProvider(rates RateProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates = rates
+}
+
+// SetLogger - журнал операций; ID запроса берётся из контекста операции
+func (s *Service) SetLogger(logger *logging.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = logger
+}
+
+// logOp - запись об операции: info при успехе, warn с ошибкой при отказе
+func (s *Service) logOp(ctx context.Context, op string, err error, kv ...interface{}) {
+	s.mu.RLock()
+	logger := s.logger
+	s.mu.RUnlock()
+	if err != nil {
+		logger.Warn(ctx, op, append(kv, "error", err)...)
+		return
+	}
+	logger.Info(ctx, op, kv...)
 }
 
 func (s *Service) SearchByNumber(number string) (*Card, bool) {
@@ -81,6 +280,151 @@ func (s *Service) SearchByNumber(number string) (*Card, bool) {
 	return nil, false
 }
 
+func (s *Service) SearchByID(id int64) (*Card, bool) {
+	for _, card := range s.GetCards() {
+		if card.ID == id {
+			return card, true
+		}
+	}
+	return nil, false
+}
+
+// Transfer - перевод между картами; сумма пересчитывается в валюты обеих карт по курсу RateProvider
+func (s *Service) Transfer(ctx context.Context, fromID int64, toID int64, amount Money) (err error) {
+	defer func() { s.logOp(ctx, "transfer", err, "from_card_id", fromID, "to_card_id", toID, "amount", amount) }()
+	from, okFrom := s.SearchByID(fromID)
+	to, okTo := s.SearchByID(toID)
+	switch {
+	case !okFrom && !okTo:
+		return ErrBothCardsNotFound
+	case !okFrom:
+		return ErrCardFromNotFound
+	case !okTo:
+		return ErrCardToNotFound
+	case from == to:
+		return ErrSameCard
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return ErrInvalidAmount
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	debit, debitFx, err := ConvertWith(s.rates, amount, from.Balance.Currency)
+	if err != nil {
+		return err
+	}
+	credit, creditFx, err := ConvertWith(s.rates, amount, to.Balance.Currency)
+	if err != nil {
+		return err
+	}
+	if err := from.usable(); err != nil {
+		return err
+	}
+	if err := s.checkAvailable(from, debit); err != nil {
+		return err
+	}
+	fromBalance, err := from.Balance.Sub(debit)
+	if err != nil {
+		return err
+	}
+	toBalance, err := to.Balance.Add(credit)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	id := s.nextTransactionID()
+	if debit.Currency == credit.Currency {
+		_, err = s.ledger.Move(now, "transfer", CardAccount(from.ID), CardAccount(to.ID), debit, id, id+1)
+	} else {
+		// разные валюты - через транзитные счета конвертации, каждая проводка сбалансирована в своей валюте;
+		// обе проводки проходят вместе, иначе списание осталось бы без зачисления
+		_, err = s.ledger.MoveAll(now, "transfer fx",
+			Leg{From: CardAccount(from.ID), To: FxAccount(debit.Currency), Amount: debit, FromTxID: id},
+			Leg{From: FxAccount(credit.Currency), To: CardAccount(to.ID), Amount: credit, ToTxID: id + 1},
+		)
+	}
+	if err != nil {
+		return err
+	}
+	from.Balance = fromBalance
+	to.Balance = toBalance
+	s.refreshAvailable(from)
+	s.refreshAvailable(to)
+	debitTr := &Transaction{ID: id, TranType: TranTypeTransfer, TranSum: debit, TranDate: now, Status: StatusDone, OwnerID: from.UserID, Fx: debitFx}
+	creditTr := &Transaction{ID: id + 1, TranType: TranTypeRefill, TranSum: credit, TranDate: now, Status: StatusDone, OwnerID: to.UserID, Fx: creditFx}
+	AddTransaction(from, debitTr)
+	AddTransaction(to, creditTr)
+	s.publishTransaction(EventTransactionPosted, from, debitTr)
+	s.publishTransaction(EventTransactionPosted, to, creditTr)
+	return nil
+}
+
+// Purchase - покупка с карты; сумма в чужой валюте пересчитывается в валюту карты
+func (s *Service) Purchase(ctx context.Context, cardID int64, amount Money, mcc string) (purchase *Transaction, err error) {
+	defer func() { s.logOp(ctx, "purchase", err, "card_id", cardID, "amount", amount, "mcc", mcc) }()
+	card, ok := s.SearchByID(cardID)
+	if !ok {
+		return nil, ErrCardNotFound
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return nil, ErrInvalidAmount
+	}
+
+	var screened *screening
+	defer func() { s.audit(screened) }() // после s.mu.Unlock: отложенные вызовы идут в обратном порядке
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	debit, fx, err := ConvertWith(s.rates, amount, card.Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := card.usable(); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(card, debit, mcc, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.checkAvailable(card, debit); err != nil {
+		return nil, err
+	}
+	balance, err := card.Balance.Sub(debit)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &Transaction{ID: s.nextTransactionID(), TranType: TranTypePurchase, TranSum: debit, TranDate: time.Now().Unix(),
+		MccCode: mcc, Status: StatusDone, OwnerID: card.UserID, Fx: fx}
+	sc, err := s.screen(card, tr)
+	if err != nil {
+		screened = sc
+		return nil, err
+	}
+	if _, err := s.ledger.Move(tr.TranDate, "purchase", CardAccount(card.ID), MerchantsAccount(debit.Currency), debit, tr.ID, 0); err != nil {
+		return nil, err
+	}
+	screened = sc
+	card.Balance = balance
+	s.refreshAvailable(card)
+	AddTransaction(card, tr)
+	s.publishTransaction(EventTransactionPosted, card, tr)
+	return tr, nil
+}
+
+// nextTransactionID - следующий свободный ID транзакции (вызывать под s.mu)
+func (s *Service) nextTransactionID() int64 {
+	var mx int64
+	for _, c := range s.cards {
+		for _, t := range c.Transactions {
+			if t.ID > mx {
+				mx = t.ID
+			}
+		}
+	}
+	return mx + 1
+}
+
 func AddTransaction(card *Card, transaction *Transaction) {
 	card.Transactions = append(card.Transactions, transaction)
 }
@@ -95,14 +439,18 @@ func valInSlice(val string, arr []string) bool {
 }
 
 // SumByMCC - функция сумм по коду mmc
-func SumByMCC(transactions []*Transaction, mcc []string) int64 {
-	var totalMcc int64
+func SumByMCC(transactions []*Transaction, mcc []string) (Money, error) {
+	var totalMcc Money
 	for _, v := range transactions {
 		if valInSlice(v.MccCode, mcc) == true {
-			totalMcc += v.TranSum
+			sum, err := totalMcc.Add(v.TranSum)
+			if err != nil {
+				return Money{}, err
+			}
+			totalMcc = sum
 		}
 	}
-	return totalMcc
+	return totalMcc, nil
 }
 
 func PrintCardTrans(c *Card) {
@@ -113,66 +461,73 @@ func PrintCardTrans(c *Card) {
 
 func SortSlice(c *Card, asc bool) {
 	if asc == true {
-		sort.SliceStable(c.Transactions, func(i, j int) bool { return c.Transactions[i].TranSum < c.Transactions[j].TranSum })
+		sort.SliceStable(c.Transactions, func(i, j int) bool { return c.Transactions[i].TranSum.Amount < c.Transactions[j].TranSum.Amount })
 	} else {
-		sort.SliceStable(c.Transactions, func(i, j int) bool { return c.Transactions[i].TranSum > c.Transactions[j].TranSum })
+		sort.SliceStable(c.Transactions, func(i, j int) bool { return c.Transactions[i].TranSum.Amount > c.Transactions[j].TranSum.Amount })
 	}
 }
 
-func Sum(transactions []int64) int64 {
-	var res int64 = 0
+func Sum(transactions []Money) (Money, error) {
+	var res Money
 	for _, v := range transactions {
-		res += v
+		sum, err := res.Add(v)
+		if err != nil {
+			return Money{}, err
+		}
+		res = sum
 	}
-	return res
+	return res, nil
 }
 
-func MakeTransMap(trans []*Transaction) map[string][]int64 {
-	var mp = make(map[string][]int64)
+// MakeTransMap - суммы транзакций по месяцам, ключ - "2020 01"
+func MakeTransMap(trans []*Transaction) map[string][]Money {
+	var mp = make(map[string][]Money)
 	for _, v := range trans {
-		var y string = strconv.Itoa(time.Unix(v.TranDate, 0).UTC().Year())
-		var m string = strconv.FormatInt(int64(time.Unix(v.TranDate, 0).UTC().Month()), 10)
-
-		var key string
-		if len(m) == 1 {
-			key = fmt.Sprintf("%s %s", y, m)
-		} else if len(m) == 2 {
-			key = fmt.Sprintf("%s 0%s", y, m)
-		}
+		date := time.Unix(v.TranDate, 0).UTC()
+		key := fmt.Sprintf("%d %02d", date.Year(), int(date.Month()))
 		mp[key] = append(mp[key], v.TranSum)
 	}
 	return mp
 }
 
-func SumConcurrently(trans []*Transaction, goroutines int) int64 {
+func SumConcurrently(trans []*Transaction, goroutines int) (Money, error) {
 	transMap := MakeTransMap(trans)
 
 	lenTM := len(transMap)
 	wg := sync.WaitGroup{}
 	wg.Add(lenTM)
 
-	total := int64(0)
-	var sumByMonths = make(map[string]int64)
+	var total Money
+	var sumByMonths = make(map[string]Money)
+	var sumErr error
 	mx := sync.Mutex{}
 
 	for i, v := range transMap {
 		yyyymm := i
 		trans := v
 		go func() {
-			sum := Sum(trans)
+			defer wg.Done()
+			sum, err := Sum(trans)
 			mx.Lock()
-			sumByMonths[yyyymm] = sum
-			total += sum
-			mx.Unlock()
-			wg.Done()
+			defer mx.Unlock()
+			if err == nil {
+				sumByMonths[yyyymm] = sum
+				total, err = total.Add(sum)
+			}
+			if err != nil && sumErr == nil {
+				sumErr = err
+			}
 		}()
 	}
 	wg.Wait()
+	if sumErr != nil {
+		return Money{}, sumErr
+	}
 	for k, v := range sumByMonths {
-		fmt.Printf("%v : %d\n", k, v)
+		fmt.Printf("%v : %v\n", k, v)
 	}
 	fmt.Println(sumByMonths)
-	return total
+	return total, nil
 }
 
 /*
@@ -219,24 +574,82 @@ func DiviveTranSlcToParts(tr []*Transaction, NumberOfParts int64) map[int64][]*T
 	return mp
 }
 
+// SpendingKey - категория MCC в одной валюте: суммы разных валют не складываются
+type SpendingKey struct {
+	Category string
+	Currency Currency
+}
+
+// Spending - траты по категориям в минимальных единицах валюты ключа
+type Spending map[SpendingKey]int64
+
+// Money - сумма по ключу вместе с валютой
+func (s Spending) Money(key SpendingKey) Money {
+	return NewMoney(s[key], key.Currency)
+}
+
+// Keys - ключи по валюте, внутри валюты - по убыванию суммы, при равенстве - по названию
+func (s Spending) Keys() []SpendingKey {
+	keys := make([]SpendingKey, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.Currency != b.Currency:
+			return a.Currency < b.Currency
+		case s[a] != s[b]:
+			return s[a] > s[b]
+		}
+		return a.Category < b.Category
+	})
+	return keys
+}
+
+// Merge - прибавляет траты other; ErrAmountOverflow, если сумма не помещается в int64
+func (s Spending) Merge(other Spending) error {
+	for key, amount := range other {
+		if err := s.add(key, amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Spending) add(key SpendingKey, amount int64) error {
+	sum, err := addInt64(s[key], amount)
+	if err != nil {
+		return fmt.Errorf("%w: %s, %s", err, key.Category, key.Currency)
+	}
+	s[key] = sum
+	return nil
+}
+
+// spendingKey - категория и валюта транзакции
+func spendingKey(t *Transaction) SpendingKey {
+	return SpendingKey{Category: TranslateMCC(t.MccCode), Currency: t.TranSum.Currency}
+}
+
 // F1 - сумма в лоб
-func F1(tr []*Transaction, ownerID int64) map[string]int64 {
-	mp := make(map[string]int64)
+func F1(tr []*Transaction, ownerID int64) (Spending, error) {
+	result := make(Spending)
 	for _, v := range tr {
-		//fmt.Printf("code %v, code name %v, transum %d \n", v.MccCode, TranslateMCC(v.MccCode), v.TranSum)
 		if v.OwnerID == ownerID {
-			mp[TranslateMCC(v.MccCode)] += v.TranSum
+			if err := result.add(spendingKey(v), v.TranSum.Amount); err != nil {
+				return nil, err
+			}
 		}
 	}
-	//fmt.Println(mp)
-	return mp
+	return result, nil
 }
 
 // F2 - сумма конкурентно через мьютексы
-func F2(tr []*Transaction, ownerID int64) map[string]int64 {
+func F2(tr []*Transaction, ownerID int64) (Spending, error) {
 	wg := sync.WaitGroup{}
 	mu := sync.Mutex{}
-	result := make(map[string]int64)
+	result := make(Spending)
+	var firstErr error
 
 	transSplit := DiviveTranSlcToParts(tr, 100)
 
@@ -244,19 +657,24 @@ func F2(tr []*Transaction, ownerID int64) map[string]int64 {
 		wg.Add(1)
 		part := v
 		go func() {
-			m := F1(part, ownerID) // Categorize(part)
+			defer wg.Done()
+			m, err := F1(part, ownerID) // Categorize(part)
 			mu.Lock()
-			// TODO: вы перекладываете данные из m в result
-			// TODO: подсказка - сделайте цикл по одной из map и смотрите, есть ли такие ключи в другой, если есть - прибавляйте
-			for k, v := range m {
-				result[k] += v
+			defer mu.Unlock()
+			if err == nil {
+				// TODO: вы перекладываете данные из m в result
+				err = result.Merge(m)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
 			}
-			mu.Unlock()
-			wg.Done()
 		}()
 	}
 	wg.Wait()
-	return result
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
 }
 
 /*
@@ -266,33 +684,41 @@ func F2(tr []*Transaction, ownerID int64) map[string]int64 {
 */
 
 // F3 - конкуретноый подсчет через каналы
-func F3(tr []*Transaction, ownerID int64) map[string]int64 {
-	result := make(map[string]int64)
-	ch := make(chan map[string]int64)
+func F3(tr []*Transaction, ownerID int64) (Spending, error) {
+	type partResult struct {
+		spending Spending
+		err      error
+	}
+	result := make(Spending)
+	ch := make(chan partResult)
 
 	transSplit := DiviveTranSlcToParts(tr, 100)
 
 	for _, v := range transSplit { // TODO здесь ваши условия разделения
 		part := v // transactions[x:y]
-		go func(ch chan<- map[string]int64) {
-			ch <- F1(part, ownerID) //Categorize(part)
+		go func(ch chan<- partResult) {
+			m, err := F1(part, ownerID) //Categorize(part)
+			ch <- partResult{spending: m, err: err}
 		}(ch)
 	}
 
-	partsCount := len(transSplit)
-	finished := 0
-	for value := range ch { // range result
-		// TODO: вы перекладываете данные из m в result
-		// TODO: подсказка - сделайте цикл по одной из map и смотрите, есть ли такие ключи в другой, если есть - прибавляйте
-		for k, v := range value {
-			result[k] += v
+	// читаем все части и после ошибки, чтобы не оставить горутины висеть на канале
+	var firstErr error
+	for range transSplit {
+		value := <-ch
+		err := value.err
+		if err == nil {
+			// TODO: вы перекладываете данные из m в result
+			err = result.Merge(value.spending)
 		}
-		finished++
-		if finished == partsCount {
-			break
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
-	return result
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
 }
 
 /*
@@ -301,10 +727,11 @@ F4) Функция с mutex'ом, который защищает любые о
 Важно: эта функция внутри себя не должна вызывать функцию из п.1
 */
 // F4 -
-func F4(tr []*Transaction, ownerID int64) map[string]int64 {
+func F4(tr []*Transaction, ownerID int64) (Spending, error) {
 	wg := sync.WaitGroup{}
 	mu := sync.Mutex{}
-	result := make(map[string]int64)
+	result := make(Spending)
+	var firstErr error
 
 	transSplit := DiviveTranSlcToParts(tr, 100)
 
@@ -312,21 +739,26 @@ func F4(tr []*Transaction, ownerID int64) map[string]int64 {
 		wg.Add(1)
 		part := v //transactions[x:y]
 		go func() {
+			defer wg.Done()
 			for _, t := range part {
 				// TODO: 1. берём конкретную транзакцию
 				// TODO: 2. смотрим, подходит ли по id владельца
 				if t.OwnerID == ownerID {
 					mu.Lock()
 					// TODO: 3. если подходит, то закидываем в общий `map`
-					result[TranslateMCC(t.MccCode)] += t.TranSum
+					if err := result.add(spendingKey(t), t.TranSum.Amount); err != nil && firstErr == nil {
+						firstErr = err
+					}
 					mu.Unlock()
 				}
 			}
-			wg.Done()
 		}()
 	}
 	wg.Wait()
-	return result
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
 }
 
 /*
@@ -339,10 +771,10 @@ func F4(tr []*Transaction, ownerID int64) map[string]int64 {
 1) Поля:
 ID       int64
 TranType string
-TranSum  int64
+TranSum  Money (сумма в колонке 2, валюта - в последней колонке 7)
 TranDate int64 // unix timestamp
 MccCode  string
-Status   string
+Status   TranStatus
 OwnerID  int64
 
 2) План реализации:
@@ -350,31 +782,79 @@ OwnerID  int64
 
 */
 
-func MapRowToTransaction(s [][]string) []*Transaction {
+// csvMinColumns - ID, тип, сумма, дата, MCC, статус, владелец; восьмая колонка - валюта
+const csvMinColumns = 7
+
+// MapRowToTransaction - строки CSV в транзакции; поле, которое не разбирается, - ErrInvalidStatement с номером строки
+func MapRowToTransaction(s [][]string) ([]*Transaction, error) {
 	trans := make([]*Transaction, 0)
-	for _, v := range s {
-		id2, _ := strconv.ParseInt(v[0], 10, 64)
-		transum2, _ := strconv.ParseInt(v[2], 10, 64)
+	for i, v := range s {
+		line := i + 1
+		invalid := func(column string, err error) error {
+			return fmt.Errorf("%w: line %d: %s: %v", ErrInvalidStatement, line, column, err)
+		}
+		if len(v) < csvMinColumns {
+			return nil, fmt.Errorf("%w: line %d has %d columns, want at least %d", ErrInvalidStatement, line, len(v), csvMinColumns)
+		}
+		id2, err := strconv.ParseInt(v[0], 10, 64)
+		if err != nil {
+			return nil, invalid("id", err)
+		}
+		currency := ""
+		if len(v) > 7 {
+			currency = v[7]
+		}
+		transum2, err := MoneyFromCSV(v[2], currency)
+		if err != nil {
+			return nil, invalid("amount", err)
+		}
 
-		layout := "2006-01-02 15:04:05 +0300 MSK"
-		trandate2, _ := time.Parse(layout, v[3]) //"2014-11-12T11:45:26.371Z"
+		// формат time.Time.String() без монотонной части: "2020-01-01 00:00:00 +0300 MSK"
+		layout := "2006-01-02 15:04:05 -0700 MST"
+		trandate2, err := time.Parse(layout, v[3]) //"2014-11-12T11:45:26.371Z"
+		if err != nil {
+			return nil, invalid("date", err)
+		}
 		trandate3 := trandate2.Unix()
 
-		owner2, _ := strconv.ParseInt(v[6], 10, 64)
+		owner2, err := strconv.ParseInt(v[6], 10, 64)
+		if err != nil {
+			return nil, invalid("owner", err)
+		}
+		status2 := StatusDone // пустой статус - старая выгрузка, см. defaultStatus
+		if strings.TrimSpace(v[5]) != "" {
+			if status2, err = ParseStatus(v[5]); err != nil {
+				return nil, invalid("status", err)
+			}
+		}
 
 		tr := &Transaction{
-			"",
-			id2,       //ID       int64
-			v[1],      //TranType string
-			transum2,  //TranSum  int64
-			trandate3, //TranDate int64 // unix timestamp
-			v[4],      //MccCode  string
-			v[5],      //Status   string
-			owner2,    //OwnerID  int64
+			ID:       id2,       //ID       int64
+			TranType: v[1],      //TranType string
+			TranSum:  transum2,  //TranSum  Money
+			TranDate: trandate3, //TranDate int64 // unix timestamp
+			MccCode:  v[4],      //MccCode  string
+			Status:   status2,   //Status   TranStatus
+			OwnerID:  owner2,    //OwnerID  int64
 		}
 		trans = append(trans, tr)
 	}
-	return trans
+	return trans, nil
+}
+
+// csvRecord - строка CSV в порядке колонок MapRowToTransaction; сумма и валюта из Money.CSVFields, валюта - последней
+func csvRecord(t *Transaction) []string {
+	sum := t.TranSum.CSVFields()
+	return []string{
+		strconv.FormatInt(t.ID, 10),
+		t.TranType,
+		sum[0],
+		time.Unix(t.TranDate, 0).String(), // TranDate
+		t.MccCode,
+		string(t.Status),
+		strconv.FormatInt(t.OwnerID, 10),
+		sum[1],
+	}
 }
 
 func ExportToCSV(tr []*Transaction, exportPath string) error {
@@ -384,16 +864,7 @@ func ExportToCSV(tr []*Transaction, exportPath string) error {
 
 	records := make([][]string, 0)
 	for _, v := range tr {
-		record := []string{
-			strconv.FormatInt(v.ID, 10),
-			v.TranType,
-			strconv.FormatInt(v.TranSum, 10),
-			time.Unix(v.TranDate, 0).String(), // TranDate
-			v.MccCode,
-			v.Status,
-			strconv.FormatInt(v.OwnerID, 10),
-		}
-		records = append(records, record)
+		records = append(records, csvRecord(v))
 	}
 
 	file, err := os.Create(exportPath)
@@ -414,35 +885,29 @@ func ExportToCSV(tr []*Transaction, exportPath string) error {
 }
 
 func ImportFromCSV(importPath string) ([]*Transaction, error) {
-	file, err := os.Open(importPath)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-	defer func(c io.Closer) {
-		if cerr := c.Close(); cerr != nil {
-			log.Println(cerr)
-		}
-	}(file)
+	return importFile(importPath, ReadCSV)
+}
 
-	reader := csv.NewReader(file)
+// ReadCSV - транзакции из CSV в формате ExportToCSV/MakeCSV (колонка валюты необязательна)
+func ReadCSV(r io.Reader) ([]*Transaction, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
 	records := make([][]string, 0)
 	for {
 		record, err := reader.Read()
-		if err != nil {
-			if err != io.EOF {
-				log.Println(err)
-				return nil, err
-			}
-			//records = append(records, record)   <-- нужно ли прикреплять EOF к концу слайса? наверно нет ...
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < csvMinColumns {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%w: line %d has %d columns, want at least %d", ErrInvalidStatement, line, len(record), csvMinColumns)
+		}
 		records = append(records, record)
 	}
-
-	transConv := MapRowToTransaction(records)
-
-	return transConv, nil
+	return MapRowToTransaction(records)
 }
 
 func ExporttoJSON(tr []*Transaction, exportPath string) error {
@@ -463,29 +928,16 @@ func ExporttoJSON(tr []*Transaction, exportPath string) error {
 }
 
 func ImportFromJSON(importPath string) ([]*Transaction, error) {
-	file, err := os.Open(importPath)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-	defer func(c io.Closer) {
-		if cerr := c.Close(); cerr != nil {
-			log.Println(cerr)
-		}
-	}(file)
+	return importFile(importPath, ReadJSON)
+}
 
-	content, err := ioutil.ReadFile(importPath)
-	if err != nil {
-		log.Fatal(err)
-		return nil, err
-	}
+// ReadJSON - транзакции из JSON-массива в формате ExporttoJSON/MakeJSON
+func ReadJSON(r io.Reader) ([]*Transaction, error) {
 	var decoded []*Transaction
-	err = json.Unmarshal(content, &decoded) // важно: передаём указатель
-	if err != nil {
-		log.Println(err)
-		return nil, err
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil { // важно: передаём указатель
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
 	}
-	//log.Println(reflect.TypeOf(decoded), decoded)
+	defaultStatus(decoded)
 	return decoded, nil
 }
 
@@ -510,6 +962,21 @@ func ExportXML(tr []*Transaction, exportPath string) error {
 }
 
 func ImportXML(importPath string) ([]*Transaction, error) {
+	return importFile(importPath, ReadXML)
+}
+
+// ReadXML - транзакции из XML в формате ExportXML/MakeXML
+func ReadXML(r io.Reader) ([]*Transaction, error) {
+	var decoded Transactions
+	if err := xml.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	defaultStatus(decoded.Transactions)
+	return decoded.Transactions, nil
+}
+
+// importFile - открыть файл и прочитать транзакции функцией read
+func importFile(importPath string, read func(io.Reader) ([]*Transaction, error)) ([]*Transaction, error) {
 	file, err := os.Open(importPath)
 	if err != nil {
 		log.Println(err)
@@ -521,21 +988,11 @@ func ImportXML(importPath string) ([]*Transaction, error) {
 		}
 	}(file)
 
-	content, err := ioutil.ReadFile(importPath)
-	if err != nil {
-		log.Fatal(err)
-		return nil, err
-	}
-	//
-	var decoded Transactions
-	err = xml.Unmarshal(content, &decoded)
+	trans, err := read(file)
 	if err != nil {
-		log.Fatal(err)
-		return nil, err
+		log.Println(err)
+		return nil, fmt.Errorf("%s: %w", importPath, err)
 	}
-	log.Printf("%#v", decoded)
-	trans := decoded.Transactions
-
 	return trans, nil
 }
 
@@ -546,16 +1003,7 @@ func MakeCSV(tr []*Transaction) ([]byte, error) {
 	buf := &bytes.Buffer{} // делать через буфер
 	w := csv.NewWriter(buf)
 	for _, v := range tr {
-		record := []string{
-			strconv.FormatInt(v.ID, 10),
-			v.TranType,
-			strconv.FormatInt(v.TranSum, 10),
-			time.Unix(v.TranDate, 0).String(), // TranDate
-			v.MccCode,
-			v.Status,
-			strconv.FormatInt(v.OwnerID, 10),
-		}
-		if err := w.Write(record); err != nil {
+		if err := w.Write(csvRecord(v)); err != nil {
 			return nil, err
 		}
 	}
@@ -591,21 +1039,21 @@ func MakeXML(tr []*Transaction) ([]byte, error) {
 
 // InitCard - go2hw9 - для инициализации карты с транзакциями (для жкспорта из webapp)
 func InitCard() *Card {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01",
+	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01",
 		Transactions: []*Transaction{
-			&Transaction{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: 1735_55, TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done Супермаркеты"},
-			&Transaction{ID: 2, TranType: "purchase", OwnerID: 2, TranSum: 2000_00, TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done"},
-			&Transaction{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: 1203_91, TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done Рестораны"},
-			&Transaction{ID: 4, TranType: "purchase", OwnerID: 2, TranSum: 3562_21, TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: ""},
-			&Transaction{ID: 5, TranType: "purchase", OwnerID: 2, TranSum: 1111_11, TranDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: ""},
-			&Transaction{ID: 6, TranType: "purchase", OwnerID: 2, TranSum: 2222_22, TranDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: ""},
-			&Transaction{ID: 7, TranType: "purchase", OwnerID: 2, TranSum: 6666_66, TranDate: time.Date(2020, 4, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "3333", Status: ""},
-			&Transaction{ID: 8, TranType: "purchase", OwnerID: 2, TranSum: 4444_44, TranDate: time.Date(2020, 4, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "3333", Status: ""},
-			&Transaction{ID: 9, TranType: "purchase", OwnerID: 2, TranSum: 5555_55, TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: ""},
-			&Transaction{ID: 10, TranType: "purchase", OwnerID: 2, TranSum: 3333_33, TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: ""},
-			&Transaction{ID: 11, TranType: "purchase", OwnerID: 2, TranSum: 3333_33, TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: ""},
-			&Transaction{ID: 12, TranType: "purchase", OwnerID: 2, TranSum: 3333_33, TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: ""},
-			&Transaction{ID: 13, TranType: "purchase", OwnerID: 2, TranSum: 3333_33, TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: ""},
+			&Transaction{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: Rub(1735_55), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+			&Transaction{ID: 2, TranType: "purchase", OwnerID: 2, TranSum: Rub(2000_00), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+			&Transaction{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: Rub(1203_91), TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+			&Transaction{ID: 4, TranType: "purchase", OwnerID: 2, TranSum: Rub(3562_21), TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: StatusDone},
+			&Transaction{ID: 5, TranType: "purchase", OwnerID: 2, TranSum: Rub(1111_11), TranDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: StatusDone},
+			&Transaction{ID: 6, TranType: "purchase", OwnerID: 2, TranSum: Rub(2222_22), TranDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "1111", Status: StatusDone},
+			&Transaction{ID: 7, TranType: "purchase", OwnerID: 2, TranSum: Rub(6666_66), TranDate: time.Date(2020, 4, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "3333", Status: StatusDone},
+			&Transaction{ID: 8, TranType: "purchase", OwnerID: 2, TranSum: Rub(4444_44), TranDate: time.Date(2020, 4, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "3333", Status: StatusDone},
+			&Transaction{ID: 9, TranType: "purchase", OwnerID: 2, TranSum: Rub(5555_55), TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: StatusDone},
+			&Transaction{ID: 10, TranType: "purchase", OwnerID: 2, TranSum: Rub(3333_33), TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+			&Transaction{ID: 11, TranType: "purchase", OwnerID: 2, TranSum: Rub(3333_33), TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: StatusDone},
+			&Transaction{ID: 12, TranType: "purchase", OwnerID: 2, TranSum: Rub(3333_33), TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: StatusDone},
+			&Transaction{ID: 13, TranType: "purchase", OwnerID: 2, TranSum: Rub(3333_33), TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
 		},
 	}
 	return card1
@@ -621,6 +1069,11 @@ var (
 	ErrBothCardsNotFound             = errors.New("CardFrom and CardTo not found")
 	ErrCardFromNotFound              = errors.New("CardFrom not found")
 	ErrCardToNotFound                = errors.New("CardTo not found")
+	ErrCardNotFound                  = errors.New("Card not found")
+	ErrInvalidAmount                 = errors.New("Amount must be positive")
+	ErrInvalidStatement              = errors.New("Transaction statement is malformed")
+	ErrSameCard                      = errors.New("CardFrom and CardTo are the same card")
+	ErrCardBlocked                   = errors.New("Card is blocked")
 	ErrInvalidCardFromNumber         = errors.New("CardFrom number is not valid")
 	ErrInvalidCardToNumber           = errors.New("CardTo number is not valid")
 
@@ -631,18 +1084,18 @@ var (
 
 func InitCardsHW11() []*Card {
 	allCards := make([]*Card, 0)
-	card11 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 1}
-	card12 := &Card{ID: 2, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 1}
+	card11 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 1}
+	card12 := &Card{ID: 2, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 1}
 
-	card21 := &Card{ID: 3, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 2}
-	card22 := &Card{ID: 4, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 2}
-	card23 := &Card{ID: 5, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 2}
+	card21 := &Card{ID: 3, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 2}
+	card22 := &Card{ID: 4, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 2}
+	card23 := &Card{ID: 5, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 2}
 
-	card31 := &Card{ID: 6, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 3}
-	card32 := &Card{ID: 7, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 3}
-	card33 := &Card{ID: 8, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 3}
+	card31 := &Card{ID: 6, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 3}
+	card32 := &Card{ID: 7, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 3}
+	card33 := &Card{ID: 8, Type: "Visa", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 3}
 
-	card41 := &Card{ID: 9, Type: "UnionPay", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01", UserID: 4}
+	card41 := &Card{ID: 9, Type: "UnionPay", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: Rub(20_000_00), CardDueDate: "2030-01-01", UserID: 4}
 
 	allCards = append(allCards, card11, card12, card21, card22, card23, card31, card32, card33, card41)
 	//
@@ -699,8 +1152,9 @@ func CheckUserID(crds []*Card, uid int64) error {
 	}
 }
 
+// GetMaxIDFromcards - ID для новой карты; без карт - 1
 func GetMaxIDFromcards(crds []*Card) int64 {
-	var newmxid int64 = crds[0].ID
+	var newmxid int64
 	for _, v := range crds {
 		if v.ID > newmxid {
 			newmxid = v.ID
@@ -710,20 +1164,11 @@ func GetMaxIDFromcards(crds []*Card) int64 {
 	return newmxid + 1
 }
 
-func AddParamCardToCardslice(crds []*Card, cardtype string, cardissuer string, userid int64, cardID int64) []*Card {
-	if cardtype == "plastic" {
-		c := &Card{
-			ID: cardID, Type: cardissuer, BankName: "Tinkoff", CardNumber: "0000 0000 0000 0000",
-			Balance: 0, CardDueDate: "2030-01-01", UserID: userid, IsVirtual: false,
-		}
-		crds = append(crds, c)
-	}
-	if cardtype == "virtual" {
-		c := &Card{
-			ID: cardID, Type: cardissuer, BankName: "Tinkoff", CardNumber: "0000 0000 0000 0000",
-			Balance: 0, CardDueDate: "2030-01-01", UserID: userid, IsVirtual: true,
+func AddParamCardToCardslice(crds []*Card, cardtype string, cardissuer string, userid int64, cardID int64, currency Currency) []*Card {
+	if _, ok := Find(CardTypes, cardtype); ok {
+		if card, err := DefaultIssueSettings().newCard(cardtype, cardissuer, userid, cardID, currency); err == nil {
+			crds = append(crds, card)
 		}
-		crds = append(crds, c)
 	}
 	return crds
 }
@@ -1,17 +1,24 @@
 package card
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestMapRowToTransaction(t *testing.T) {
 	trans := []*Transaction{
-		&Transaction{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: 1735_55, TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done Супермаркеты"},
-		&Transaction{ID: 2, TranType: "purchase", OwnerID: 2, TranSum: 2000_00, TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done"},
-		&Transaction{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: 1203_91, TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done Рестораны"},
+		&Transaction{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: Rub(1735_55), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+		&Transaction{ID: 2, TranType: "purchase", OwnerID: 2, TranSum: Rub(2000_00), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
+		&Transaction{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: Rub(1203_91), TranDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: StatusDone},
 	}
 	transFromImport := [][]string{
 		{"1", "purchase", "173555", "2020-01-01 00:00:00 +0300 MSK", "5411", "done", "Супермаркеты", "2"},
@@ -23,228 +30,179 @@ func TestMapRowToTransaction(t *testing.T) {
 	type args struct {
 		s [][]string
 	}
+	valid := []string{"1", "purchase", "173555", "2020-01-01 00:00:00 +0000 UTC", "5411", "done", "2", "RUB"}
+	row := func(column int, value string) []string {
+		r := append([]string(nil), valid...)
+		r[column] = value
+		return r
+	}
 	tests := []struct {
-		name string
-		args args
-		want []*Transaction
+		name    string
+		args    args
+		want    []*Transaction
+		wantErr string // подстрока ошибки ErrInvalidStatement
 	}{
-		// TODO: Add test cases.
+		{name: "valid", args: args{s: [][]string{valid}}, want: []*Transaction{
+			{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: Rub(1735_55), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), MccCode: "5411", Status: StatusDone},
+		}},
+		{name: "bad id", args: args{s: [][]string{valid, row(0, "one")}}, wantErr: "line 2: id"},
+		{name: "bad amount", args: args{s: [][]string{row(2, "17.35")}}, wantErr: "line 1: amount"},
+		{name: "bad currency", args: args{s: [][]string{row(7, "XXX")}}, wantErr: "line 1: amount"},
+		{name: "bad date", args: args{s: [][]string{row(3, "2020-01-01")}}, wantErr: "line 1: date"},
+		{name: "bad status", args: args{s: [][]string{row(5, "lost")}}, wantErr: "line 1: status"},
+		{name: "bad owner", args: args{s: [][]string{row(6, "")}}, wantErr: "line 1: owner"},
+		{name: "short row", args: args{s: [][]string{valid[:5]}}, wantErr: "line 1 has 5 columns"},
 	}
 	for _, tt := range tests {
-		if got := MapRowToTransaction(tt.args.s); !reflect.DeepEqual(got, tt.want) {
-			t.Errorf("MapRowToTransaction() = %v, want %v", got, tt.want)
+		got, err := MapRowToTransaction(tt.args.s)
+		if tt.wantErr != "" {
+			if !errors.Is(err, ErrInvalidStatement) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: MapRowToTransaction() error = %v, want %v with %q", tt.name, err, ErrInvalidStatement, tt.wantErr)
+			}
+			continue
 		}
-	}
-}
-
-/*
-// MakeTransactions - конструирование транзакций к карте по шаблону
-func MakeTransactions() []*Transaction {
-	const users = 10_000
-	const transactionsPerUser = 10_000
-	const transactionAmount = 1_00
-	transactions := make([]*Transaction, users*transactionsPerUser)
-	for index := range transactions {
-		switch index % 100 {
-		case 0:
-			// Например, каждая 100-ая транзакция в банке от нашего юзера в категории такой-то (OwnerID = 2)
-			transactions[index] = &Transaction{ID: int64(index), TranType: "purchase", OwnerID: 2, TranSum: transactionAmount, //  * int64(index)
-				TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5411", Status: "done"}
-		case 20:
-			// Например, каждая 120-ая транзакция в банке от нашего юзера в категории такой-то (OwnerID = 2)
-			transactions[index] = &Transaction{ID: int64(index), TranType: "purchase", OwnerID: 2, TranSum: transactionAmount,
-				TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "5555", Status: "done"}
-		default:
-			// Транзакции других юзеров, нужны для "общей" массы
-			transactions[index] = &Transaction{ID: int64(index), TranType: "purchase", OwnerID: int64(index), TranSum: transactionAmount,
-				TranDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local).Unix(), MccCode: "3333", Status: "done"}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: MapRowToTransaction() = %v, %v, want %v", tt.name, got, err, tt.want)
 		}
 	}
-	return transactions
 }
 
-func TestF1(t *testing.T) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	wantMap := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-
-	type args struct {
-		tr      []*Transaction
-		ownerID int64
+func TestReadStatement(t *testing.T) {
+	trans := []*Transaction{
+		{ID: 1, TranType: "purchase", OwnerID: 2, TranSum: Rub(1735_55), TranDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), MccCode: "5411", Status: StatusDone},
+		{ID: 2, TranType: "refund", OwnerID: 2, TranSum: NewMoney(12_34, EUR), TranDate: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC).Unix(), MccCode: "5912", Status: StatusDone},
 	}
 	tests := []struct {
 		name string
-		args args
-		want map[string]int64
+		make func([]*Transaction) ([]byte, error)
+		read func(r io.Reader) ([]*Transaction, error)
 	}{
-		{
-			name: "Test F1 with made trans",
-			args: args{tr: card1.Transactions, ownerID: 2},
-			want: wantMap,
-		},
+		{name: "csv", make: MakeCSV, read: ReadCSV},
+		{name: "json", make: MakeJSON, read: ReadJSON},
+		{name: "xml", make: MakeXML, read: ReadXML},
 	}
 	for _, tt := range tests {
-		if got := F1(tt.args.tr, tt.args.ownerID); !reflect.DeepEqual(got, tt.want) {
-			t.Errorf("F1() = %v, want %v", got, tt.want)
-		}
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.make(trans)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.read(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, trans) {
+				t.Errorf("read() = %v, want %v", got, trans)
+			}
+		})
 	}
-}
-
-func TestF2(t *testing.T) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
 
-	wantMap := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-
-	type args struct {
-		tr      []*Transaction
-		ownerID int64
-	}
-	tests := []struct {
+	malformed := []struct {
 		name string
-		args args
-		want map[string]int64
+		read func(r io.Reader) ([]*Transaction, error)
+		data string
 	}{
-		{
-			name: "Test F2 with made trans",
-			args: args{tr: card1.Transactions, ownerID: 2},
-			want: wantMap,
-		},
-	}
-	for _, tt := range tests {
-		if got := F2(tt.args.tr, tt.args.ownerID); !reflect.DeepEqual(got, tt.want) {
-			t.Errorf("F2() = %v, want %v", got, tt.want)
+		{name: "csv short row", read: ReadCSV, data: "1,purchase,100\n"},
+		{name: "csv bad date", read: ReadCSV, data: "1,purchase,100,yesterday,5411,done,2\n"},
+		{name: "json", read: ReadJSON, data: `[{"id": "one"}]`},
+		{name: "xml", read: ReadXML, data: "<transactions><transaction>"},
+	}
+	for _, tt := range malformed {
+		if _, err := tt.read(strings.NewReader(tt.data)); !errors.Is(err, ErrInvalidStatement) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, ErrInvalidStatement)
 		}
 	}
 }
 
-func TestF3(t *testing.T) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	wantMap := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-
-	type args struct {
-		tr      []*Transaction
-		ownerID int64
+// TestReadStatement_Legacy - выгрузки до появления валют и статусов: у транзакций 4-13 из InitCard статус пустой
+func TestReadStatement_Legacy(t *testing.T) {
+	date := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	want := []*Transaction{
+		{ID: 3, TranType: "purchase", OwnerID: 2, TranSum: Rub(1203_91), TranDate: date.Unix(), MccCode: "5411", Status: StatusDone},
+		{ID: 4, TranType: "purchase", OwnerID: 2, TranSum: Rub(3562_21), TranDate: date.Unix(), MccCode: "1111", Status: StatusDone},
 	}
 	tests := []struct {
 		name string
-		args args
-		want map[string]int64
+		read func(r io.Reader) ([]*Transaction, error)
+		data string
 	}{
-		{
-			name: "Test F3 with made trans",
-			args: args{tr: card1.Transactions, ownerID: 2},
-			want: wantMap,
-		},
+		{name: "csv", read: ReadCSV, data: "3,purchase,120391,2020-02-01 00:00:00 +0000 UTC,5411,done Рестораны,2\n" +
+			"4,purchase,356221,2020-02-01 00:00:00 +0000 UTC,1111,,2\n"},
+		{name: "json", read: ReadJSON, data: fmt.Sprintf(`[{"XMLName":"","id":3,"trantype":"purchase","transum":120391,"trandate":%[1]d,"mcccode":"5411","status":"done Рестораны","ownerid":2},`+
+			`{"XMLName":"","id":4,"trantype":"purchase","transum":356221,"trandate":%[1]d,"mcccode":"1111","status":"","ownerid":2}]`, date.Unix())},
+		{name: "xml", read: ReadXML, data: fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<transactions>`+
+			`<transaction><id>3</id><trantype>purchase</trantype><transum>120391</transum><trandate>%[1]d</trandate><mcccode>5411</mcccode><status>done Рестораны</status><ownerid>2</ownerid></transaction>`+
+			`<transaction><id>4</id><trantype>purchase</trantype><transum>356221</transum><trandate>%[1]d</trandate><mcccode>1111</mcccode><status></status><ownerid>2</ownerid></transaction>`+
+			`</transactions>`, date.Unix())},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := F3(tt.args.tr, tt.args.ownerID); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("F3() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestF4(t *testing.T) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	wantMap := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-
-	type args struct {
-		tr      []*Transaction
-		ownerID int64
-	}
-	tests := []struct {
-		name string
-		args args
-		want map[string]int64
-	}{
-		{
-			name: "Test F4 with made trans",
-			args: args{tr: card1.Transactions, ownerID: 2},
-			want: wantMap,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := F4(tt.args.tr, tt.args.ownerID); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("F4() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-// go test -bench=. -benchtime=3x ./...
-// benchmark F1
-func BenchmarkF1(b *testing.B) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	want := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-	b.ResetTimer() // сбрасываем таймер, т.к. сама генерация транзакций достаточно ресурсоёмка
-	for i := 0; i < b.N; i++ {
-		result := F1(card1.Transactions, 2)
-		b.StopTimer() // останавливаем таймер, чтобы время сравнения не учитывалось
-		if !reflect.DeepEqual(result, want) {
-			b.Fatalf("invalid result, got %v, want %v", result, want)
+		got, err := tt.read(strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: read() error = %v", tt.name, err)
+			continue
 		}
-		b.StartTimer() // продолжаем работу таймера
-	}
-}
-
-// benchmark F2
-func BenchmarkF2(b *testing.B) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	want := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-	b.ResetTimer() // сбрасываем таймер, т.к. сама генерация транзакций достаточно ресурсоёмка
-	for i := 0; i < b.N; i++ {
-		result := F2(card1.Transactions, 2)
-		b.StopTimer() // останавливаем таймер, чтобы время сравнения не учитывалось
-		if !reflect.DeepEqual(result, want) {
-			b.Fatalf("invalid result, got %v, want %v", result, want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: read() = %v, want %v", tt.name, got, want)
 		}
-		b.StartTimer() // продолжаем работу таймера
 	}
 }
 
-// benchmark F3
-func BenchmarkF3(b *testing.B) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	want := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-	b.ResetTimer() // сбрасываем таймер, т.к. сама генерация транзакций достаточно ресурсоёмка
-	for i := 0; i < b.N; i++ {
-		result := F3(card1.Transactions, 2)
-		b.StopTimer() // останавливаем таймер, чтобы время сравнения не учитывалось
-		if !reflect.DeepEqual(result, want) {
-			b.Fatalf("invalid result, got %v, want %v", result, want)
-		}
-		b.StartTimer() // продолжаем работу таймера
+func TestMakeTransMap(t *testing.T) {
+	trans := []*Transaction{
+		{TranSum: Rub(1_00), TranDate: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC).Unix()},
+		{TranSum: Rub(2_00), TranDate: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC).Unix()},
+		{TranSum: Rub(3_00), TranDate: time.Date(2020, 10, 31, 0, 0, 0, 0, time.UTC).Unix()},
+	}
+	want := map[string][]Money{"2020 01": {Rub(1_00)}, "2020 10": {Rub(2_00), Rub(3_00)}}
+	if got := MakeTransMap(trans); !reflect.DeepEqual(got, want) {
+		t.Errorf("MakeTransMap() = %v, want %v", got, want)
 	}
 }
 
-// benchmark F4
-func BenchmarkF4(b *testing.B) {
-	card1 := &Card{ID: 1, Type: "Master", BankName: "Citi", CardNumber: "1111 2222 3333 4444", Balance: 20_000_00, CardDueDate: "2030-01-01"}
-	card1.Transactions = MakeTransactions()
-
-	want := map[string]int64{"Категория 3333": 100, "Категория 5555": 100000000, "Супермаркеты": 100000000}
-	b.ResetTimer() // сбрасываем таймер, т.к. сама генерация транзакций достаточно ресурсоёмка
-	for i := 0; i < b.N; i++ {
-		result := F4(card1.Transactions, 2)
-		b.StopTimer() // останавливаем таймер, чтобы время сравнения не учитывалось
-		if !reflect.DeepEqual(result, want) {
-			b.Fatalf("invalid result, got %v, want %v", result, want)
+func TestService_UserCards(t *testing.T) {
+	svc := NewService()
+	svc.SetCards([]*Card{{ID: 1, UserID: 1, Balance: Rub(100_00)}, {ID: 2, UserID: 2, Balance: Rub(100_00)}})
+	if _, err := svc.Purchase(context.Background(), 1, Rub(10_00), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := svc.UserCards(1)
+	if err != nil || len(cards) != 1 || cards[0].Balance != Rub(90_00) || len(cards[0].Transactions) != 1 {
+		t.Fatalf("UserCards(1) = %+v, %v", cards, err)
+	}
+	// копии: изменения не видны сервису
+	cards[0].Balance = Rub(0)
+	cards[0].Transactions[0].Status = StatusReversed
+	if again, _ := svc.UserCards(1); again[0].Balance != Rub(90_00) || again[0].Transactions[0].Status != StatusDone {
+		t.Errorf("UserCards() returned live cards: %+v", again[0])
+	}
+	if _, err := svc.UserCards(3); !errors.Is(err, ErrNoCardWithUserID) {
+		t.Errorf("UserCards(3) error = %v, want %v", err, ErrNoCardWithUserID)
+	}
+
+	// сериализация копий параллельно со списаниями (go test -race)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := svc.Purchase(context.Background(), 1, Rub(1_00), "5411"); err != nil {
+				t.Error(err)
+				return
+			}
 		}
-		b.StartTimer() // продолжаем работу таймера
-	}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			cards, err := svc.UserCards(1)
+			if err == nil {
+				_, err = json.Marshal(cards)
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
 }
-*/
@@ -0,0 +1,69 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidStatus = errors.New("transaction status is not valid")
+
+// TranStatus - статус транзакции
+type TranStatus string
+
+const (
+	StatusPending  TranStatus = "pending"
+	StatusDone     TranStatus = "done"
+	StatusReversed TranStatus = "reversed"
+	StatusRefunded TranStatus = "refunded"
+	StatusDeclined TranStatus = "declined"
+)
+
+var statuses = []TranStatus{StatusPending, StatusDone, StatusReversed, StatusRefunded, StatusDeclined}
+
+// Valid - статус из списка известных
+func (st TranStatus) Valid() bool {
+	for _, v := range statuses {
+		if v == st {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatus - разбор статуса; старое значение "done Супермаркеты" считается проведённым.
+// Пустой статус - ошибка: значение по умолчанию подставляет читающий (ValidateFixture, defaultStatus)
+func ParseStatus(s string) (TranStatus, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidStatus)
+	}
+	fields := strings.Fields(s)
+	if st := TranStatus(fields[0]); st.Valid() {
+		return st, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidStatus, s)
+}
+
+// UnmarshalText - используется при импорте JSON/XML, понимает старые значения; пустой статус остаётся пустым
+func (st *TranStatus) UnmarshalText(data []byte) error {
+	if strings.TrimSpace(string(data)) == "" {
+		*st = ""
+		return nil
+	}
+	parsed, err := ParseStatus(string(data))
+	if err != nil {
+		return err
+	}
+	*st = parsed
+	return nil
+}
+
+// defaultStatus - в выгрузках до появления статусов (InitCard) статус пустой: такие транзакции проведены
+func defaultStatus(trs []*Transaction) {
+	for _, t := range trs {
+		if t.Status == "" {
+			t.Status = StatusDone
+		}
+	}
+}
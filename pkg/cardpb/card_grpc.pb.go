@@ -0,0 +1,220 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: card.proto
+
+package cardpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CardService_IssueCard_FullMethodName          = "/card.v1.CardService/IssueCard"
+	CardService_ListUserCards_FullMethodName      = "/card.v1.CardService/ListUserCards"
+	CardService_Transfer_FullMethodName           = "/card.v1.CardService/Transfer"
+	CardService_SpendingByCategory_FullMethodName = "/card.v1.CardService/SpendingByCategory"
+)
+
+// CardServiceClient is the client API for CardService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CardServiceClient interface {
+	IssueCard(ctx context.Context, in *IssueCardRequest, opts ...grpc.CallOption) (*Card, error)
+	ListUserCards(ctx context.Context, in *ListUserCardsRequest, opts ...grpc.CallOption) (*ListUserCardsResponse, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error)
+	SpendingByCategory(ctx context.Context, in *SpendingByCategoryRequest, opts ...grpc.CallOption) (*SpendingByCategoryResponse, error)
+}
+
+type cardServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCardServiceClient(cc grpc.ClientConnInterface) CardServiceClient {
+	return &cardServiceClient{cc}
+}
+
+func (c *cardServiceClient) IssueCard(ctx context.Context, in *IssueCardRequest, opts ...grpc.CallOption) (*Card, error) {
+	out := new(Card)
+	err := c.cc.Invoke(ctx, CardService_IssueCard_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) ListUserCards(ctx context.Context, in *ListUserCardsRequest, opts ...grpc.CallOption) (*ListUserCardsResponse, error) {
+	out := new(ListUserCardsResponse)
+	err := c.cc.Invoke(ctx, CardService_ListUserCards_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error) {
+	out := new(TransferResponse)
+	err := c.cc.Invoke(ctx, CardService_Transfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) SpendingByCategory(ctx context.Context, in *SpendingByCategoryRequest, opts ...grpc.CallOption) (*SpendingByCategoryResponse, error) {
+	out := new(SpendingByCategoryResponse)
+	err := c.cc.Invoke(ctx, CardService_SpendingByCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CardServiceServer is the server API for CardService service.
+// All implementations must embed UnimplementedCardServiceServer
+// for forward compatibility
+type CardServiceServer interface {
+	IssueCard(context.Context, *IssueCardRequest) (*Card, error)
+	ListUserCards(context.Context, *ListUserCardsRequest) (*ListUserCardsResponse, error)
+	Transfer(context.Context, *TransferRequest) (*TransferResponse, error)
+	SpendingByCategory(context.Context, *SpendingByCategoryRequest) (*SpendingByCategoryResponse, error)
+	mustEmbedUnimplementedCardServiceServer()
+}
+
+// UnimplementedCardServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCardServiceServer struct {
+}
+
+func (UnimplementedCardServiceServer) IssueCard(context.Context, *IssueCardRequest) (*Card, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueCard not implemented")
+}
+func (UnimplementedCardServiceServer) ListUserCards(context.Context, *ListUserCardsRequest) (*ListUserCardsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserCards not implemented")
+}
+func (UnimplementedCardServiceServer) Transfer(context.Context, *TransferRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedCardServiceServer) SpendingByCategory(context.Context, *SpendingByCategoryRequest) (*SpendingByCategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpendingByCategory not implemented")
+}
+func (UnimplementedCardServiceServer) mustEmbedUnimplementedCardServiceServer() {}
+
+// UnsafeCardServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CardServiceServer will
+// result in compilation errors.
+type UnsafeCardServiceServer interface {
+	mustEmbedUnimplementedCardServiceServer()
+}
+
+func RegisterCardServiceServer(s grpc.ServiceRegistrar, srv CardServiceServer) {
+	s.RegisterService(&CardService_ServiceDesc, srv)
+}
+
+func _CardService_IssueCard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueCardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).IssueCard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_IssueCard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).IssueCard(ctx, req.(*IssueCardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_ListUserCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).ListUserCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_ListUserCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).ListUserCards(ctx, req.(*ListUserCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_SpendingByCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SpendingByCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).SpendingByCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_SpendingByCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).SpendingByCategory(ctx, req.(*SpendingByCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CardService_ServiceDesc is the grpc.ServiceDesc for CardService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CardService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "card.v1.CardService",
+	HandlerType: (*CardServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IssueCard",
+			Handler:    _CardService_IssueCard_Handler,
+		},
+		{
+			MethodName: "ListUserCards",
+			Handler:    _CardService_ListUserCards_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _CardService_Transfer_Handler,
+		},
+		{
+			MethodName: "SpendingByCategory",
+			Handler:    _CardService_SpendingByCategory_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "card.proto",
+}
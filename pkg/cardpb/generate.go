@@ -0,0 +1,4 @@
+// Package cardpb - protobuf-описание и gRPC-сервис API карт (код сгенерирован из card.proto)
+package cardpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative card.proto
@@ -0,0 +1,302 @@
+// Package datagen - синтетические пользователи, карты и транзакции для тестов, бенчмарков и демо-данных.
+// Генерация детерминирована: одинаковые Options (включая Seed) дают одинаковые данные.
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+var ErrInvalidOptions = errors.New("generator options are not valid")
+
+// Options - объём и форма данных
+type Options struct {
+	Seed                int64
+	Users               int       // пользователи с ID 1..Users
+	MaxCardsPerUser     int       // у пользователя от 1 до MaxCardsPerUser карт
+	TransactionsPerCard int       // в среднем; у карты - от 0 до удвоенного среднего
+	From                time.Time // даты транзакций - [From, To)
+	To                  time.Time
+	BankName            string
+}
+
+// DefaultOptions - десяток пользователей с транзакциями за 2020 год
+func DefaultOptions() Options {
+	return Options{
+		Seed:                1,
+		Users:               10,
+		MaxCardsPerUser:     3,
+		TransactionsPerCard: 20,
+		From:                time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:                  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		BankName:            "Citi",
+	}
+}
+
+// maxCards - ID карты после 6-значного BIN занимает не больше 9 цифр номера
+const maxCards = 999_999_999
+
+// Validate - размеры неотрицательные, номера карт помещаются в 16 цифр, период непустой
+func (o Options) Validate() error {
+	switch {
+	case o.Users < 0:
+		return fmt.Errorf("%w: users %d is negative", ErrInvalidOptions, o.Users)
+	case o.MaxCardsPerUser < 1:
+		return fmt.Errorf("%w: max cards per user %d must be positive", ErrInvalidOptions, o.MaxCardsPerUser)
+	case int64(o.Users)*int64(o.MaxCardsPerUser) > maxCards:
+		return fmt.Errorf("%w: up to %d cards do not fit 6-digit BIN card numbers", ErrInvalidOptions, int64(o.Users)*int64(o.MaxCardsPerUser))
+	case o.TransactionsPerCard < 0:
+		return fmt.Errorf("%w: transactions per card %d is negative", ErrInvalidOptions, o.TransactionsPerCard)
+	case !o.From.Before(o.To):
+		return fmt.Errorf("%w: period %s - %s is empty", ErrInvalidOptions, o.From.Format(time.RFC3339), o.To.Format(time.RFC3339))
+	case o.BankName == "":
+		return fmt.Errorf("%w: bank name is empty", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// mcc - код MCC с долей среди покупок и типичной (медианной) суммой
+type mcc struct {
+	code   string
+	weight int
+	median int64   // в копейках
+	spread float64 // сигма логнормального распределения: чем больше, тем шире разброс сумм
+	round  int64   // кратность суммы (наличные - сотнями рублей), 0 - до копейки
+}
+
+// mccs - примерная структура трат по карте: продукты чаще всего, азартные игры реже всего
+var mccs = []mcc{
+	{code: "5411", weight: 30, median: 850_00, spread: 0.8},
+	{code: "5499", weight: 8, median: 350_00, spread: 0.7},
+	{code: "5812", weight: 10, median: 1_800_00, spread: 0.7},
+	{code: "5814", weight: 12, median: 450_00, spread: 0.5},
+	{code: "5541", weight: 9, median: 2_500_00, spread: 0.4},
+	{code: "5542", weight: 3, median: 2_000_00, spread: 0.4},
+	{code: "5912", weight: 8, median: 650_00, spread: 0.8},
+	{code: "5533", weight: 3, median: 3_500_00, spread: 1.0},
+	{code: "6011", weight: 5, median: 5_000_00, spread: 0.9, round: 100_00},
+	{code: "6010", weight: 1, median: 20_000_00, spread: 0.8, round: 100_00},
+	{code: "5555", weight: 6, median: 1_200_00, spread: 1.0},
+	{code: "1111", weight: 2, median: 900_00, spread: 1.0},
+	{code: "3333", weight: 2, median: 900_00, spread: 1.0},
+	{code: "7995", weight: 1, median: 1_000_00, spread: 1.2},
+}
+
+// statusWeights - почти все покупки проведены; немного в ожидании, отклонённых и возвращённых
+var statusWeights = []struct {
+	status card.TranStatus
+	weight int
+}{
+	{status: card.StatusDone, weight: 920},
+	{status: card.StatusPending, weight: 30},
+	{status: card.StatusDeclined, weight: 30},
+	{status: card.StatusReversed, weight: 10},
+	{status: card.StatusRefunded, weight: 10},
+}
+
+// issuerWeights - доли платёжных систем и префиксы BIN, как в card.DefaultCatalog
+var issuerWeights = []struct {
+	issuer string
+	bin    string
+	weight int
+}{
+	{issuer: "Visa", bin: "4000", weight: 45},
+	{issuer: "Master", bin: "5100", weight: 40},
+	{issuer: "UnionPay", bin: "6200", weight: 15},
+}
+
+// Generator - источник данных; не безопасен для использования из нескольких горутин
+type Generator struct {
+	opts     Options
+	rnd      *rand.Rand
+	mccTotal int
+	lastCard int64
+	lastTran int64
+}
+
+func New(opts Options) (*Generator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	g := &Generator{opts: opts, rnd: rand.New(rand.NewSource(opts.Seed))}
+	for _, m := range mccs {
+		g.mccTotal += m.weight
+	}
+	return g, nil
+}
+
+// Cards - карты всех пользователей с транзакциями, отсортированными по дате
+func (g *Generator) Cards() []*card.Card {
+	cards := make([]*card.Card, 0, g.opts.Users*(g.opts.MaxCardsPerUser+1)/2)
+	for userID := int64(1); userID <= int64(g.opts.Users); userID++ {
+		n := 1 + g.rnd.Intn(g.opts.MaxCardsPerUser)
+		for i := 0; i < n; i++ {
+			cards = append(cards, g.Card(userID))
+		}
+	}
+	return cards
+}
+
+// Card - карта пользователя со случайным числом транзакций (в среднем TransactionsPerCard)
+func (g *Generator) Card(userID int64) *card.Card {
+	g.lastCard++
+	issuer := issuerWeights[len(issuerWeights)-1]
+	pick := g.rnd.Intn(100)
+	for _, iw := range issuerWeights {
+		if pick < iw.weight {
+			issuer = iw
+			break
+		}
+		pick -= iw.weight
+	}
+	virtual := g.rnd.Intn(10) < 4
+	cardtype := 0 // индекс в card.CardTypes, как в BIN продуктов каталога
+	if virtual {
+		cardtype = 1
+	}
+	due := g.opts.To.AddDate(1+g.rnd.Intn(5), g.rnd.Intn(12), 0)
+
+	number, err := card.CardNumber(fmt.Sprintf("%s%d0", issuer.bin, cardtype), g.lastCard)
+	if err != nil {
+		panic(err) // число карт ограничено в Options.Validate
+	}
+	c := &card.Card{
+		ID:          g.lastCard,
+		Type:        issuer.issuer,
+		BankName:    g.opts.BankName,
+		CardNumber:  number,
+		CardDueDate: time.Date(due.Year(), due.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+		Balance:     card.Rub(g.amount(50_000_00, 1.0, 100)),
+		UserID:      userID,
+		IsVirtual:   virtual,
+	}
+
+	n := 0
+	if g.opts.TransactionsPerCard > 0 {
+		n = g.rnd.Intn(2*g.opts.TransactionsPerCard + 1)
+	}
+	backing := make([]card.Transaction, n)
+	c.Transactions = make([]*card.Transaction, n)
+	for i := range backing {
+		g.fill(&backing[i], userID)
+		c.Transactions[i] = &backing[i]
+	}
+	// история карты - по порядку: ID растут вместе с датой
+	sort.Slice(c.Transactions, func(i, j int) bool { return c.Transactions[i].TranDate < c.Transactions[j].TranDate })
+	for i, tr := range c.Transactions {
+		tr.ID = g.lastTran - int64(n) + int64(i) + 1
+	}
+	return c
+}
+
+// Transactions - n транзакций владельцев 1..owners вперемешку, для бенчмарков F1-F4;
+// выделяется одним блоком, поэтому миллионы транзакций создаются быстро
+func (g *Generator) Transactions(n int, owners int) []*card.Transaction {
+	if owners < 1 {
+		owners = 1
+	}
+	backing := make([]card.Transaction, n)
+	transactions := make([]*card.Transaction, n)
+	for i := range backing {
+		g.fill(&backing[i], 1+g.rnd.Int63n(int64(owners)))
+		transactions[i] = &backing[i]
+	}
+	return transactions
+}
+
+// Transaction - одна покупка владельца ownerID
+func (g *Generator) Transaction(ownerID int64) *card.Transaction {
+	tr := &card.Transaction{}
+	g.fill(tr, ownerID)
+	return tr
+}
+
+func (g *Generator) fill(tr *card.Transaction, ownerID int64) {
+	g.lastTran++
+	m := g.mcc()
+	tr.ID = g.lastTran
+	tr.TranType = "purchase"
+	tr.TranSum = card.Rub(g.amount(m.median, m.spread, m.round))
+	tr.TranDate = g.date()
+	tr.MccCode = m.code
+	tr.Status = g.status()
+	tr.OwnerID = ownerID
+}
+
+func (g *Generator) mcc() mcc {
+	pick := g.rnd.Intn(g.mccTotal)
+	for _, m := range mccs {
+		if pick < m.weight {
+			return m
+		}
+		pick -= m.weight
+	}
+	return mccs[len(mccs)-1]
+}
+
+func (g *Generator) status() card.TranStatus {
+	pick := g.rnd.Intn(1000)
+	for _, sw := range statusWeights {
+		if pick < sw.weight {
+			return sw.status
+		}
+		pick -= sw.weight
+	}
+	return card.StatusDone
+}
+
+// amount - логнормальная сумма вокруг median: не меньше рубля и не больше двадцати медиан
+func (g *Generator) amount(median int64, spread float64, round int64) int64 {
+	v := float64(median) * math.Exp(spread*g.rnd.NormFloat64())
+	v = math.Max(100, math.Min(v, float64(20*median)))
+	amount := int64(v)
+	if round > 0 {
+		amount = (amount + round/2) / round * round
+		if amount == 0 {
+			amount = round
+		}
+	}
+	return amount
+}
+
+// date - момент в [From, To); днём покупок больше, чем ночью
+func (g *Generator) date() int64 {
+	days := int(g.opts.To.Sub(g.opts.From).Hours()/24) + 1
+	for {
+		day := g.opts.From.AddDate(0, 0, g.rnd.Intn(days))
+		hour := 8 + int(math.Abs(g.rnd.NormFloat64()*4)) // 8-20 чаще всего
+		if g.rnd.Intn(10) == 0 {
+			hour = g.rnd.Intn(24)
+		}
+		t := day.Add(time.Duration(hour%24)*time.Hour + time.Duration(g.rnd.Intn(3600))*time.Second)
+		if !t.Before(g.opts.From) && t.Before(g.opts.To) {
+			return t.Unix()
+		}
+	}
+}
+
+// Cards - карты с транзакциями по opts одним вызовом
+func Cards(opts Options) ([]*card.Card, error) {
+	g, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.Cards(), nil
+}
+
+// Transactions - n транзакций владельцев 1..owners с периодом и банком из DefaultOptions
+func Transactions(seed int64, n int, owners int) []*card.Transaction {
+	opts := DefaultOptions()
+	opts.Seed = seed
+	g, err := New(opts)
+	if err != nil {
+		panic(err) // DefaultOptions всегда корректны
+	}
+	return g.Transactions(n, owners)
+}
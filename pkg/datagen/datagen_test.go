@@ -0,0 +1,165 @@
+package datagen
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func TestCards_Deterministic(t *testing.T) {
+	a, err := Cards(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Cards(DefaultOptions())
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same options produced different cards")
+	}
+
+	opts := DefaultOptions()
+	opts.Seed = 2
+	c, _ := Cards(opts)
+	if reflect.DeepEqual(a, c) {
+		t.Error("different seeds produced the same cards")
+	}
+
+	if !reflect.DeepEqual(Transactions(7, 1000, 10), Transactions(7, 1000, 10)) {
+		t.Error("same seed produced different transactions")
+	}
+}
+
+func TestCards_Shape(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Users = 50
+	cards, err := Cards(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numbers := make(map[string]bool)
+	tranIDs := make(map[int64]bool)
+	perUser := make(map[int64]int)
+	for i, c := range cards {
+		if c.ID != int64(i+1) {
+			t.Errorf("card %d ID = %d, want %d", i, c.ID, i+1)
+		}
+		if !card.ValidCardNumber(c.CardNumber) || numbers[c.CardNumber] {
+			t.Errorf("card %d number %q is invalid or duplicated", c.ID, c.CardNumber)
+		}
+		numbers[c.CardNumber] = true
+		if err := card.CheckCardTypeCardIssuer("virtual", c.Type); err != nil {
+			t.Errorf("card %d issuer: %v", c.ID, err)
+		}
+		if _, err := time.Parse("2006-01-02", c.CardDueDate); err != nil || c.CardDueDate <= "2021-01-01" {
+			t.Errorf("card %d due date %q", c.ID, c.CardDueDate)
+		}
+		perUser[c.UserID]++
+		if len(c.Transactions) > 2*opts.TransactionsPerCard {
+			t.Errorf("card %d has %d transactions", c.ID, len(c.Transactions))
+		}
+		for j, tr := range c.Transactions {
+			if tranIDs[tr.ID] {
+				t.Errorf("transaction ID %d is duplicated", tr.ID)
+			}
+			tranIDs[tr.ID] = true
+			if tr.OwnerID != c.UserID {
+				t.Errorf("transaction %d owner %d, card user %d", tr.ID, tr.OwnerID, c.UserID)
+			}
+			if j > 0 && (tr.TranDate < c.Transactions[j-1].TranDate || tr.ID < c.Transactions[j-1].ID) {
+				t.Errorf("card %d transactions are not ordered by date and ID", c.ID)
+			}
+		}
+	}
+	if len(perUser) != opts.Users {
+		t.Errorf("cards belong to %d users, want %d", len(perUser), opts.Users)
+	}
+	for userID, n := range perUser {
+		if n < 1 || n > opts.MaxCardsPerUser {
+			t.Errorf("user %d has %d cards", userID, n)
+		}
+	}
+}
+
+func TestTransactions_Distribution(t *testing.T) {
+	const n = 100_000
+	opts := DefaultOptions()
+	transactions := Transactions(1, n, 100)
+
+	byMCC := make(map[string]int)
+	byStatus := make(map[card.TranStatus]int)
+	amounts := make(map[string][]int64)
+	for _, tr := range transactions {
+		byMCC[tr.MccCode]++
+		byStatus[tr.Status]++
+		amounts[tr.MccCode] = append(amounts[tr.MccCode], tr.TranSum.Amount)
+		if tr.TranDate < opts.From.Unix() || tr.TranDate >= opts.To.Unix() {
+			t.Fatalf("transaction %d date %v is out of range", tr.ID, time.Unix(tr.TranDate, 0).UTC())
+		}
+		if tr.TranSum.Currency != card.RUB || tr.TranSum.Amount < 100 {
+			t.Fatalf("transaction %d amount %v", tr.ID, tr.TranSum)
+		}
+		if tr.OwnerID < 1 || tr.OwnerID > 100 {
+			t.Fatalf("transaction %d owner %d", tr.ID, tr.OwnerID)
+		}
+	}
+
+	total := 0
+	for _, m := range mccs {
+		total += m.weight
+	}
+	for _, m := range mccs {
+		share, want := float64(byMCC[m.code])/n, float64(m.weight)/float64(total)
+		if math.Abs(share-want) > 0.01 {
+			t.Errorf("mcc %s share = %.3f, want %.3f", m.code, share, want)
+		}
+		// медиана суммы близка к заданной
+		median := medianOf(amounts[m.code])
+		if ratio := float64(median) / float64(m.median); ratio < 0.9 || ratio > 1.1 {
+			t.Errorf("mcc %s median = %d, want about %d", m.code, median, m.median)
+		}
+		if m.round > 0 {
+			for _, a := range amounts[m.code] {
+				if a%m.round != 0 {
+					t.Fatalf("mcc %s amount %d is not a multiple of %d", m.code, a, m.round)
+				}
+			}
+		}
+	}
+	if done := float64(byStatus[card.StatusDone]) / n; done < 0.9 || done > 0.94 {
+		t.Errorf("done share = %.3f, want about 0.92", done)
+	}
+	for _, st := range []card.TranStatus{card.StatusPending, card.StatusDeclined, card.StatusReversed, card.StatusRefunded} {
+		if byStatus[st] == 0 {
+			t.Errorf("no %s transactions", st)
+		}
+	}
+}
+
+func medianOf(values []int64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func TestOptions_Validate(t *testing.T) {
+	modify := []func(o *Options){
+		func(o *Options) { o.Users = -1 },
+		func(o *Options) { o.MaxCardsPerUser = 0 },
+		func(o *Options) { o.Users, o.MaxCardsPerUser = 100_000, 10_000 },
+		func(o *Options) { o.TransactionsPerCard = -1 },
+		func(o *Options) { o.To = o.From },
+		func(o *Options) { o.BankName = "" },
+	}
+	for i, m := range modify {
+		opts := DefaultOptions()
+		m(&opts)
+		if _, err := New(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("case %d: New() error = %v, want %v", i, err, ErrInvalidOptions)
+		}
+	}
+}
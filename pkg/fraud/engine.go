@@ -0,0 +1,154 @@
+package fraud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+var ErrInvalidConfig = errors.New("fraud rules config is not valid")
+
+// Engine - набор правил; итоговое решение - самое строгое из решений правил
+type Engine struct {
+	rules []Rule
+	audit *AuditLog
+}
+
+func NewEngine(audit *AuditLog, rules ...Rule) *Engine {
+	return &Engine{rules: rules, audit: audit}
+}
+
+// Screen - реализует card.Screener
+func (e *Engine) Screen(in card.ScreeningInput) card.Decision {
+	decision := card.Decision{Outcome: card.OutcomeApprove, Reasons: make([]string, 0)}
+	for _, r := range e.rules {
+		outcome, reason := r.Evaluate(in)
+		if outcome == card.OutcomeApprove {
+			continue
+		}
+		decision.Reasons = append(decision.Reasons, r.Name()+": "+reason)
+		if outcome.Severity() > decision.Outcome.Severity() {
+			decision.Outcome = outcome
+		}
+	}
+	return decision
+}
+
+// Audit - реализует card.ScreeningAuditor: Service вызывает его после фиксации транзакции
+func (e *Engine) Audit(in card.ScreeningInput, decision card.Decision) {
+	if e.audit != nil {
+		e.audit.Write(in, decision)
+	}
+}
+
+// AuditRecord - строка журнала решений
+type AuditRecord struct {
+	Time          int64        `json:"time"` // unix timestamp
+	CardID        int64        `json:"card_id"`
+	UserID        int64        `json:"user_id"`
+	TransactionID int64        `json:"transaction_id"`
+	Amount        card.Money   `json:"amount"`
+	MccCode       string       `json:"mcc"`
+	Outcome       card.Outcome `json:"outcome"`
+	Reasons       []string     `json:"reasons"`
+}
+
+// AuditLog - журнал решений антифрода, по одной JSON-записи на строку
+type AuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w)}
+}
+
+func (a *AuditLog) Write(in card.ScreeningInput, decision card.Decision) {
+	rec := &AuditRecord{
+		Time:          in.Now.Unix(),
+		CardID:        in.Card.ID,
+		UserID:        in.Card.UserID,
+		TransactionID: in.Transaction.ID,
+		Amount:        in.Transaction.TranSum,
+		MccCode:       in.Transaction.MccCode,
+		Outcome:       decision.Outcome,
+		Reasons:       decision.Reasons,
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.enc.Encode(rec); err != nil {
+		log.Println(err)
+	}
+}
+
+// RuleConfig - общие поля правил в файле конфигурации
+type RuleConfig struct {
+	Enabled bool         `json:"enabled"`
+	Action  card.Outcome `json:"action"` // review или decline
+}
+
+// Config - файл правил, например test/fraud_rules.json
+type Config struct {
+	Velocity struct {
+		RuleConfig
+		MaxTransactions int   `json:"max_transactions"`
+		WindowMinutes   int64 `json:"window_minutes"`
+	} `json:"velocity"`
+	UnusualMCC struct {
+		RuleConfig
+		MinHistory int `json:"min_history"`
+	} `json:"unusual_mcc"`
+	AmountSpike struct {
+		RuleConfig
+		Multiplier int64 `json:"multiplier"`
+		MinHistory int   `json:"min_history"`
+	} `json:"amount_spike"`
+}
+
+func validAction(o card.Outcome) bool {
+	return o == card.OutcomeReview || o == card.OutcomeDecline
+}
+
+// Rules - правила из конфигурации (только включённые)
+func (c *Config) Rules() ([]Rule, error) {
+	rules := make([]Rule, 0)
+	if v := c.Velocity; v.Enabled {
+		if !validAction(v.Action) || v.MaxTransactions <= 0 || v.WindowMinutes <= 0 {
+			return nil, fmt.Errorf("%w: velocity", ErrInvalidConfig)
+		}
+		rules = append(rules, &VelocityRule{MaxTransactions: v.MaxTransactions, Window: time.Duration(v.WindowMinutes) * time.Minute, Action: v.Action})
+	}
+	if u := c.UnusualMCC; u.Enabled {
+		if !validAction(u.Action) || u.MinHistory < 0 {
+			return nil, fmt.Errorf("%w: unusual_mcc", ErrInvalidConfig)
+		}
+		rules = append(rules, &UnusualMCCRule{MinHistory: u.MinHistory, Action: u.Action})
+	}
+	if a := c.AmountSpike; a.Enabled {
+		if !validAction(a.Action) || a.Multiplier <= 0 || a.MinHistory < 0 {
+			return nil, fmt.Errorf("%w: amount_spike", ErrInvalidConfig)
+		}
+		rules = append(rules, &AmountSpikeRule{Multiplier: a.Multiplier, MinHistory: a.MinHistory, Action: a.Action})
+	}
+	return rules, nil
+}
+
+// LoadConfig - чтение файла правил
+func LoadConfig(importPath string) (*Config, error) {
+	content, err := ioutil.ReadFile(importPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return &cfg, nil
+}
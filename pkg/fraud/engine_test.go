@@ -0,0 +1,127 @@
+package fraud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+func history(now time.Time) []*card.Transaction {
+	return []*card.Transaction{
+		{ID: 1, TranType: card.TranTypePurchase, TranSum: card.Rub(100_00), TranDate: now.Add(-time.Hour).Unix(), MccCode: "5411", Status: card.StatusDone, OwnerID: 1},
+		{ID: 2, TranType: card.TranTypePurchase, TranSum: card.Rub(200_00), TranDate: now.Add(-2 * time.Minute).Unix(), MccCode: "5411", Status: card.StatusDone, OwnerID: 1},
+		{ID: 3, TranType: card.TranTypePurchase, TranSum: card.Rub(300_00), TranDate: now.Add(-time.Minute).Unix(), MccCode: "5912", Status: card.StatusDone, OwnerID: 1},
+		{ID: 4, TranType: card.TranTypePurchase, TranSum: card.Rub(900_00), TranDate: now.Unix(), MccCode: "5912", Status: card.StatusReversed, OwnerID: 1},
+	}
+}
+
+func TestEngine_Screen(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.Local)
+	c := &card.Card{ID: 1, UserID: 1, Balance: card.Rub(10_000_00)}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		tr      *card.Transaction
+		outcome card.Outcome
+	}{
+		{
+			name:    "velocity ok",
+			rule:    &VelocityRule{MaxTransactions: 3, Window: 5 * time.Minute, Action: card.OutcomeDecline},
+			tr:      &card.Transaction{TranSum: card.Rub(1_00), MccCode: "5411", OwnerID: 1},
+			outcome: card.OutcomeApprove,
+		},
+		{
+			name:    "velocity exceeded",
+			rule:    &VelocityRule{MaxTransactions: 2, Window: 5 * time.Minute, Action: card.OutcomeDecline},
+			tr:      &card.Transaction{TranSum: card.Rub(1_00), MccCode: "5411", OwnerID: 1},
+			outcome: card.OutcomeDecline,
+		},
+		{
+			name:    "known category",
+			rule:    &UnusualMCCRule{MinHistory: 2, Action: card.OutcomeReview},
+			tr:      &card.Transaction{TranSum: card.Rub(1_00), MccCode: "5912", OwnerID: 1},
+			outcome: card.OutcomeApprove,
+		},
+		{
+			name:    "unusual category",
+			rule:    &UnusualMCCRule{MinHistory: 2, Action: card.OutcomeReview},
+			tr:      &card.Transaction{TranSum: card.Rub(1_00), MccCode: "5533", OwnerID: 1},
+			outcome: card.OutcomeReview,
+		},
+		{
+			name:    "amount spike",
+			rule:    &AmountSpikeRule{Multiplier: 5, MinHistory: 3, Action: card.OutcomeReview},
+			tr:      &card.Transaction{TranSum: card.Rub(1_000_01), MccCode: "5411", OwnerID: 1},
+			outcome: card.OutcomeReview,
+		},
+		{
+			name:    "no spike",
+			rule:    &AmountSpikeRule{Multiplier: 5, MinHistory: 3, Action: card.OutcomeReview},
+			tr:      &card.Transaction{TranSum: card.Rub(999_00), MccCode: "5411", OwnerID: 1},
+			outcome: card.OutcomeApprove,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEngine(nil, tt.rule).Screen(card.ScreeningInput{Card: c, Transaction: tt.tr, History: history(now), Now: now})
+			if got.Outcome != tt.outcome {
+				t.Errorf("Screen() = %+v, want %v", got, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestEngine_AuditAndService(t *testing.T) {
+	buf := &bytes.Buffer{}
+	engine := NewEngine(NewAuditLog(buf),
+		&VelocityRule{MaxTransactions: 2, Window: time.Hour, Action: card.OutcomeDecline},
+	)
+
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{{ID: 1, UserID: 1, Balance: card.Rub(1000_00)}})
+	svc.SetScreener(engine)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Purchase(context.Background(), 1, card.Rub(1_00), "5411"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.Purchase(context.Background(), 1, card.Rub(1_00), "5411"); !errors.Is(err, card.ErrFraudDeclined) {
+		t.Fatalf("Purchase() error = %v, want %v", err, card.ErrFraudDeclined)
+	}
+
+	dec := json.NewDecoder(buf)
+	var records []AuditRecord
+	for dec.More() {
+		var rec AuditRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 3 || records[2].Outcome != card.OutcomeDecline || len(records[2].Reasons) != 1 {
+		t.Errorf("audit records = %+v", records)
+	}
+}
+
+func TestConfig_Rules(t *testing.T) {
+	cfg, err := LoadConfig("../../test/fraud_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := cfg.Rules()
+	if err != nil || len(rules) != 3 {
+		t.Errorf("Rules() = %v, %v", rules, err)
+	}
+
+	cfg.Velocity.Action = card.OutcomeApprove
+	if _, err := cfg.Rules(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Rules() error = %v, want %v", err, ErrInvalidConfig)
+	}
+}
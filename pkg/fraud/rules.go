@@ -0,0 +1,124 @@
+package fraud
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// Rule - одно правило антифрода; возвращает решение и причину (для approve причина не нужна)
+type Rule interface {
+	Name() string
+	Evaluate(in card.ScreeningInput) (card.Outcome, string)
+}
+
+// purchases - покупки, которые реально прошли или стоят в холде
+func purchases(history []*card.Transaction) []*card.Transaction {
+	res := make([]*card.Transaction, 0, len(history))
+	for _, t := range history {
+		if t.TranType != card.TranTypePurchase {
+			continue
+		}
+		if t.Status != card.StatusDone && t.Status != card.StatusPending {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+// VelocityRule - не больше MaxTransactions покупок за Window
+type VelocityRule struct {
+	MaxTransactions int
+	Window          time.Duration
+	Action          card.Outcome
+}
+
+func (r *VelocityRule) Name() string {
+	return "velocity"
+}
+
+func (r *VelocityRule) Evaluate(in card.ScreeningInput) (card.Outcome, string) {
+	since := in.Now.Add(-r.Window).Unix()
+	count := 1 // сама новая транзакция
+	for _, t := range purchases(in.History) {
+		if t.TranDate >= since {
+			count++
+		}
+	}
+	if count > r.MaxTransactions {
+		return r.Action, fmt.Sprintf("%d transactions in %v (max %d)", count, r.Window, r.MaxTransactions)
+	}
+	return card.OutcomeApprove, ""
+}
+
+// UnusualMCCRule - категория покупки не встречалась в истории владельца (по отчёту F1)
+type UnusualMCCRule struct {
+	MinHistory int // при меньшей истории правило не срабатывает
+	Action     card.Outcome
+}
+
+func (r *UnusualMCCRule) Name() string {
+	return "unusual_mcc"
+}
+
+func (r *UnusualMCCRule) Evaluate(in card.ScreeningInput) (card.Outcome, string) {
+	history := purchases(in.History)
+	if len(history) < r.MinHistory {
+		return card.OutcomeApprove, ""
+	}
+	spending, err := card.F1(history, in.Transaction.OwnerID)
+	if err != nil {
+		// переполнение суммы - категория точно встречалась
+		return card.OutcomeApprove, ""
+	}
+	category := card.TranslateMCC(in.Transaction.MccCode)
+	for key := range spending {
+		if key.Category == category { // в любой валюте
+			return card.OutcomeApprove, ""
+		}
+	}
+	return r.Action, fmt.Sprintf("category %q (MCC %s) is new for user %d", category, in.Transaction.MccCode, in.Transaction.OwnerID)
+}
+
+// AmountSpikeRule - сумма больше средней покупки владельца в Multiplier раз
+type AmountSpikeRule struct {
+	Multiplier int64
+	MinHistory int
+	Action     card.Outcome
+}
+
+func (r *AmountSpikeRule) Name() string {
+	return "amount_spike"
+}
+
+func (r *AmountSpikeRule) Evaluate(in card.ScreeningInput) (card.Outcome, string) {
+	amount := in.Transaction.TranSum
+	total := card.NewMoney(0, amount.Currency)
+	var count int64
+	for _, t := range purchases(in.History) {
+		// средняя считается только по покупкам в той же валюте
+		if t.TranSum.Currency != amount.Currency {
+			continue
+		}
+		sum, err := total.Add(t.TranSum)
+		if err != nil {
+			return card.OutcomeApprove, ""
+		}
+		total = sum
+		count++
+	}
+	if count == 0 || count < int64(r.MinHistory) {
+		return card.OutcomeApprove, ""
+	}
+	avg := total.Amount / count
+	if avg <= 0 || avg > math.MaxInt64/r.Multiplier {
+		return card.OutcomeApprove, ""
+	}
+	if amount.Amount > avg*r.Multiplier {
+		return r.Action, fmt.Sprintf("amount %v is more than %d times the average %v", amount, r.Multiplier, card.NewMoney(avg, amount.Currency))
+	}
+	return card.OutcomeApprove, ""
+}
@@ -0,0 +1,179 @@
+// Package lifecycle - запуск серверов и их корректная остановка: сигнал, Shutdown с дедлайном, сброс хранилищ
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout - сколько ждать завершения запросов и хуков при остановке
+const DefaultShutdownTimeout = 15 * time.Second
+
+// Server - компонент с блокирующим Serve; Serve возвращает nil после штатной остановки через Shutdown
+type Server interface {
+	Serve() error
+	Shutdown(ctx context.Context) error
+}
+
+// HTTPServer - http.Server; без Listener слушает Server.Addr
+type HTTPServer struct {
+	Server   *http.Server
+	Listener net.Listener
+}
+
+func (s *HTTPServer) Serve() error {
+	var err error
+	if s.Listener != nil {
+		err = s.Server.Serve(s.Listener)
+	} else {
+		err = s.Server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown - дождаться текущих запросов; по дедлайну соединения закрываются принудительно
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	err := s.Server.Shutdown(ctx)
+	if err != nil {
+		s.Server.Close()
+	}
+	return err
+}
+
+// ServerFuncs - адаптер для серверов с другим API (например, gRPC)
+type ServerFuncs struct {
+	ServeFunc    func() error
+	ShutdownFunc func(ctx context.Context) error
+}
+
+func (s ServerFuncs) Serve() error {
+	return s.ServeFunc()
+}
+
+func (s ServerFuncs) Shutdown(ctx context.Context) error {
+	return s.ShutdownFunc(ctx)
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager - набор серверов и хуков остановки
+type Manager struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	servers []namedServer
+	hooks   []hook
+}
+
+func New(shutdownTimeout time.Duration) *Manager {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	return &Manager{timeout: shutdownTimeout}
+}
+
+// Serve - добавить сервер; запускается в Run
+func (m *Manager) Serve(name string, server Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.servers = append(m.servers, namedServer{name: name, server: server})
+}
+
+// OnShutdown - хук после остановки всех серверов (сброс файлов, доставка очередей); выполняются в порядке добавления
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Run - запустить серверы и ждать отмены ctx (сигнала) или падения любого сервера,
+// затем остановить все серверы и выполнить хуки за общий дедлайн; возвращает первую ошибку
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	servers := append([]namedServer(nil), m.servers...)
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		s := s
+		go func() {
+			err := s.server.Serve()
+			if err != nil {
+				err = fmt.Errorf("%s: %w", s.name, err)
+			}
+			errs <- err
+		}()
+	}
+
+	var first error
+	running := len(servers)
+	select {
+	case <-ctx.Done():
+		log.Println("shutdown: signal received")
+	case first = <-errs:
+		running--
+		log.Printf("shutdown: server stopped: %v", first)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	record := func(name string, err error) {
+		if err == nil {
+			return
+		}
+		err = fmt.Errorf("%s: %w", name, err)
+		log.Println("shutdown:", err)
+		if first == nil {
+			first = err
+		}
+	}
+
+	var wg sync.WaitGroup
+	shutdownErrs := make([]error, len(servers))
+	for i, s := range servers {
+		i, s := i, s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shutdownErrs[i] = s.server.Shutdown(shutdownCtx)
+		}()
+	}
+	wg.Wait()
+	for i, s := range servers {
+		record(s.name, shutdownErrs[i])
+	}
+wait:
+	for ; running > 0; running-- {
+		select {
+		case err := <-errs:
+			if err != nil && first == nil {
+				first = err
+			}
+		case <-shutdownCtx.Done():
+			record("serve", shutdownCtx.Err())
+			break wait
+		}
+	}
+
+	for _, h := range hooks {
+		record(h.name, h.fn(shutdownCtx))
+	}
+	return first
+}
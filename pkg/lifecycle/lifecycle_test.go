@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// slowServer - HTTP-сервер, обработчик которого отвечает через delay
+func slowServer(t *testing.T, delay time.Duration, started chan<- struct{}) (*HTTPServer, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(delay)
+		w.Write([]byte("done"))
+	})
+	return &HTTPServer{Server: &http.Server{Handler: mux}, Listener: lis}, "http://" + lis.Addr().String()
+}
+
+type result struct {
+	body string
+	err  error
+}
+
+func get(url string) <-chan result {
+	res := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			res <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		res <- result{body: string(body), err: err}
+	}()
+	return res
+}
+
+func TestManager_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv, url := slowServer(t, 200*time.Millisecond, started)
+
+	var mu sync.Mutex
+	var order []string
+	m := New(time.Second)
+	m.Serve("http", srv)
+	m.OnShutdown("flush", func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "flush")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	inFlight := get(url)
+	<-started
+	cancel()
+
+	res := <-inFlight
+	mu.Lock()
+	order = append(order, "response")
+	mu.Unlock()
+	if res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v", res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if len(order) != 2 {
+		t.Errorf("order = %v", order)
+	}
+	if res := <-get(url); res.err == nil {
+		t.Error("request after shutdown succeeded")
+	}
+}
+
+func TestManager_ShutdownDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv, url := slowServer(t, 2*time.Second, started)
+
+	hookCalled := false
+	m := New(50 * time.Millisecond)
+	m.Serve("http", srv)
+	m.OnShutdown("flush", func(ctx context.Context) error {
+		hookCalled = true
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	inFlight := get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not stop by deadline")
+	}
+	if !hookCalled {
+		t.Error("shutdown hook is not called")
+	}
+	if res := <-inFlight; res.err == nil {
+		t.Error("request is not cut off by deadline")
+	}
+}
+
+func TestManager_ServerFailure(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	stopped := make(chan struct{})
+	m := New(time.Second)
+	m.Serve("broken", &HTTPServer{Server: &http.Server{Addr: busy.Addr().String()}})
+	m.Serve("other", ServerFuncs{
+		ServeFunc: func() error {
+			<-stopped
+			return nil
+		},
+		ShutdownFunc: func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		},
+	})
+	if err := m.Run(context.Background()); err == nil {
+		t.Error("Run() error = nil, want listen error")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("other server is not shut down")
+	}
+}
+
+func TestManager_Signal(t *testing.T) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan struct{}, 1)
+	srv, _ := slowServer(t, 0, started)
+	m := New(time.Second)
+	m.Serve("http", srv)
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not stop on SIGTERM")
+	}
+}
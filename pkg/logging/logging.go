@@ -0,0 +1,230 @@
+// Package logging - структурированный журнал: одна JSON-строка на запись, уровни, ID запроса из context.Context
+// и маскирование чувствительных данных (номера карт, секреты)
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLevel = errors.New("log level is not valid")
+
+// Level - уровень записи
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel - debug, info, warn или error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+}
+
+// Logger - журнал с уровнем и постоянными полями; безопасен для одновременного использования
+type Logger struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  Level
+	fields []interface{}
+	now    func() time.Time
+}
+
+// New - записи с уровнем не ниже level пишутся в w
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{mu: &sync.Mutex{}, w: w, level: level, now: time.Now}
+}
+
+// Nop - журнал, который ничего не пишет
+func Nop() *Logger {
+	return New(ioutil.Discard, LevelError+1)
+}
+
+// With - журнал с дополнительными постоянными полями (пары ключ, значение)
+func (l *Logger) With(kv ...interface{}) *Logger {
+	cp := *l
+	cp.fields = append(append([]interface{}(nil), l.fields...), kv...)
+	return &cp
+}
+
+// Enabled - будет ли записана запись уровня level
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	l.Log(ctx, LevelDebug, msg, kv...)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	l.Log(ctx, LevelInfo, msg, kv...)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	l.Log(ctx, LevelWarn, msg, kv...)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	l.Log(ctx, LevelError, msg, kv...)
+}
+
+// Log - запись {"time", "level", "msg", "request_id", поля...}; kv - пары ключ, значение
+func (l *Logger) Log(ctx context.Context, level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeField(&buf, "time", l.now().UTC().Format(time.RFC3339Nano))
+	writeField(&buf, "level", level.String())
+	writeField(&buf, "msg", msg)
+	if id := RequestID(ctx); id != "" {
+		writeField(&buf, "request_id", id)
+	}
+	fields := append(append([]interface{}(nil), l.fields...), kv...)
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		var value interface{} = "!MISSING"
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		writeField(&buf, key, Redact(key, value))
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(buf.Bytes())
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(encodeValue(value))
+}
+
+// encodeValue - JSON значения; ошибки и Duration - строкой; номера карт маскируются в любом месте значения
+func encodeValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return cardNumberPattern.ReplaceAllFunc(data, func(pan []byte) []byte {
+		return []byte(MaskCardNumber(string(pan)))
+	})
+}
+
+// cardNumberPattern - 13-19 цифр, возможно группами через пробел или дефис
+var cardNumberPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// MaskCardNumber - видны только последние 4 цифры: "**** 4444"
+func MaskCardNumber(number string) string {
+	digits := make([]byte, 0, len(number))
+	for i := 0; i < len(number); i++ {
+		if number[i] >= '0' && number[i] <= '9' {
+			digits = append(digits, number[i])
+		}
+	}
+	if len(digits) < 4 {
+		return "****"
+	}
+	return "**** " + string(digits[len(digits)-4:])
+}
+
+// sensitiveKeys - поля, значение которых не пишется совсем (ключи сравниваются без регистра, "_" и "-")
+var sensitiveKeys = map[string]bool{
+	"secret": true, "password": true, "token": true, "authorization": true, "cvv": true, "cvc": true, "pin": true,
+	"webhooksecret": true,
+}
+
+// Redact - значение для журнала: секреты заменяются на "***", номера карт маскируются
+func Redact(key string, value interface{}) interface{} {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if sensitiveKeys[normalized] {
+		return "***"
+	}
+	if s, ok := value.(string); ok && (normalized == "cardnumber" || normalized == "pan") {
+		return MaskCardNumber(s)
+	}
+	return value
+}
+
+type contextKey int
+
+const requestIDKey contextKey = 0
+
+// WithRequestID - контекст с ID запроса; он попадает во все записи с этим контекстом
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID - ID запроса из контекста или ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID - случайный ID из 16 hex-символов
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// StdWriter - io.Writer для log.SetOutput: каждая строка стандартного журнала становится записью уровня level
+func (l *Logger) StdWriter(level Level) io.Writer {
+	return stdWriter{logger: l, level: level}
+}
+
+type stdWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.logger.Log(context.Background(), w.level, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decode - записи журнала как map
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo).With("component", "test")
+	logger.now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	logger.Debug(ctx, "hidden")
+	logger.Info(ctx, "transfer", "card_id", 1, "error", errors.New("boom"), "latency", 1500*time.Millisecond)
+	logger.Warn(context.Background(), "odd", "key")
+
+	records := decode(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("records = %v", records)
+	}
+	want := map[string]interface{}{"time": "2020-01-01T00:00:00Z", "level": "info", "msg": "transfer", "request_id": "req-1",
+		"component": "test", "card_id": 1.0, "error": "boom", "latency": "1.5s"}
+	for k, v := range want {
+		if records[0][k] != v {
+			t.Errorf("%s = %v, want %v", k, records[0][k], v)
+		}
+	}
+	if _, ok := records[1]["request_id"]; ok || records[1]["key"] != "!MISSING" || records[1]["level"] != "warn" {
+		t.Errorf("record = %v", records[1])
+	}
+	if !strings.HasPrefix(buf.String(), `{"time":`) {
+		t.Errorf("fields out of order: %s", buf.String())
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		key   string
+		value interface{}
+		want  string
+	}{
+		{key: "card_number", value: "1111 2222 3333 4444", want: `"**** 4444"`},
+		{key: "CardNumber", value: "1111222233334444", want: `"**** 4444"`},
+		{key: "webhook_secret", value: "s3cret", want: `"***"`},
+		{key: "Authorization", value: "Bearer x", want: `"***"`},
+		{key: "msg", value: "card 4000-0000-0000-0010 declined", want: `"card **** 0010 declined"`},
+		{key: "card", value: struct{ CardNumber string }{"5100 5000 0000 0429"}, want: `{"CardNumber":"**** 0429"}`},
+		{key: "card_id", value: 42, want: `42`},
+		{key: "amount", value: "173555", want: `"173555"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := string(encodeValue(Redact(tt.key, tt.value))); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"debug", "INFO", "Warn", "error"} {
+		level, err := ParseLevel(name)
+		if err != nil || !strings.EqualFold(level.String(), name) {
+			t.Errorf("ParseLevel(%q) = %v, %v", name, level, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("ParseLevel(trace) error = %v, want %v", err, ErrInvalidLevel)
+	}
+}
+
+func TestStdWriter(t *testing.T) {
+	var buf bytes.Buffer
+	std := log.New(New(&buf, LevelInfo).StdWriter(LevelWarn), "", 0)
+	std.Println("legacy 1111 2222 3333 4444")
+
+	records := decode(t, &buf)
+	if len(records) != 1 || records[0]["level"] != "warn" || records[0]["msg"] != "legacy **** 4444" {
+		t.Errorf("records = %v", records)
+	}
+}
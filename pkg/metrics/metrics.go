@@ -0,0 +1,317 @@
+// Package metrics - счётчики и гистограммы с выдачей в текстовом формате Prometheus (exposition format 0.0.4)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType - тип ответа /metrics
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets - границы гистограммы времени обработки запроса, секунды
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric - семейство метрик с одним именем
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry - набор метрик; порядок выдачи - по имени
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register - повторное имя - ошибка программиста, как и неверное число меток при записи
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.name()]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", m.name()))
+	}
+	r.metrics[m.name()] = m
+}
+
+// WriteText - все метрики в текстовом формате Prometheus
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	list := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		list = append(list, m)
+	}
+	r.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name() < list[j].name() })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range list {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP - обработчик GET /metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	_ = r.WriteText(w)
+}
+
+// desc - имя, описание и имена меток семейства
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) header(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, escapeHelp(d.help), d.metricName, typ)
+}
+
+// key - значения меток одной серии как ключ map
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s: got %d label values, want %d", d.metricName, len(values), len(d.labels)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// sample - строка "name{labels} value"; extra - дополнительная метка (le у гистограммы)
+func (d desc) sample(w *bufio.Writer, suffix string, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(d.metricName + suffix)
+	if len(values) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range d.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(values) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// sortedKeys - серии в стабильном порядке
+func sortedKeys(n int, each func(func(key string))) []string {
+	keys := make([]string, 0, n)
+	each(func(key string) { keys = append(keys, key) })
+	sort.Strings(keys)
+	return keys
+}
+
+func splitKey(key string, labels int) []string {
+	if labels == 0 {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// CounterVec - монотонный счётчик с метками
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec - счётчик, зарегистрированный в r
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{metricName: name, help: help, labels: labels}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Inc - +1 к серии с заданными значениями меток
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add - отрицательные v игнорируются: счётчик не убывает
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := c.key(labelValues)
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value - текущее значение серии
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := sortedKeys(len(c.values), func(add func(string)) {
+		for k := range c.values {
+			add(k)
+		}
+	})
+	for _, k := range keys {
+		c.sample(w, "", splitKey(k, len(c.labels)), "", "", c.values[k])
+	}
+}
+
+// histogram - одна серия гистограммы; counts - не накопленные, по бакетам + последний для +Inf
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec - гистограмма с метками
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+// NewHistogramVec - гистограмма, зарегистрированная в r; buckets == nil - DefaultBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{desc: desc{metricName: name, help: help, labels: labels}, buckets: buckets, values: make(map[string]*histogram)}
+	r.register(h)
+	return h
+}
+
+// Observe - добавить наблюдение в серию
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v) // первый бакет с границей >= v
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = s
+	}
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+// Count - число наблюдений в серии
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.values[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := sortedKeys(len(h.values), func(add func(string)) {
+		for k := range h.values {
+			add(k)
+		}
+	})
+	for _, k := range keys {
+		s := h.values[k]
+		values := splitKey(k, len(h.labels))
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			h.sample(w, "_bucket", values, "le", formatFloat(le), float64(cumulative))
+		}
+		h.sample(w, "_bucket", values, "le", "+Inf", float64(s.count))
+		h.sample(w, "_sum", values, "", "", s.sum)
+		h.sample(w, "_count", values, "", "", float64(s.count))
+	}
+}
+
+// Emit - передать значение серии из функции сбора
+type Emit func(value float64, labelValues ...string)
+
+// funcMetric - значения вычисляются при каждой выдаче (состояние, которое хранится вне реестра)
+type funcMetric struct {
+	desc
+	typ     string
+	collect func(Emit)
+}
+
+// NewCounterFunc - счётчик, значения которого collect отдаёт через emit при каждой выдаче
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func(emit Emit)) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, labels: labels}, typ: "counter", collect: collect})
+}
+
+// NewGaugeFunc - то же для величины, которая может убывать
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(emit Emit)) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, labels: labels}, typ: "gauge", collect: collect})
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	f.header(w, f.typ)
+	f.collect(func(value float64, labelValues ...string) {
+		f.key(labelValues) // проверка числа меток
+		f.sample(w, "", labelValues, "", "", value)
+	})
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
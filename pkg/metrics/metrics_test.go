@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("http_requests_total", "HTTP requests.", "route", "code")
+	requests.Inc("/purchaseCard", "200")
+	requests.Add(2, "/purchaseCard", "200")
+	requests.Inc(`/a"b\c`, "404")
+	requests.Add(-1, "/purchaseCard", "200")
+
+	latency := r.NewHistogramVec("http_request_duration_seconds", "Latency\nin seconds.", []float64{0.5, 0.1}, "route")
+	latency.Observe(0.05, "/echo")
+	latency.Observe(0.1, "/echo")
+	latency.Observe(3, "/echo")
+
+	r.NewGaugeFunc("up", "Always one.", nil, func(emit Emit) { emit(1) })
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP http_request_duration_seconds Latency\nin seconds.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="/echo",le="0.1"} 2
+http_request_duration_seconds_bucket{route="/echo",le="0.5"} 2
+http_request_duration_seconds_bucket{route="/echo",le="+Inf"} 3
+http_request_duration_seconds_sum{route="/echo"} 3.15
+http_request_duration_seconds_count{route="/echo"} 3
+# HELP http_requests_total HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b\\c",code="404"} 1
+http_requests_total{route="/purchaseCard",code="200"} 3
+# HELP up Always one.
+# TYPE up gauge
+up 1
+`
+	if buf.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", buf.String(), want)
+	}
+	if got := requests.Value("/purchaseCard", "200"); got != 3 {
+		t.Errorf("Value() = %v, want 3", got)
+	}
+	if got := latency.Count("/echo"); got != 3 {
+		t.Errorf("Count() = %v, want 3", got)
+	}
+}
+
+func TestRegistry_Panics(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(r *Registry)
+	}{
+		{name: "duplicate", call: func(r *Registry) {
+			r.NewCounterVec("x", "")
+			r.NewCounterVec("x", "")
+		}},
+		{name: "label count", call: func(r *Registry) {
+			r.NewCounterVec("x", "", "a").Inc("1", "2")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			tt.call(NewRegistry())
+		})
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("x_total", "X.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != ContentType || !bytes.Contains(rec.Body.Bytes(), []byte("x_total 1\n")) {
+		t.Errorf("GET = %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != 405 {
+		t.Errorf("POST = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+// Package ratelimit - token bucket по ключу (IP клиента, пользователь)
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("rate limit rule is not valid")
+
+// Rule - Rate токенов в секунду, не больше Burst подряд; нулевое правило - без ограничения
+type Rule struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Enabled - правило ограничивает запросы
+func (r Rule) Enabled() bool {
+	return r != Rule{}
+}
+
+// Validate - Rate и Burst положительные (или оба нулевые)
+func (r Rule) Validate() error {
+	if !r.Enabled() {
+		return nil
+	}
+	if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) || r.Burst <= 0 {
+		return fmt.Errorf("%w: rate %v, burst %d (both must be positive)", ErrInvalidRule, r.Rate, r.Burst)
+	}
+	return nil
+}
+
+// Policy - правила для одного маршрута: по IP клиента и по пользователю
+type Policy struct {
+	PerIP   Rule `json:"per_ip"`
+	PerUser Rule `json:"per_user"`
+}
+
+// Validate - проверка обоих правил
+func (p Policy) Validate() error {
+	if err := p.PerIP.Validate(); err != nil {
+		return fmt.Errorf("per_ip: %w", err)
+	}
+	if err := p.PerUser.Validate(); err != nil {
+		return fmt.Errorf("per_user: %w", err)
+	}
+	return nil
+}
+
+// bucket - токены на момент last
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepInterval - как часто Limiter удаляет полные корзины (ключи, которые давно не приходили)
+const sweepInterval = time.Minute
+
+// Limiter - корзины по ключу; полная корзина равна отсутствующей, поэтому её можно удалить
+type Limiter struct {
+	rule      Rule
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rule Rule) *Limiter {
+	return &Limiter{rule: rule, buckets: make(map[string]*bucket)}
+}
+
+// Allow - взять токен для key; если токена нет - через сколько он появится
+func (l *Limiter) Allow(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	if !l.rule.Enabled() {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: float64(l.rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(l.rule, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.rule.Rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// Refund - вернуть токен, взятый Allow для key, если запрос всё же отклонён другим лимитом
+func (l *Limiter) Refund(key string, now time.Time) {
+	if !l.rule.Enabled() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, found := l.buckets[key]
+	if !found {
+		return // корзина удалена полной - возвращать некуда
+	}
+	b.refill(l.rule, now)
+	b.tokens = math.Min(float64(l.rule.Burst), b.tokens+1)
+}
+
+func (b *bucket) refill(rule Rule, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+		b.last = now
+	}
+}
+
+// sweep - вызывать под l.mu
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(l.rule, now)
+		if b.tokens >= float64(l.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Len - число отслеживаемых ключей
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(Rule{Rate: 2, Burst: 3})
+
+	tests := []struct {
+		name       string
+		key        string
+		at         time.Duration // от start
+		ok         bool
+		retryAfter time.Duration
+	}{
+		{name: "burst 1", key: "a", ok: true},
+		{name: "burst 2", key: "a", ok: true},
+		{name: "burst 3", key: "a", ok: true},
+		{name: "empty", key: "a", ok: false, retryAfter: 500 * time.Millisecond},
+		{name: "other key", key: "b", ok: true},
+		{name: "partly refilled", key: "a", at: 250 * time.Millisecond, ok: false, retryAfter: 250 * time.Millisecond},
+		{name: "refilled", key: "a", at: 500 * time.Millisecond, ok: true},
+		{name: "empty again", key: "a", at: 500 * time.Millisecond, ok: false, retryAfter: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, retryAfter := l.Allow(tt.key, start.Add(tt.at))
+			if ok != tt.ok || retryAfter != tt.retryAfter {
+				t.Errorf("Allow() = %v, %v, want %v, %v", ok, retryAfter, tt.ok, tt.retryAfter)
+			}
+		})
+	}
+
+	// через минуту корзины полные и удаляются
+	l.Allow("c", start.Add(2*time.Minute))
+	if n := l.Len(); n != 1 {
+		t.Errorf("Len() after sweep = %d, want 1", n)
+	}
+}
+
+func TestLimiter_Refund(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(Rule{Rate: 1, Burst: 2})
+	l.Allow("a", now)
+	l.Allow("a", now)
+	l.Refund("a", now)
+	if ok, _ := l.Allow("a", now); !ok {
+		t.Error("Allow() after Refund() denied")
+	}
+	if ok, _ := l.Allow("a", now); ok {
+		t.Error("Allow() over burst allowed")
+	}
+	// токены не копятся сверх Burst
+	l.Refund("b", now)
+	l.Refund("a", now)
+	l.Refund("a", now)
+	l.Refund("a", now)
+	for i, want := range []bool{true, true, false} {
+		if ok, _ := l.Allow("a", now); ok != want {
+			t.Errorf("Allow() #%d = %v, want %v", i+1, ok, want)
+		}
+	}
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	l := NewLimiter(Rule{})
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a", time.Time{}); !ok {
+			t.Fatal("disabled limiter denied")
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		err    error
+	}{
+		{policy: Policy{}},
+		{policy: Policy{PerIP: Rule{Rate: 0.5, Burst: 1}, PerUser: Rule{Rate: 1, Burst: 10}}},
+		{policy: Policy{PerIP: Rule{Rate: 1}}, err: ErrInvalidRule},
+		{policy: Policy{PerUser: Rule{Rate: -1, Burst: 1}}, err: ErrInvalidRule},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); !errors.Is(err, tt.err) {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.policy, err, tt.err)
+		}
+	}
+}
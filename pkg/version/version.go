@@ -0,0 +1,28 @@
+// Package version - данные сборки, которые подставляются при линковке:
+//
+//	go build -ldflags "-X github.com/wool/go2hw11/pkg/version.Version=v1.2.0 \
+//		-X github.com/wool/go2hw11/pkg/version.Commit=$(git rev-parse HEAD) \
+//		-X github.com/wool/go2hw11/pkg/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server_new
+package version
+
+import "runtime"
+
+// значения по умолчанию - у сборки без -ldflags (go run, тесты)
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info - ответ GET /version
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get - данные текущей сборки
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// DeadLetter - событие, которое не удалось доставить за MaxAttempts попыток
+type DeadLetter struct {
+	URL      string     `json:"url"`
+	Event    card.Event `json:"event"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error"` // ошибка последней попытки
+	Time     int64      `json:"time"`  // unix timestamp
+}
+
+// DeadLetterStore - хранилище недоставленных событий
+type DeadLetterStore interface {
+	Put(letter DeadLetter) error
+	List() ([]DeadLetter, error)
+}
+
+// MemoryDeadLetters - хранилище в памяти
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+func NewMemoryDeadLetters() *MemoryDeadLetters {
+	return &MemoryDeadLetters{letters: make([]DeadLetter, 0)}
+}
+
+func (m *MemoryDeadLetters) Put(letter DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.letters = append(m.letters, letter)
+	return nil
+}
+
+func (m *MemoryDeadLetters) List() ([]DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]DeadLetter, len(m.letters))
+	copy(res, m.letters)
+	return res, nil
+}
+
+// FileDeadLetters - хранилище в файле, по одной JSON-записи на строку
+type FileDeadLetters struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileDeadLetters(path string) *FileDeadLetters {
+	return &FileDeadLetters{path: path}
+}
+
+func (f *FileDeadLetters) Put(letter DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(letter); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileDeadLetters) List() ([]DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	letters := make([]DeadLetter, 0)
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return letters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var letter DeadLetter
+		if err := dec.Decode(&letter); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
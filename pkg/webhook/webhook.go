@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// заголовки запроса вебхука
+const (
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex hmac метки времени и тела>
+	HeaderTimestamp = "X-Webhook-Timestamp" // unix-время отправки, входит в подпись
+	HeaderEventID   = "X-Webhook-Event-Id"
+	HeaderEventType = "X-Webhook-Event-Type"
+	HeaderAttempt   = "X-Webhook-Attempt"
+)
+
+// значения по умолчанию для Config
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = 500 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultQueueSize   = 1024
+	DefaultTolerance   = 5 * time.Minute // допустимый возраст подписи для Verify
+)
+
+var (
+	ErrNoEndpoints       = errors.New("webhook endpoints are not set")
+	ErrInvalidEndpoint   = errors.New("webhook endpoint is not valid")
+	ErrUnexpectedStatus  = errors.New("webhook receiver returned non-2xx status")
+	ErrInvalidSignature  = errors.New("webhook signature is not valid")
+	ErrSignatureExpired  = errors.New("webhook signature is expired")
+	ErrDispatcherStopped = errors.New("webhook dispatcher is stopped")
+	ErrQueueFull         = errors.New("webhook queue is full")
+)
+
+// Endpoint - получатель вебхуков; пустой Events - все типы событий
+type Endpoint struct {
+	URL    string           `json:"url"`
+	Secret string           `json:"secret"`
+	Events []card.EventType `json:"events,omitempty"`
+}
+
+func (e Endpoint) accepts(t card.EventType) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, v := range e.Events {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Config - настройки диспетчера; нулевые значения заменяются значениями по умолчанию
+type Config struct {
+	Endpoints   []Endpoint
+	MaxAttempts int           // всего попыток, включая первую
+	Backoff     time.Duration // пауза перед второй попыткой, далее удваивается
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // таймаут одного запроса
+	QueueSize   int           // очередь каждого получателя; что не поместилось - сразу в dead-letter
+	Client      *http.Client
+	DeadLetters DeadLetterStore
+}
+
+// Sign - подпись: hex(HMAC-SHA256(secret, "<timestamp>.<body>")); метка времени не даёт повторить старый запрос
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - проверка подписи на стороне получателя; timestamp - заголовок X-Webhook-Timestamp,
+// подпись старше tolerance (или из будущего) - ErrSignatureExpired
+func Verify(secret string, body []byte, timestamp, signature string, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signed %v ago", ErrSignatureExpired, age.Truncate(time.Second))
+	}
+	return nil
+}
+
+// delivery - одно событие для одного получателя
+type delivery struct {
+	endpoint Endpoint
+	event    card.Event
+	body     []byte
+}
+
+// worker - очередь одного получателя: медленный получатель не задерживает доставку остальным
+type worker struct {
+	endpoint Endpoint
+	queue    chan delivery
+}
+
+// Dispatcher - рассылка событий шины по вебхукам с повторами и dead-letter
+type Dispatcher struct {
+	cfg     Config
+	workers []*worker
+	stop    chan struct{}
+	abort   sync.Once
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closed  bool
+}
+
+func NewDispatcher(cfg Config) (*Dispatcher, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	for _, e := range cfg.Endpoints {
+		if e.URL == "" || e.Secret == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidEndpoint, e.URL)
+		}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+	if cfg.DeadLetters == nil {
+		cfg.DeadLetters = NewMemoryDeadLetters()
+	}
+	d := &Dispatcher{cfg: cfg, stop: make(chan struct{})}
+	for _, e := range cfg.Endpoints {
+		w := &worker{endpoint: e, queue: make(chan delivery, cfg.QueueSize)}
+		d.workers = append(d.workers, w)
+		d.wg.Add(1)
+		go d.run(w)
+	}
+	return d, nil
+}
+
+// DeadLetters - хранилище недоставленных событий
+func (d *Dispatcher) DeadLetters() DeadLetterStore {
+	return d.cfg.DeadLetters
+}
+
+// Subscribe - подписать диспетчер на шину событий; возвращает функцию отписки
+func (d *Dispatcher) Subscribe(bus *card.EventBus) (unsubscribe func()) {
+	return bus.Subscribe(func(e card.Event) {
+		if err := d.Dispatch(e); err != nil {
+			log.Println(err)
+		}
+	})
+}
+
+// Dispatch - поставить событие в очереди подходящих получателей; не ждёт: при полной очереди
+// событие сразу уходит в dead-letter и возвращается ErrQueueFull
+func (d *Dispatcher) Dispatch(e card.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var dropped []delivery
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrDispatcherStopped
+	}
+	for _, w := range d.workers {
+		if !w.endpoint.accepts(e.Type) {
+			continue
+		}
+		dl := delivery{endpoint: w.endpoint, event: e, body: body}
+		select {
+		case w.queue <- dl:
+		default:
+			dropped = append(dropped, dl)
+		}
+	}
+	d.mu.Unlock()
+
+	// запись в dead-letter - файловый ввод-вывод, поэтому вне мьютекса
+	for _, dl := range dropped {
+		d.deadLetter(dl, 0, ErrQueueFull)
+	}
+	if len(dropped) > 0 {
+		return fmt.Errorf("%w: event %d", ErrQueueFull, e.ID)
+	}
+	return nil
+}
+
+// Close - остановить приём событий и дождаться доставки очереди; недоставленное уходит в dead-letter
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, w := range d.workers {
+		close(w.queue)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// Shutdown - Close с дедлайном: по отмене ctx повторы прерываются (Abort), остаток очереди уходит в dead-letter
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.Abort()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Abort - как Close, но без повторов: неотправленное сразу уходит в dead-letter
+func (d *Dispatcher) Abort() {
+	d.abort.Do(func() { close(d.stop) })
+	d.Close()
+}
+
+func (d *Dispatcher) run(w *worker) {
+	defer d.wg.Done()
+	for dl := range w.queue {
+		d.deliver(dl)
+	}
+}
+
+// deliver - доставка с повторами; backoff удваивается до MaxBackoff, после последней попытки - dead-letter
+func (d *Dispatcher) deliver(dl delivery) {
+	backoff := d.cfg.Backoff
+	attempt := 0
+	var err error
+	for attempt < d.cfg.MaxAttempts && !d.aborted() {
+		attempt++
+		if err = d.send(dl, attempt); err == nil {
+			return
+		}
+		if attempt == d.cfg.MaxAttempts || !d.wait(backoff) {
+			break
+		}
+		backoff *= 2
+		if backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+	if err == nil {
+		// после Abort остаток очереди не отправляется
+		err = ErrDispatcherStopped
+	}
+	d.deadLetter(dl, attempt, err)
+}
+
+func (d *Dispatcher) deadLetter(dl delivery, attempts int, reason error) {
+	letter := DeadLetter{URL: dl.endpoint.URL, Event: dl.event, Attempts: attempts, Error: reason.Error(), Time: time.Now().Unix()}
+	if err := d.cfg.DeadLetters.Put(letter); err != nil {
+		log.Println(err)
+	}
+}
+
+func (d *Dispatcher) aborted() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait - пауза перед повтором; false, если диспетчер прерван через Abort
+func (d *Dispatcher) wait(backoff time.Duration) bool {
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-d.stop:
+		return false
+	}
+}
+
+func (d *Dispatcher) send(dl delivery, attempt int) error {
+	req, err := http.NewRequest(http.MethodPost, dl.endpoint.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// каждая попытка подписывается заново: повтор после паузы не должен устареть у получателя
+	timestamp := time.Now().Unix()
+	req.Header.Set(HeaderSignature, Sign(dl.endpoint.Secret, timestamp, dl.body))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderEventID, strconv.FormatInt(dl.event.ID, 10))
+	req.Header.Set(HeaderEventType, string(dl.event.Type))
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wool/go2hw11/pkg/card"
+)
+
+// receiver - httptest-получатель; первые failures запросов отвечает 500
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	calls    int
+	got      []string // типы принятых событий
+	bad      int      // запросы с неверной подписью
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if err := Verify(r.secret, body, req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), DefaultTolerance); err != nil {
+		r.bad++
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.calls <= r.failures {
+		http.Error(w, "try later", http.StatusInternalServerError)
+		return
+	}
+	r.got = append(r.got, req.Header.Get(HeaderEventType))
+}
+
+func (r *receiver) delivered() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.got)
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		secret    string // секрет на стороне диспетчера
+		calls     int
+		delivered int
+		dead      int
+	}{
+		{name: "first attempt", failures: 0, secret: "s3cret", calls: 1, delivered: 1},
+		{name: "after retries", failures: 2, secret: "s3cret", calls: 3, delivered: 1},
+		{name: "dead letter", failures: 10, secret: "s3cret", calls: 3, dead: 1},
+		{name: "wrong secret", failures: 0, secret: "other", calls: 3, dead: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv := &receiver{secret: "s3cret", failures: tt.failures}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			d, err := NewDispatcher(Config{
+				Endpoints:   []Endpoint{{URL: srv.URL, Secret: tt.secret}},
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Dispatch(card.Event{ID: 1, Type: card.EventCardIssued, CardID: 1}); err != nil {
+				t.Fatal(err)
+			}
+			d.Close()
+
+			letters, _ := d.DeadLetters().List()
+			if rcv.calls != tt.calls || len(rcv.got) != tt.delivered || len(letters) != tt.dead {
+				t.Errorf("calls = %d, delivered = %d, dead = %d", rcv.calls, len(rcv.got), len(letters))
+			}
+			if tt.dead > 0 && letters[0].Attempts != 3 {
+				t.Errorf("dead letter = %+v", letters[0])
+			}
+			if err := d.Dispatch(card.Event{ID: 2}); !errors.Is(err, ErrDispatcherStopped) {
+				t.Errorf("Dispatch() after Close error = %v, want %v", err, ErrDispatcherStopped)
+			}
+		})
+	}
+}
+
+func TestDispatcher_ServiceEvents(t *testing.T) {
+	rcv := &receiver{secret: "s3cret"}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := NewDispatcher(Config{
+		Endpoints:   []Endpoint{{URL: srv.URL, Secret: "s3cret", Events: []card.EventType{card.EventCardIssued, card.EventCardBlocked, card.EventTransactionPosted}}},
+		DeadLetters: NewFileDeadLetters(filepath.Join(dir, "dead.log")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := card.NewService()
+	svc.SetCards([]*card.Card{{ID: 1, UserID: 1, Balance: card.Rub(1000_00)}})
+	unsubscribe := d.Subscribe(svc.Events())
+
+	issued, err := svc.IssueCard(context.Background(), "virtual", "Visa", 1, card.RUB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Purchase(context.Background(), 1, card.Rub(10_00), "5411"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BlockCard(context.Background(), issued.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UnblockCard(context.Background(), issued.ID); err != nil { // не подписан на CardUnblocked
+		t.Fatal(err)
+	}
+
+	want := []string{"CardIssued", "TransactionPosted", "CardBlocked"}
+	// шина и диспетчер доставляют асинхронно
+	deadline := time.Now().Add(5 * time.Second)
+	for rcv.delivered() < len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	unsubscribe()
+	d.Close()
+
+	if len(rcv.got) != len(want) {
+		t.Fatalf("delivered = %v, want %v", rcv.got, want)
+	}
+	for i := range want {
+		if rcv.got[i] != want[i] {
+			t.Errorf("delivered = %v, want %v", rcv.got, want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Now().Unix()
+	old := now - int64(DefaultTolerance/time.Second) - 60
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		want      error
+	}{
+		{name: "valid", timestamp: strconv.FormatInt(now, 10), signature: Sign("s3cret", now, body)},
+		{name: "wrong secret", timestamp: strconv.FormatInt(now, 10), signature: Sign("other", now, body), want: ErrInvalidSignature},
+		{name: "timestamp is not signed", timestamp: strconv.FormatInt(now+1, 10), signature: Sign("s3cret", now, body), want: ErrInvalidSignature},
+		{name: "replayed", timestamp: strconv.FormatInt(old, 10), signature: Sign("s3cret", old, body), want: ErrSignatureExpired},
+		{name: "no timestamp", timestamp: "", signature: Sign("s3cret", 0, body), want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		if err := Verify("s3cret", body, tt.timestamp, tt.signature, DefaultTolerance); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDispatcher_SlowEndpoint(t *testing.T) {
+	started, release := make(chan struct{}, 3), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer slow.Close()
+	rcv := &receiver{secret: "s3cret"}
+	fast := httptest.NewServer(rcv)
+	defer fast.Close()
+
+	d, err := NewDispatcher(Config{
+		Endpoints: []Endpoint{{URL: slow.URL, Secret: "s3cret"}, {URL: fast.URL, Secret: "s3cret"}},
+		QueueSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDelivered := func(n int) {
+		for deadline := time.Now().Add(5 * time.Second); rcv.delivered() < n && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		if got := rcv.delivered(); got < n {
+			t.Fatalf("fast endpoint got %d events, want %d: it waits for the slow one", got, n)
+		}
+	}
+
+	// первое событие медленный получатель держит, второе ждёт в его очереди, третье не помещается
+	if err := d.Dispatch(card.Event{ID: 1, Type: card.EventCardIssued}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	waitDelivered(1)
+	if err := d.Dispatch(card.Event{ID: 2, Type: card.EventCardIssued}); err != nil {
+		t.Fatal(err)
+	}
+	waitDelivered(2)
+	if err := d.Dispatch(card.Event{ID: 3, Type: card.EventCardIssued}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Dispatch() error = %v, want %v", err, ErrQueueFull)
+	}
+	waitDelivered(3)
+
+	letters, _ := d.DeadLetters().List()
+	if len(letters) != 1 || letters[0].URL != slow.URL || letters[0].Event.ID != 3 || letters[0].Error != ErrQueueFull.Error() {
+		t.Errorf("dead letters = %+v, want event 3 for the slow endpoint", letters)
+	}
+	close(release)
+	d.Close()
+}
+
+func TestNewDispatcher(t *testing.T) {
+	if _, err := NewDispatcher(Config{}); !errors.Is(err, ErrNoEndpoints) {
+		t.Errorf("NewDispatcher() error = %v, want %v", err, ErrNoEndpoints)
+	}
+	if _, err := NewDispatcher(Config{Endpoints: []Endpoint{{URL: "http://localhost"}}}); !errors.Is(err, ErrInvalidEndpoint) {
+		t.Errorf("NewDispatcher() error = %v, want %v", err, ErrInvalidEndpoint)
+	}
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	rcv := &receiver{secret: "s3cret", failures: 100}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{
+		Endpoints:   []Endpoint{{URL: srv.URL, Secret: "s3cret"}},
+		MaxAttempts: 10,
+		Backoff:     time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if err := d.Dispatch(card.Event{ID: i, Type: card.EventCardIssued}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	letters, _ := d.DeadLetters().List()
+	if len(letters) != 3 || rcv.calls != 1 {
+		t.Errorf("dead letters = %d, calls = %d, want 3 and 1", len(letters), rcv.calls)
+	}
+}